@@ -0,0 +1,320 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Item is an item of a table, its attributes by name. An item is marshalled as DynamoDB JSON e.g.
+// {"id":{"S":"1"}} and unmarshalled from it.
+type Item map[string]*dynamodb.AttributeValue
+
+func (i Item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeMap(i))
+}
+
+// copy returns a deep copy of the item, so the items of a table can't be changed by the callers it
+// returns them to
+func (i Item) copy() Item {
+	if i == nil {
+		return nil
+	}
+
+	item := Item{}
+	for name, value := range i {
+		item[name] = copyAttributeValue(value)
+	}
+
+	return item
+}
+
+func encodeMap(m map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	encoded := map[string]interface{}{}
+	for name, value := range m {
+		encoded[name] = encodeAttributeValue(value)
+	}
+
+	return encoded
+}
+
+// encodeAttributeValue returns the DynamoDB JSON of an attribute value, an object of its type and value
+func encodeAttributeValue(av *dynamodb.AttributeValue) interface{} {
+	switch attributeType(av) {
+	case "S":
+		return map[string]interface{}{"S": aws.StringValue(av.S)}
+	case "N":
+		return map[string]interface{}{"N": aws.StringValue(av.N)}
+	case "B":
+		return map[string]interface{}{"B": av.B}
+	case "BOOL":
+		return map[string]interface{}{"BOOL": aws.BoolValue(av.BOOL)}
+	case "NULL":
+		return map[string]interface{}{"NULL": true}
+	case "SS":
+		return map[string]interface{}{"SS": aws.StringValueSlice(av.SS)}
+	case "NS":
+		return map[string]interface{}{"NS": aws.StringValueSlice(av.NS)}
+	case "BS":
+		return map[string]interface{}{"BS": av.BS}
+	case "L":
+		list := make([]interface{}, len(av.L))
+		for i, value := range av.L {
+			list[i] = encodeAttributeValue(value)
+		}
+		return map[string]interface{}{"L": list}
+	case "M":
+		return map[string]interface{}{"M": encodeMap(av.M)}
+	}
+
+	return map[string]interface{}{}
+}
+
+// attributeType returns the type of an attribute value e.g. "S", or "" if it has none
+func attributeType(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return "S"
+	case av.N != nil:
+		return "N"
+	case av.B != nil:
+		return "B"
+	case av.BOOL != nil:
+		return "BOOL"
+	case av.NULL != nil:
+		return "NULL"
+	case av.SS != nil:
+		return "SS"
+	case av.NS != nil:
+		return "NS"
+	case av.BS != nil:
+		return "BS"
+	case av.L != nil:
+		return "L"
+	case av.M != nil:
+		return "M"
+	}
+
+	return ""
+}
+
+func copyAttributeValue(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+
+	value := *av
+	if av.L != nil {
+		value.L = make([]*dynamodb.AttributeValue, len(av.L))
+		for i, element := range av.L {
+			value.L[i] = copyAttributeValue(element)
+		}
+	}
+	if av.M != nil {
+		value.M = Item(av.M).copy()
+	}
+	if av.SS != nil {
+		value.SS = append([]*string{}, av.SS...)
+	}
+	if av.NS != nil {
+		value.NS = append([]*string{}, av.NS...)
+	}
+	if av.BS != nil {
+		value.BS = append([][]byte{}, av.BS...)
+	}
+
+	return &value
+}
+
+// compare compares the values of two scalar attributes of the same type, numbers numerically, returning
+// false if they can't be compared
+func compare(a, b *dynamodb.AttributeValue) (int, bool) {
+	switch typ := attributeType(a); {
+	case typ != attributeType(b):
+		return 0, false
+	case typ == "N":
+		x, y, ok := parseNumbers(aws.StringValue(a.N), aws.StringValue(b.N))
+		if !ok {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case typ == "S":
+		return strings.Compare(aws.StringValue(a.S), aws.StringValue(b.S)), true
+	case typ == "B":
+		return bytes.Compare(a.B, b.B), true
+	}
+
+	return 0, false
+}
+
+// equal reports whether two attribute values are equal. Numbers are equal if they're numerically equal
+// and sets if they have the same elements.
+func equal(a, b *dynamodb.AttributeValue) bool {
+	typ := attributeType(a)
+	if typ != attributeType(b) {
+		return false
+	}
+
+	switch typ {
+	case "N", "S", "B":
+		result, ok := compare(a, b)
+		return ok && result == 0
+	case "BOOL":
+		return aws.BoolValue(a.BOOL) == aws.BoolValue(b.BOOL)
+	case "NULL":
+		return true
+	case "SS", "NS", "BS":
+		x, y := setElements(a), setElements(b)
+		if len(x) != len(y) {
+			return false
+		}
+		for _, element := range x {
+			if !containsElement(y, element) {
+				return false
+			}
+		}
+		return true
+	case "L":
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !equal(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for name, value := range a.M {
+			if !equal(value, b.M[name]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// setElements returns the elements of a set as scalar attribute values
+func setElements(av *dynamodb.AttributeValue) []*dynamodb.AttributeValue {
+	elements := []*dynamodb.AttributeValue{}
+	for _, s := range av.SS {
+		elements = append(elements, &dynamodb.AttributeValue{S: s})
+	}
+	for _, n := range av.NS {
+		elements = append(elements, &dynamodb.AttributeValue{N: n})
+	}
+	for _, b := range av.BS {
+		elements = append(elements, &dynamodb.AttributeValue{B: b})
+	}
+
+	return elements
+}
+
+// newSet returns a set of the type of the elements
+func newSet(typ string, elements []*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	set := &dynamodb.AttributeValue{}
+	for _, element := range elements {
+		switch typ {
+		case "SS":
+			set.SS = append(set.SS, element.S)
+		case "NS":
+			set.NS = append(set.NS, element.N)
+		case "BS":
+			set.BS = append(set.BS, element.B)
+		}
+	}
+
+	return set
+}
+
+func containsElement(elements []*dynamodb.AttributeValue, element *dynamodb.AttributeValue) bool {
+	for _, e := range elements {
+		if equal(e, element) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// size returns the size of an attribute as the size function does, the length of a string or binary
+// and the number of elements of a set, list or map
+func size(av *dynamodb.AttributeValue) (int, bool) {
+	switch attributeType(av) {
+	case "S":
+		return utf8.RuneCountInString(aws.StringValue(av.S)), true
+	case "B":
+		return len(av.B), true
+	case "SS", "NS", "BS":
+		return len(setElements(av)), true
+	case "L":
+		return len(av.L), true
+	case "M":
+		return len(av.M), true
+	}
+
+	return 0, false
+}
+
+// keyString returns a string identifying the values of the key attributes of an item. Numbers are
+// normalised, so numerically equal keys are the same.
+func keyString(item Item, names ...string) string {
+	parts := []string{}
+	for _, name := range names {
+		value := item[name]
+		if number, ok := new(big.Rat).SetString(aws.StringValue(value.N)); ok && value.N != nil {
+			value = &dynamodb.AttributeValue{N: aws.String(number.RatString())}
+		}
+
+		data, _ := json.Marshal(encodeAttributeValue(value))
+		parts = append(parts, string(data))
+	}
+
+	return strings.Join(parts, "|")
+}
+
+func parseNumbers(x, y string) (*big.Rat, *big.Rat, bool) {
+	a, ok := new(big.Rat).SetString(x)
+	if !ok {
+		return nil, nil, false
+	}
+
+	b, ok := new(big.Rat).SetString(y)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return a, b, true
+}
+
+// addNumbers returns the sum, or with negate the difference, of two numbers. Numbers are decimals, so
+// their sum is exact.
+func addNumbers(x, y string, negate bool) (string, bool) {
+	a, b, ok := parseNumbers(x, y)
+	if !ok {
+		return "", false
+	}
+
+	if negate {
+		b.Neg(b)
+	}
+
+	sum := a.Add(a, b)
+	if sum.IsInt() {
+		return sum.Num().String(), true
+	}
+
+	return strings.TrimRight(sum.FloatString(38), "0"), true
+}
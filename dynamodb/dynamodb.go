@@ -0,0 +1,15 @@
+//go:generate mockgen -package dynamodb -source=dynamodb.go -destination dynamodb_mock.go
+
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Client defines the dynamodb client interface of the item operations of the service integration
+type Client interface {
+	GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
+}
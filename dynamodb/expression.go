@@ -0,0 +1,903 @@
+package dynamodb
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type tokenKind int
+
+const (
+	nameToken tokenKind = iota
+	nameRefToken
+	valueRefToken
+	numberToken
+	punctToken
+	endToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// punctuation is ordered so that the longest operator matches first
+var punctuation = []string{"<>", "<=", ">=", "=", "<", ">", "(", ")", ",", ".", "[", "]", "+", "-"}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':':
+			end := i + 1
+			for end < len(expr) && isNameChar(expr[end]) {
+				end++
+			}
+			if end == i+1 {
+				return nil, newValidationError("Invalid expression: Syntax error; token: \"" + string(c) + "\"")
+			}
+			kind := nameRefToken
+			if c == ':' {
+				kind = valueRefToken
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[i:end]})
+			i = end
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(expr) && expr[end] >= '0' && expr[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, token{kind: numberToken, text: expr[i:end]})
+			i = end
+		case isNameChar(c):
+			end := i
+			for end < len(expr) && isNameChar(expr[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: nameToken, text: expr[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(expr[i:], p) {
+					tokens = append(tokens, token{kind: punctToken, text: p})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, newValidationError("Invalid expression: Syntax error; token: \"" + string(c) + "\"")
+			}
+		}
+	}
+
+	return append(tokens, token{kind: endToken}), nil
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// pathElement is an element of a document path, the name of an attribute or the index of a list element
+type pathElement struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// documentPath is the path of an attribute of an item e.g. a.b[0]
+type documentPath []pathElement
+
+// get returns the attribute of the path, or nil if the item doesn't have it
+func (p documentPath) get(item Item) *dynamodb.AttributeValue {
+	value := item[p[0].name]
+	for _, element := range p[1:] {
+		switch {
+		case value == nil:
+			return nil
+		case element.isIndex:
+			if element.index >= len(value.L) {
+				return nil
+			}
+			value = value.L[element.index]
+		default:
+			value = value.M[element.name]
+		}
+	}
+
+	return value
+}
+
+// set sets the attribute of the path, whose parent must exist. An index beyond the end of a list appends
+// the value to it.
+func (p documentPath) set(item Item, value *dynamodb.AttributeValue) error {
+	if len(p) == 1 {
+		item[p[0].name] = value
+		return nil
+	}
+
+	parent, last := p[:len(p)-1].get(item), p[len(p)-1]
+	switch {
+	case last.isIndex && attributeType(parent) == "L":
+		if last.index >= len(parent.L) {
+			parent.L = append(parent.L, value)
+		} else {
+			parent.L[last.index] = value
+		}
+	case !last.isIndex && attributeType(parent) == "M":
+		parent.M[last.name] = value
+	default:
+		return newValidationError("The document path provided in the update expression is invalid for update")
+	}
+
+	return nil
+}
+
+// overlaps reports whether either path is the other or an attribute of it
+func (p documentPath) overlaps(other documentPath) bool {
+	for i := 0; i < len(p) && i < len(other); i++ {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// remove removes the attribute of the path, if the item has it
+func (p documentPath) remove(item Item) {
+	if len(p) == 1 {
+		delete(item, p[0].name)
+		return
+	}
+
+	parent, last := p[:len(p)-1].get(item), p[len(p)-1]
+	switch {
+	case last.isIndex && attributeType(parent) == "L":
+		if last.index < len(parent.L) {
+			parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+		}
+	case !last.isIndex && attributeType(parent) == "M":
+		delete(parent.M, last.name)
+	}
+}
+
+// operand returns the value of an operand of an expression against an item, nil if it doesn't exist
+type operand func(item Item) (*dynamodb.AttributeValue, error)
+
+// condition evaluates a condition expression against an item
+type condition func(item Item) (bool, error)
+
+// updateAction evaluates an action of an update expression against the item as it was before the update
+// and applies it to the item being updated
+type updateAction func(old, item Item) error
+
+// parser parses an expression, substituting its names and values from the expression attribute names and
+// values
+type parser struct {
+	tokens []token
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func newParser(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*parser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens, names: names, values: values}, nil
+}
+
+// parseCondition parses a condition expression e.g. attribute_not_exists(id) AND #status = :status
+func parseCondition(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (condition, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	return cond, p.expectEnd()
+}
+
+// parseUpdate parses an update expression e.g. SET #count = #count + :one REMOVE obsolete, returning its
+// actions and the names of the attributes it updates
+func parseUpdate(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) ([]updateAction, []string, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actions, updated, paths := []updateAction{}, []string{}, []documentPath{}
+	for p.peek().kind != endToken {
+		clause := strings.ToUpper(p.next().text)
+		switch clause {
+		case "SET", "REMOVE", "ADD", "DELETE":
+		default:
+			return nil, nil, newValidationError("Invalid UpdateExpression: Syntax error; token: \"" + clause + "\"")
+		}
+
+		for {
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, other := range paths {
+				if path.overlaps(other) {
+					return nil, nil, newValidationError("Invalid UpdateExpression: Two document paths overlap with each other")
+				}
+			}
+			paths = append(paths, path)
+			updated = append(updated, path[0].name)
+
+			action, err := p.parseUpdateAction(clause, path)
+			if err != nil {
+				return nil, nil, err
+			}
+			actions = append(actions, action)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, nil, newValidationError("Invalid UpdateExpression: The expression can not be empty")
+	}
+
+	return actions, updated, nil
+}
+
+// parseProjection parses a projection expression, a list of document paths
+func parseProjection(expr string, names map[string]*string) ([]documentPath, error) {
+	p, err := newParser(expr, names, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []documentPath{}
+	for {
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	return paths, p.expectEnd()
+}
+
+func (p *parser) parseUpdateAction(clause string, path documentPath) (updateAction, error) {
+	switch clause {
+	case "SET":
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.parseSetValue()
+		if err != nil {
+			return nil, err
+		}
+		return func(old, item Item) error {
+			v, err := value(old)
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				return newValidationError("The provided expression refers to an attribute that does not exist in the item")
+			}
+			return path.set(item, copyAttributeValue(v))
+		}, nil
+	case "ADD", "DELETE":
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if clause == "ADD" {
+			return func(old, item Item) error {
+				return add(item, path, path.get(old), value)
+			}, nil
+		}
+		return func(old, item Item) error {
+			return deleteElements(item, path, path.get(old), value)
+		}, nil
+	}
+
+	return func(old, item Item) error {
+		path.remove(item)
+		return nil
+	}, nil
+}
+
+// add adds a number to a number attribute or elements to a set, setting the attribute if it doesn't exist
+func add(item Item, path documentPath, current, value *dynamodb.AttributeValue) error {
+	if current == nil {
+		return path.set(item, copyAttributeValue(value))
+	}
+
+	typ := attributeType(current)
+	switch {
+	case typ != attributeType(value):
+	case typ == "N":
+		sum, ok := addNumbers(aws.StringValue(current.N), aws.StringValue(value.N), false)
+		if !ok {
+			break
+		}
+		return path.set(item, &dynamodb.AttributeValue{N: aws.String(sum)})
+	case typ == "SS" || typ == "NS" || typ == "BS":
+		elements := setElements(current)
+		for _, element := range setElements(value) {
+			if !containsElement(elements, element) {
+				elements = append(elements, element)
+			}
+		}
+		return path.set(item, newSet(typ, elements))
+	}
+
+	return newValidationError("An operand in the update expression has an incorrect data type")
+}
+
+// deleteElements deletes elements from a set, removing the attribute if none remain
+func deleteElements(item Item, path documentPath, current, value *dynamodb.AttributeValue) error {
+	typ := attributeType(value)
+	if typ != "SS" && typ != "NS" && typ != "BS" {
+		return newValidationError("An operand in the update expression has an incorrect data type")
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	if attributeType(current) != typ {
+		return newValidationError("An operand in the update expression has an incorrect data type")
+	}
+
+	remove := setElements(value)
+	elements := []*dynamodb.AttributeValue{}
+	for _, element := range setElements(current) {
+		if !containsElement(remove, element) {
+			elements = append(elements, element)
+		}
+	}
+
+	if len(elements) == 0 {
+		path.remove(item)
+		return nil
+	}
+
+	return path.set(item, newSet(typ, elements))
+}
+
+func (p *parser) parseSetValue() (operand, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.peekPunct("+") && !p.peekPunct("-") {
+		return left, nil
+	}
+
+	negate := p.next().text == "-"
+	right, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item Item) (*dynamodb.AttributeValue, error) {
+		x, err := left(item)
+		if err != nil {
+			return nil, err
+		}
+		y, err := right(item)
+		if err != nil {
+			return nil, err
+		}
+
+		if attributeType(x) != "N" || attributeType(y) != "N" {
+			return nil, newValidationError("An operand in the update expression has an incorrect data type")
+		}
+
+		sum, ok := addNumbers(aws.StringValue(x.N), aws.StringValue(y.N), negate)
+		if !ok {
+			return nil, newValidationError("An operand in the update expression has an incorrect data type")
+		}
+
+		return &dynamodb.AttributeValue{N: aws.String(sum)}, nil
+	}, nil
+}
+
+func (p *parser) parseSetOperand() (operand, error) {
+	if p.peekFunction("if_not_exists") {
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(item Item) (*dynamodb.AttributeValue, error) {
+			if current := path.get(item); current != nil {
+				return current, nil
+			}
+			return value(item)
+		}, nil
+	}
+
+	if p.peekFunction("list_append") {
+		p.next()
+		p.next()
+		left, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(item Item) (*dynamodb.AttributeValue, error) {
+			x, err := left(item)
+			if err != nil {
+				return nil, err
+			}
+			y, err := right(item)
+			if err != nil {
+				return nil, err
+			}
+
+			if attributeType(x) != "L" || attributeType(y) != "L" {
+				return nil, newValidationError("An operand in the update expression has an incorrect data type")
+			}
+
+			return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, x.L...), y.L...)}, nil
+		}, nil
+	}
+
+	return p.parseOperand()
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = and(left, right)
+	}
+
+	return left, nil
+}
+
+func or(left, right condition) condition {
+	return func(item Item) (bool, error) {
+		ok, err := left(item)
+		if err != nil || ok {
+			return ok, err
+		}
+		return right(item)
+	}
+}
+
+func and(left, right condition) condition {
+	return func(item Item) (bool, error) {
+		ok, err := left(item)
+		if err != nil || !ok {
+			return ok, err
+		}
+		return right(item)
+	}
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if !p.acceptKeyword("NOT") {
+		return p.parsePrimary()
+	}
+
+	cond, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item Item) (bool, error) {
+		ok, err := cond(item)
+		return !ok, err
+	}, nil
+}
+
+func (p *parser) parsePrimary() (condition, error) {
+	if p.accept("(") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return cond, p.expect(")")
+	}
+
+	for _, function := range []string{"attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains"} {
+		if p.peekFunction(function) {
+			return p.parseFunction()
+		}
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, p.syntaxError()
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return and(comparison(left, ">=", low), comparison(left, "<=", high)), nil
+	case p.acceptKeyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var cond condition
+		for {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if cond == nil {
+				cond = comparison(left, "=", right)
+			} else {
+				cond = or(cond, comparison(left, "=", right))
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+		return cond, p.expect(")")
+	}
+
+	comparator := p.next()
+	switch comparator.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, p.syntaxError()
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparison(left, comparator.text, right), nil
+}
+
+// comparison compares two operands. A comparison of attributes that don't exist or can't be ordered is
+// false, other than the inequality of attributes.
+func comparison(left operand, comparator string, right operand) condition {
+	return func(item Item) (bool, error) {
+		x, err := left(item)
+		if err != nil {
+			return false, err
+		}
+		y, err := right(item)
+		if err != nil {
+			return false, err
+		}
+
+		switch comparator {
+		case "=":
+			return x != nil && y != nil && equal(x, y), nil
+		case "<>":
+			return x == nil || y == nil || !equal(x, y), nil
+		}
+
+		result, ok := compare(x, y)
+		if !ok {
+			return false, nil
+		}
+
+		switch comparator {
+		case "<":
+			return result < 0, nil
+		case "<=":
+			return result <= 0, nil
+		case ">":
+			return result > 0, nil
+		default:
+			return result >= 0, nil
+		}
+	}
+}
+
+func (p *parser) parseFunction() (condition, error) {
+	function := p.next().text
+	p.next()
+
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var argument operand
+	if function != "attribute_exists" && function != "attribute_not_exists" {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if argument, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return func(item Item) (bool, error) {
+		value := path.get(item)
+
+		var arg *dynamodb.AttributeValue
+		if argument != nil {
+			var err error
+			if arg, err = argument(item); err != nil {
+				return false, err
+			}
+		}
+
+		switch function {
+		case "attribute_exists":
+			return value != nil, nil
+		case "attribute_not_exists":
+			return value == nil, nil
+		case "attribute_type":
+			if attributeType(arg) != "S" {
+				return false, newValidationError("Invalid ConditionExpression: Incorrect operand type for operator or function; operator or function: attribute_type")
+			}
+			return value != nil && attributeType(value) == aws.StringValue(arg.S), nil
+		case "begins_with":
+			switch {
+			case attributeType(value) == "S" && attributeType(arg) == "S":
+				return strings.HasPrefix(aws.StringValue(value.S), aws.StringValue(arg.S)), nil
+			case attributeType(value) == "B" && attributeType(arg) == "B":
+				return strings.HasPrefix(string(value.B), string(arg.B)), nil
+			}
+			return false, nil
+		default:
+			switch attributeType(value) {
+			case "S":
+				return attributeType(arg) == "S" && strings.Contains(aws.StringValue(value.S), aws.StringValue(arg.S)), nil
+			case "SS", "NS", "BS":
+				return containsElement(setElements(value), arg), nil
+			case "L":
+				return containsElement(value.L, arg), nil
+			}
+			return false, nil
+		}
+	}, nil
+}
+
+// parseOperand parses a value, a path or the size of a path
+func (p *parser) parseOperand() (operand, error) {
+	if p.peek().kind == valueRefToken {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return func(Item) (*dynamodb.AttributeValue, error) {
+			return value, nil
+		}, nil
+	}
+
+	if p.peekFunction("size") {
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(item Item) (*dynamodb.AttributeValue, error) {
+			n, ok := size(path.get(item))
+			if !ok {
+				return nil, nil
+			}
+			return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(n))}, nil
+		}, nil
+	}
+
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item Item) (*dynamodb.AttributeValue, error) {
+		return path.get(item), nil
+	}, nil
+}
+
+func (p *parser) parseValue() (*dynamodb.AttributeValue, error) {
+	t := p.next()
+	if t.kind != valueRefToken {
+		return nil, p.syntaxErrorAt(t)
+	}
+
+	value, ok := p.values[t.text]
+	if !ok {
+		return nil, newValidationError("An expression attribute value used in expression is not defined; attribute value: " + t.text)
+	}
+
+	return value, nil
+}
+
+func (p *parser) parsePath() (documentPath, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	path := documentPath{{name: name}}
+	for {
+		switch {
+		case p.accept("."):
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, pathElement{name: name})
+		case p.accept("["):
+			t := p.next()
+			if t.kind != numberToken {
+				return nil, p.syntaxErrorAt(t)
+			}
+			index, _ := strconv.Atoi(t.text)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, pathElement{index: index, isIndex: true})
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *parser) parseName() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case nameToken:
+		return t.text, nil
+	case nameRefToken:
+		name, ok := p.names[t.text]
+		if !ok {
+			return "", newValidationError("An expression attribute name used in the document path is not defined; attribute name: " + t.text)
+		}
+		return aws.StringValue(name), nil
+	}
+
+	return "", p.syntaxErrorAt(t)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != endToken {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekPunct(text string) bool {
+	t := p.peek()
+	return t.kind == punctToken && t.text == text
+}
+
+// peekFunction reports whether the next tokens are a call of the function
+func (p *parser) peekFunction(name string) bool {
+	t := p.peek()
+	return t.kind == nameToken && t.text == name && p.tokens[p.pos+1].text == "("
+}
+
+func (p *parser) accept(text string) bool {
+	if p.peekPunct(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	t := p.peek()
+	if t.kind == nameToken && strings.EqualFold(t.text, keyword) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) expectEnd() error {
+	if p.peek().kind != endToken {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) syntaxError() error {
+	return p.syntaxErrorAt(p.peek())
+}
+
+func (p *parser) syntaxErrorAt(t token) error {
+	if t.kind == endToken {
+		return newValidationError("Invalid expression: Syntax error; token: <EOF>")
+	}
+	return newValidationError("Invalid expression: Syntax error; token: \"" + t.text + "\"")
+}
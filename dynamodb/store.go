@@ -0,0 +1,398 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+// ErrCodeValidationException is the code of the error of an invalid request e.g. a malformed expression
+const ErrCodeValidationException = "ValidationException"
+
+// Table defines a table by name and its key, a partition key and optional sort key
+type Table struct {
+	Name         string
+	PartitionKey string
+	SortKey      string
+}
+
+func (t Table) keyNames() []string {
+	if t.SortKey == "" {
+		return []string{t.PartitionKey}
+	}
+	return []string{t.PartitionKey, t.SortKey}
+}
+
+type table struct {
+	Table
+	items map[string]Item
+}
+
+// Store is an in-memory store of tables, which serves the item operations of the dynamodb client e.g. to
+// the DynamoDB service integration. Tables are created and seeded before executions and their items can
+// be inspected after.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+func NewStore() *Store {
+	return &Store{
+		tables: map[string]*table{},
+	}
+}
+
+// CreateTable creates an empty table, replacing any table of the same name
+func (s *Store) CreateTable(t Table) error {
+	if t.Name == "" || t.PartitionKey == "" {
+		return errors.New("a table must have a name and partition key")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tables[t.Name] = &table{Table: t, items: map[string]Item{}}
+	return nil
+}
+
+// Seed puts the items into the table
+func (s *Store) Seed(tableName string, items ...Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(tableName)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		key, err := t.key(item, false)
+		if err != nil {
+			return err
+		}
+		t.items[key] = item.copy()
+	}
+
+	return nil
+}
+
+// SeedJSON puts the items of a JSON array of items in DynamoDB JSON into the table e.g.
+// [{"id":{"S":"1"}}]
+func (s *Store) SeedJSON(tableName string, data []byte) error {
+	items := []Item{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return errors.Wrap(err, "error unmarshaling items")
+	}
+
+	return s.Seed(tableName, items...)
+}
+
+// Items returns the items of the table, ordered by key
+func (s *Store) Items(tableName string) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(t.items))
+	for key := range t.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]Item, len(keys))
+	for i, key := range keys {
+		items[i] = t.items[key].copy()
+	}
+
+	return items, nil
+}
+
+// Item returns the item of the key in the table, or nil if it doesn't exist
+func (s *Store) Item(tableName string, key Item) (Item, error) {
+	output, err := s.GetItem(&dynamodb.GetItemInput{TableName: aws.String(tableName), Key: key})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Item, nil
+}
+
+func (s *Store) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(aws.StringValue(input.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	item := t.items[key]
+	if item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	if input.ProjectionExpression != nil {
+		paths, err := parseProjection(aws.StringValue(input.ProjectionExpression), input.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.GetItemOutput{Item: project(item, paths)}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: item.copy()}, nil
+}
+
+func (s *Store) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(aws.StringValue(input.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Item, false)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	if err := checkCondition(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+
+	t.items[key] = Item(input.Item).copy()
+
+	output := &dynamodb.PutItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = old
+	}
+
+	return output, nil
+}
+
+// UpdateItem updates the item of the key, creating it if it doesn't exist. The values of the update
+// expression are evaluated against the item as it was before the update.
+func (s *Store) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(aws.StringValue(input.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	if err := checkCondition(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+
+	item := old.copy()
+	if item == nil {
+		item = Item(input.Key).copy()
+	}
+
+	updated := []string{}
+	if input.UpdateExpression != nil {
+		actions, names, err := parseUpdate(aws.StringValue(input.UpdateExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			if name == t.PartitionKey || name == t.SortKey {
+				return nil, newValidationError("Cannot update attribute " + name + ". This attribute is part of the key")
+			}
+		}
+
+		for _, action := range actions {
+			if err := action(old.copy(), item); err != nil {
+				return nil, err
+			}
+		}
+		updated = names
+	}
+
+	t.items[key] = item
+
+	output := &dynamodb.UpdateItemOutput{}
+	switch aws.StringValue(input.ReturnValues) {
+	case dynamodb.ReturnValueAllOld:
+		output.Attributes = old.copy()
+	case dynamodb.ReturnValueAllNew:
+		output.Attributes = item.copy()
+	case dynamodb.ReturnValueUpdatedOld:
+		output.Attributes = selectAttributes(old, updated)
+	case dynamodb.ReturnValueUpdatedNew:
+		output.Attributes = selectAttributes(item, updated)
+	}
+
+	return output, nil
+}
+
+func (s *Store) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(aws.StringValue(input.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	if err := checkCondition(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+
+	delete(t.items, key)
+
+	output := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = old
+	}
+
+	return output, nil
+}
+
+// table returns the table of the name. The caller must hold the lock.
+func (s *Store) table(name string) (*table, error) {
+	t, ok := s.tables[name]
+	if !ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found: Table: "+name+" not found", nil)
+	}
+
+	return t, nil
+}
+
+// key returns the key of an item, whose key attributes must be scalars. A key must have no other
+// attributes.
+func (t *table) key(item map[string]*dynamodb.AttributeValue, exact bool) (string, error) {
+	names := t.keyNames()
+	if exact && len(item) != len(names) {
+		return "", newValidationError("The provided key element does not match the schema")
+	}
+
+	for _, name := range names {
+		switch attributeType(item[name]) {
+		case "S", "N", "B":
+		default:
+			return "", newValidationError("The provided key element does not match the schema")
+		}
+	}
+
+	return keyString(item, names...), nil
+}
+
+// checkCondition returns ConditionalCheckFailedException if the item, nil if it doesn't exist, doesn't
+// satisfy the condition expression
+func checkCondition(expr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue, item Item) error {
+	if expr == nil {
+		return nil
+	}
+
+	cond, err := parseCondition(aws.StringValue(expr), names, values)
+	if err != nil {
+		return err
+	}
+
+	ok, err := cond(item)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	return nil
+}
+
+// project returns the attributes of the item at the paths
+func project(item Item, paths []documentPath) Item {
+	projected := Item{}
+	for _, path := range paths {
+		value := path.get(item)
+		if value == nil {
+			continue
+		}
+
+		parent := &dynamodb.AttributeValue{M: projected}
+		for i, element := range path {
+			if i == len(path)-1 {
+				setChild(parent, element, copyAttributeValue(value))
+				break
+			}
+
+			child := getChild(parent, element)
+			if child == nil {
+				child = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+				if path[i+1].isIndex {
+					child = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+				}
+				setChild(parent, element, child)
+			}
+			parent = child
+		}
+	}
+
+	return projected
+}
+
+// getChild returns the attribute of a projection at the element. Each projected list element is appended
+// to its list, in the order of the paths.
+func getChild(parent *dynamodb.AttributeValue, element pathElement) *dynamodb.AttributeValue {
+	if element.isIndex {
+		return nil
+	}
+
+	return parent.M[element.name]
+}
+
+func setChild(parent *dynamodb.AttributeValue, element pathElement, value *dynamodb.AttributeValue) {
+	if element.isIndex {
+		parent.L = append(parent.L, value)
+		return
+	}
+
+	parent.M[element.name] = value
+}
+
+// selectAttributes returns the named attributes of the item
+func selectAttributes(item Item, names []string) Item {
+	selected := Item{}
+	for _, name := range names {
+		if value, ok := item[name]; ok {
+			selected[name] = copyAttributeValue(value)
+		}
+	}
+
+	return selected
+}
+
+func newValidationError(message string) error {
+	return awserr.New(ErrCodeValidationException, message, nil)
+}
@@ -0,0 +1,224 @@
+// +build unit
+
+package dynamodb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/eggsbenjamin/stepFnLocal/dynamodb"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T, items string) *dynamodb.Store {
+	store := dynamodb.NewStore()
+	require.NoError(t, store.CreateTable(dynamodb.Table{Name: "orders", PartitionKey: "id"}))
+	require.NoError(t, store.SeedJSON("orders", []byte(items)))
+	return store
+}
+
+func item(t *testing.T, data string) dynamodb.Item {
+	item := dynamodb.Item{}
+	require.NoError(t, json.Unmarshal([]byte(data), &item))
+	return item
+}
+
+func requireAWSError(t *testing.T, code string, err error) {
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "expected an awserr.Error, got %v", err)
+	require.Equal(t, code, awsErr.Code())
+}
+
+func requireItemJSON(t *testing.T, expected string, item dynamodb.Item) {
+	data, err := json.Marshal(item)
+	require.NoError(t, err)
+	require.JSONEq(t, expected, string(data))
+}
+
+func TestStore(t *testing.T) {
+	const seed = `[{"id":{"S":"1"},"status":{"S":"NEW"},"count":{"N":"1"},"tags":{"SS":["a"]},"lines":{"L":[{"M":{"sku":{"S":"x"}}}]}}]`
+
+	t.Run("get item", func(t *testing.T) {
+		store := newStore(t, seed)
+
+		output, err := store.GetItem(&awsdynamodb.GetItemInput{
+			TableName: aws.String("orders"),
+			Key:       item(t, `{"id":{"S":"1"}}`),
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, seed[1:len(seed)-1], output.Item)
+
+		output, err = store.GetItem(&awsdynamodb.GetItemInput{
+			TableName:                aws.String("orders"),
+			Key:                      item(t, `{"id":{"S":"1"}}`),
+			ProjectionExpression:     aws.String("#s, lines[0].sku"),
+			ExpressionAttributeNames: map[string]*string{"#s": aws.String("status")},
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, `{"status":{"S":"NEW"},"lines":{"L":[{"M":{"sku":{"S":"x"}}}]}}`, output.Item)
+
+		output, err = store.GetItem(&awsdynamodb.GetItemInput{
+			TableName: aws.String("orders"),
+			Key:       item(t, `{"id":{"S":"2"}}`),
+		})
+		require.NoError(t, err)
+		require.Nil(t, output.Item)
+	})
+
+	t.Run("put item", func(t *testing.T) {
+		store := newStore(t, seed)
+
+		output, err := store.PutItem(&awsdynamodb.PutItemInput{
+			TableName:    aws.String("orders"),
+			Item:         item(t, `{"id":{"S":"1"},"status":{"S":"DONE"}}`),
+			ReturnValues: aws.String(awsdynamodb.ReturnValueAllOld),
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, seed[1:len(seed)-1], output.Attributes)
+
+		items, err := store.Items("orders")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		requireItemJSON(t, `{"id":{"S":"1"},"status":{"S":"DONE"}}`, items[0])
+	})
+
+	t.Run("conditions", func(t *testing.T) {
+		tests := map[string]bool{
+			"attribute_exists(id)":                    true,
+			"attribute_not_exists(id)":                false,
+			"#s = :new":                               true,
+			"#s <> :new":                              false,
+			"#count < :two AND #count >= :one":        true,
+			"#count BETWEEN :one AND :two":            true,
+			"#s IN (:done, :new)":                     true,
+			"NOT (#s = :new) OR size(tags) = :one":    true,
+			"begins_with(#s, :n)":                     true,
+			"contains(tags, :a) AND contains(#s, :n)": true,
+			"attribute_type(tags, :ss)":               true,
+			"lines[0].sku = :x":                       true,
+			"missing = :new":                          false,
+			"missing <> :new":                         true,
+		}
+
+		for expr, expected := range tests {
+			store := newStore(t, seed)
+			_, err := store.DeleteItem(&awsdynamodb.DeleteItemInput{
+				TableName:                aws.String("orders"),
+				Key:                      item(t, `{"id":{"S":"1"}}`),
+				ConditionExpression:      aws.String(expr),
+				ExpressionAttributeNames: map[string]*string{"#s": aws.String("status"), "#count": aws.String("count")},
+				ExpressionAttributeValues: item(t, `{
+					":new":{"S":"NEW"},":done":{"S":"DONE"},":n":{"S":"N"},":a":{"S":"a"},":x":{"S":"x"},
+					":one":{"N":"1"},":two":{"N":"2.0"},":ss":{"S":"SS"}
+				}`),
+			})
+
+			if expected {
+				require.NoError(t, err, expr)
+			} else {
+				requireAWSError(t, awsdynamodb.ErrCodeConditionalCheckFailedException, err)
+			}
+		}
+	})
+
+	t.Run("update item", func(t *testing.T) {
+		store := newStore(t, seed)
+
+		output, err := store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"1"}}`),
+			UpdateExpression:          aws.String("SET #s = :done, #count = #count + :inc, lines = list_append(lines, :lines), created = if_not_exists(created, :now) REMOVE obsolete ADD tags :tags"),
+			ConditionExpression:       aws.String("#s = :new"),
+			ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status"), "#count": aws.String("count")},
+			ExpressionAttributeValues: item(t, `{":done":{"S":"DONE"},":new":{"S":"NEW"},":inc":{"N":"1.5"},":lines":{"L":[{"S":"y"}]},":now":{"N":"100"},":tags":{"SS":["b"]}}`),
+			ReturnValues:              aws.String(awsdynamodb.ReturnValueUpdatedNew),
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, `{
+			"status":{"S":"DONE"},"count":{"N":"2.5"},"lines":{"L":[{"M":{"sku":{"S":"x"}}},{"S":"y"}]},
+			"created":{"N":"100"},"tags":{"SS":["a","b"]}
+		}`, output.Attributes)
+
+		output, err = store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"1"}}`),
+			UpdateExpression:          aws.String("DELETE tags :a"),
+			ExpressionAttributeValues: item(t, `{":a":{"SS":["a"]}}`),
+			ReturnValues:              aws.String(awsdynamodb.ReturnValueUpdatedOld),
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, `{"tags":{"SS":["a","b"]}}`, output.Attributes)
+
+		found, err := store.Item("orders", item(t, `{"id":{"S":"1"}}`))
+		require.NoError(t, err)
+		requireItemJSON(t, `{"tags":{"SS":["b"]}}`, dynamodb.Item{"tags": found["tags"]})
+
+		_, err = store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"1"}}`),
+			UpdateExpression:          aws.String("SET #s = :done"),
+			ConditionExpression:       aws.String("#s = :new"),
+			ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+			ExpressionAttributeValues: item(t, `{":done":{"S":"DONE"},":new":{"S":"NEW"}}`),
+		})
+		requireAWSError(t, awsdynamodb.ErrCodeConditionalCheckFailedException, err)
+
+		output, err = store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"2"}}`),
+			UpdateExpression:          aws.String("ADD visits :one"),
+			ExpressionAttributeValues: item(t, `{":one":{"N":"1"}}`),
+			ReturnValues:              aws.String(awsdynamodb.ReturnValueAllNew),
+		})
+		require.NoError(t, err)
+		requireItemJSON(t, `{"id":{"S":"2"},"visits":{"N":"1"}}`, output.Attributes)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		store := newStore(t, seed)
+
+		_, err := store.GetItem(&awsdynamodb.GetItemInput{TableName: aws.String("missing"), Key: item(t, `{"id":{"S":"1"}}`)})
+		requireAWSError(t, awsdynamodb.ErrCodeResourceNotFoundException, err)
+
+		_, err = store.GetItem(&awsdynamodb.GetItemInput{TableName: aws.String("orders"), Key: item(t, `{"other":{"S":"1"}}`)})
+		requireAWSError(t, dynamodb.ErrCodeValidationException, err)
+
+		_, err = store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"1"}}`),
+			UpdateExpression:          aws.String("SET id = :v"),
+			ExpressionAttributeValues: item(t, `{":v":{"S":"2"}}`),
+		})
+		requireAWSError(t, dynamodb.ErrCodeValidationException, err)
+
+		_, err = store.UpdateItem(&awsdynamodb.UpdateItemInput{
+			TableName:                 aws.String("orders"),
+			Key:                       item(t, `{"id":{"S":"1"}}`),
+			UpdateExpression:          aws.String("SET lines[0].sku = :v REMOVE lines"),
+			ExpressionAttributeValues: item(t, `{":v":{"S":"2"}}`),
+		})
+		requireAWSError(t, dynamodb.ErrCodeValidationException, err)
+
+		for _, expr := range []string{"#s =", "status = :undefined", "#undefined = :v", "status == :v"} {
+			_, err = store.DeleteItem(&awsdynamodb.DeleteItemInput{
+				TableName:                 aws.String("orders"),
+				Key:                       item(t, `{"id":{"S":"1"}}`),
+				ConditionExpression:       aws.String(expr),
+				ExpressionAttributeNames:  map[string]*string{"#s": aws.String("status")},
+				ExpressionAttributeValues: item(t, `{":v":{"S":"NEW"}}`),
+			})
+			requireAWSError(t, dynamodb.ErrCodeValidationException, err)
+		}
+	})
+
+	t.Run("numeric keys", func(t *testing.T) {
+		store := newStore(t, `[{"id":{"N":"1.0"}}]`)
+
+		found, err := store.Item("orders", item(t, `{"id":{"N":"1"}}`))
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	})
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+const (
+	// DefaultEventBusName is the name of the bus of entries without a bus name
+	DefaultEventBusName = "default"
+
+	ErrCodeValidationException = "ValidationException"
+	ErrCodeInvalidArgument     = "InvalidArgument"
+	ErrCodeMalformedDetail     = "MalformedDetail"
+
+	// maxEntries is the maximum number of entries of a request
+	maxEntries = 10
+)
+
+// Event is an event put on a bus
+type Event struct {
+	EventID      string
+	EventBusName string
+	Source       string
+	DetailType   string
+	Detail       string
+	Resources    []string
+	Time         time.Time
+}
+
+// FailureFn returns the error code and message of an entry to fail, or an empty code to put the entry
+type FailureFn func(entry PutEventsRequestEntry) (errorCode, errorMessage string)
+
+// Bus captures the events put on event buses in memory, to be inspected after an execution e.g. by the
+// EventBridge service integration. Entries can be failed with an injected FailureFn.
+type Bus struct {
+	mu      sync.Mutex
+	events  []Event
+	failure FailureFn
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// FailEntries fails the entries for which fn returns an error code, instead of putting them, replacing
+// any FailureFn. A nil fn puts every valid entry.
+func (b *Bus) FailEntries(fn FailureFn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failure = fn
+}
+
+// Events returns the events put on every bus, in the order they were put
+func (b *Bus) Events() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Event{}, b.events...)
+}
+
+// EventsOnBus returns the events put on the bus of the name, in the order they were put
+func (b *Bus) EventsOnBus(eventBusName string) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := []Event{}
+	for _, event := range b.events {
+		if event.EventBusName == eventBusName {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// PutEvents puts each entry on its bus. An invalid or injected failed entry fails without failing the
+// request, which is reflected in the failed entry count of the response.
+func (b *Bus) PutEvents(input *PutEventsInput) (*PutEventsOutput, error) {
+	if len(input.Entries) < 1 || len(input.Entries) > maxEntries {
+		return nil, awserr.New(ErrCodeValidationException, fmt.Sprintf("1 validation error detected: Value at 'entries' failed to satisfy constraint: Member must have length between 1 and %d", maxEntries), nil)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	output := &PutEventsOutput{
+		Entries: make([]*PutEventsResultEntry, 0, len(input.Entries)),
+	}
+
+	for _, entry := range input.Entries {
+		errorCode, errorMessage := validateEntry(entry)
+		if errorCode == "" && b.failure != nil {
+			errorCode, errorMessage = b.failure(*entry)
+		}
+
+		if errorCode != "" {
+			output.FailedEntryCount++
+			output.Entries = append(output.Entries, &PutEventsResultEntry{
+				ErrorCode:    aws.String(errorCode),
+				ErrorMessage: aws.String(errorMessage),
+			})
+			continue
+		}
+
+		event := Event{
+			EventID:      uuid.New(),
+			EventBusName: aws.StringValue(entry.EventBusName),
+			Source:       aws.StringValue(entry.Source),
+			DetailType:   aws.StringValue(entry.DetailType),
+			Detail:       aws.StringValue(entry.Detail),
+			Resources:    aws.StringValueSlice(entry.Resources),
+			Time:         aws.TimeValue(entry.Time),
+		}
+		if event.EventBusName == "" {
+			event.EventBusName = DefaultEventBusName
+		}
+		if event.Time.IsZero() {
+			event.Time = time.Now()
+		}
+
+		b.events = append(b.events, event)
+		output.Entries = append(output.Entries, &PutEventsResultEntry{
+			EventId: aws.String(event.EventID),
+		})
+	}
+
+	return output, nil
+}
+
+// validateEntry returns the error code and message of an invalid entry, which must have a source, a
+// detail type and a detail of a JSON object
+func validateEntry(entry *PutEventsRequestEntry) (errorCode, errorMessage string) {
+	if entry == nil {
+		return ErrCodeInvalidArgument, "Parameter Entry is not valid."
+	}
+
+	required := []struct {
+		name  string
+		value *string
+	}{
+		{"Source", entry.Source},
+		{"DetailType", entry.DetailType},
+		{"Detail", entry.Detail},
+	}
+	for _, param := range required {
+		if aws.StringValue(param.value) == "" {
+			return ErrCodeInvalidArgument, fmt.Sprintf("Parameter %s is not valid. Reason: %s is a required argument.", param.name, param.name)
+		}
+	}
+
+	detail := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(*entry.Detail), &detail); err != nil {
+		return ErrCodeMalformedDetail, "Detail is malformed."
+	}
+
+	return "", ""
+}
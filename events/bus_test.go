@@ -0,0 +1,101 @@
+// +build unit
+
+package events_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/eggsbenjamin/stepFnLocal/events"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(bus, detailType, detail string) *events.PutEventsRequestEntry {
+	return &events.PutEventsRequestEntry{
+		EventBusName: aws.String(bus),
+		Source:       aws.String("orders"),
+		DetailType:   aws.String(detailType),
+		Detail:       aws.String(detail),
+	}
+}
+
+func TestBus(t *testing.T) {
+	t.Run("put events", func(t *testing.T) {
+		bus := events.NewBus()
+
+		output, err := bus.PutEvents(&events.PutEventsInput{
+			Entries: []*events.PutEventsRequestEntry{
+				entry("", "created", `{"id":"1"}`),
+				entry("audit", "created", `{"id":"1"}`),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(0), output.FailedEntryCount)
+		require.Len(t, output.Entries, 2)
+
+		all := bus.Events()
+		require.Len(t, all, 2)
+		require.Equal(t, aws.StringValue(output.Entries[0].EventId), all[0].EventID)
+		require.Equal(t, events.DefaultEventBusName, all[0].EventBusName)
+		require.Equal(t, "orders", all[0].Source)
+		require.Equal(t, "created", all[0].DetailType)
+		require.Equal(t, `{"id":"1"}`, all[0].Detail)
+		require.False(t, all[0].Time.IsZero())
+
+		audit := bus.EventsOnBus("audit")
+		require.Len(t, audit, 1)
+		require.Equal(t, aws.StringValue(output.Entries[1].EventId), audit[0].EventID)
+	})
+
+	t.Run("failed entries", func(t *testing.T) {
+		bus := events.NewBus()
+		bus.FailEntries(func(entry events.PutEventsRequestEntry) (string, string) {
+			if aws.StringValue(entry.DetailType) == "cancelled" {
+				return "InternalFailure", "injected failure"
+			}
+			return "", ""
+		})
+
+		output, err := bus.PutEvents(&events.PutEventsInput{
+			Entries: []*events.PutEventsRequestEntry{
+				entry("", "created", `{"id":"1"}`),
+				entry("", "cancelled", `{"id":"1"}`),
+				entry("", "created", `not json`),
+				entry("", "", `{"id":"1"}`),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), output.FailedEntryCount)
+		require.NotNil(t, output.Entries[0].EventId)
+		require.Equal(t, "InternalFailure", aws.StringValue(output.Entries[1].ErrorCode))
+		require.Equal(t, "injected failure", aws.StringValue(output.Entries[1].ErrorMessage))
+		require.Equal(t, events.ErrCodeMalformedDetail, aws.StringValue(output.Entries[2].ErrorCode))
+		require.Equal(t, events.ErrCodeInvalidArgument, aws.StringValue(output.Entries[3].ErrorCode))
+		require.Len(t, bus.Events(), 1)
+
+		bus.FailEntries(nil)
+		output, err = bus.PutEvents(&events.PutEventsInput{
+			Entries: []*events.PutEventsRequestEntry{entry("", "cancelled", `{"id":"1"}`)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(0), output.FailedEntryCount)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		bus := events.NewBus()
+
+		for _, count := range []int{0, 11} {
+			entries := []*events.PutEventsRequestEntry{}
+			for i := 0; i < count; i++ {
+				entries = append(entries, entry("", "created", `{}`))
+			}
+
+			_, err := bus.PutEvents(&events.PutEventsInput{Entries: entries})
+			awsErr, ok := err.(awserr.Error)
+			require.True(t, ok, "expected an awserr.Error, got %v", err)
+			require.Equal(t, events.ErrCodeValidationException, awsErr.Code())
+		}
+		require.Empty(t, bus.Events())
+	})
+}
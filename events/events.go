@@ -0,0 +1,42 @@
+//go:generate mockgen -package events -source=events.go -destination events_mock.go
+
+package events
+
+import (
+	"time"
+)
+
+// Client defines the events client interface of the service integration
+type Client interface {
+	PutEvents(*PutEventsInput) (*PutEventsOutput, error)
+}
+
+// PutEventsInput is the request of PutEvents. The request and response of PutEvents are defined here,
+// rather than by the cloudwatchevents package of the SDK, as its entries have no event bus.
+type PutEventsInput struct {
+	Entries []*PutEventsRequestEntry `json:"Entries"`
+}
+
+// PutEventsRequestEntry is an event to put on a bus, the default bus if it has no bus name
+type PutEventsRequestEntry struct {
+	Detail       *string    `json:"Detail"`
+	DetailType   *string    `json:"DetailType"`
+	EventBusName *string    `json:"EventBusName"`
+	Resources    []*string  `json:"Resources"`
+	Source       *string    `json:"Source"`
+	Time         *time.Time `json:"Time"`
+	TraceHeader  *string    `json:"TraceHeader"`
+}
+
+// PutEventsOutput is the response of PutEvents, with a result entry for each request entry in order
+type PutEventsOutput struct {
+	Entries          []*PutEventsResultEntry `json:"Entries"`
+	FailedEntryCount int64                   `json:"FailedEntryCount"`
+}
+
+// PutEventsResultEntry is the result of putting an event, its ID or the error that failed it
+type PutEventsResultEntry struct {
+	ErrorCode    *string `json:"ErrorCode,omitempty"`
+	ErrorMessage *string `json:"ErrorMessage,omitempty"`
+	EventId      *string `json:"EventId,omitempty"`
+}
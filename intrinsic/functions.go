@@ -0,0 +1,430 @@
+package intrinsic
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	mathrand "math/rand"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// impl implements an intrinsic function against its resolved arguments
+type impl func(args []interface{}) (interface{}, error)
+
+// spec describes an intrinsic function
+type spec struct {
+	impl     impl
+	minArgs  int
+	maxArgs  int  // -1 for variadic
+	template bool // first argument is a States.Format style template
+}
+
+func (s spec) validateArity(count int) error {
+	if count < s.minArgs || (s.maxArgs >= 0 && count > s.maxArgs) {
+		return errors.Errorf("invalid number of arguments: %d", count)
+	}
+	return nil
+}
+
+// template is a raw string literal with its escape sequences intact
+type template string
+
+var functions = map[string]spec{
+	"States.Format":         {impl: format, minArgs: 1, maxArgs: -1, template: true},
+	"States.StringToJson":   {impl: stringToJSON, minArgs: 1, maxArgs: 1},
+	"States.JsonToString":   {impl: jsonToString, minArgs: 1, maxArgs: 1},
+	"States.Array":          {impl: array, minArgs: 0, maxArgs: -1},
+	"States.ArrayPartition": {impl: arrayPartition, minArgs: 2, maxArgs: 2},
+	"States.ArrayContains":  {impl: arrayContains, minArgs: 2, maxArgs: 2},
+	"States.ArrayRange":     {impl: arrayRange, minArgs: 3, maxArgs: 3},
+	"States.ArrayGetItem":   {impl: arrayGetItem, minArgs: 2, maxArgs: 2},
+	"States.ArrayLength":    {impl: arrayLength, minArgs: 1, maxArgs: 1},
+	"States.ArrayUnique":    {impl: arrayUnique, minArgs: 1, maxArgs: 1},
+	"States.Base64Encode":   {impl: base64Encode, minArgs: 1, maxArgs: 1},
+	"States.Base64Decode":   {impl: base64Decode, minArgs: 1, maxArgs: 1},
+	"States.Hash":           {impl: hashFn, minArgs: 2, maxArgs: 2},
+	"States.JsonMerge":      {impl: jsonMerge, minArgs: 3, maxArgs: 3},
+	"States.MathRandom":     {impl: mathRandom, minArgs: 2, maxArgs: 3},
+	"States.MathAdd":        {impl: mathAdd, minArgs: 2, maxArgs: 2},
+	"States.StringSplit":    {impl: stringSplit, minArgs: 2, maxArgs: 2},
+	"States.UUID":           {impl: uuid, minArgs: 0, maxArgs: 0},
+}
+
+func format(args []interface{}) (interface{}, error) {
+	var placeholders []string
+	var tmpl string
+	switch v := args[0].(type) {
+	case template:
+		tmpl = string(v)
+	case string:
+		// templates resolved from a path have no escape sequences
+		tmpl = strings.Replace(v, `\`, `\\`, -1)
+	default:
+		return nil, errors.New("template must be a string")
+	}
+
+	for _, arg := range args[1:] {
+		switch v := arg.(type) {
+		case string:
+			placeholders = append(placeholders, v)
+		case json.Number, bool, nil:
+			str, err := encode(v)
+			if err != nil {
+				return nil, err
+			}
+			placeholders = append(placeholders, string(str))
+		default:
+			return nil, errors.New("arguments must be strings, numbers, booleans or null")
+		}
+	}
+
+	var result strings.Builder
+	next := 0
+	for i := 0; i < len(tmpl); i++ {
+		switch {
+		case tmpl[i] == '\\' && i+1 < len(tmpl):
+			i++
+			result.WriteByte(tmpl[i])
+		case tmpl[i] == '{' && i+1 < len(tmpl) && tmpl[i+1] == '}':
+			if next >= len(placeholders) {
+				return nil, errors.New("more placeholders than arguments")
+			}
+			result.WriteString(placeholders[next])
+			next++
+			i++
+		default:
+			result.WriteByte(tmpl[i])
+		}
+	}
+
+	if next != len(placeholders) {
+		return nil, errors.New("more arguments than placeholders")
+	}
+
+	return result.String(), nil
+}
+
+func stringToJSON(args []interface{}) (interface{}, error) {
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("argument must be a string")
+	}
+
+	return decode([]byte(str))
+}
+
+func jsonToString(args []interface{}) (interface{}, error) {
+	str, err := encode(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return string(str), nil
+}
+
+func array(args []interface{}) (interface{}, error) {
+	return append([]interface{}{}, args...), nil
+}
+
+func arrayPartition(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("first argument must be an array")
+	}
+	size, err := toInt(args[1])
+	if err != nil || size <= 0 {
+		return nil, errors.New("chunk size must be a positive integer")
+	}
+
+	result := []interface{}{}
+	for i := 0; i < len(arr); i += size {
+		end := i + size
+		if end > len(arr) {
+			end = len(arr)
+		}
+		result = append(result, append([]interface{}{}, arr[i:end]...))
+	}
+
+	return result, nil
+}
+
+func arrayContains(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("first argument must be an array")
+	}
+
+	for _, item := range arr {
+		if equal(item, args[1]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func arrayRange(args []interface{}) (interface{}, error) {
+	start, err := toInt(args[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid start")
+	}
+	end, err := toInt(args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid end")
+	}
+	step, err := toInt(args[2])
+	if err != nil || step == 0 {
+		return nil, errors.New("step must be a non zero integer")
+	}
+
+	result := []interface{}{}
+	for i := start; (step > 0 && i <= end) || (step < 0 && i >= end); i += step {
+		result = append(result, json.Number(fmt.Sprint(i)))
+		if len(result) > 1000 {
+			return nil, errors.New("range exceeds 1000 items")
+		}
+	}
+
+	return result, nil
+}
+
+func arrayGetItem(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("first argument must be an array")
+	}
+	index, err := toInt(args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid index")
+	}
+	if index < 0 || index >= len(arr) {
+		return nil, errors.Errorf("index %d out of range", index)
+	}
+
+	return arr[index], nil
+}
+
+func arrayLength(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("argument must be an array")
+	}
+
+	return json.Number(fmt.Sprint(len(arr))), nil
+}
+
+func arrayUnique(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("argument must be an array")
+	}
+
+	result := []interface{}{}
+	for _, item := range arr {
+		duplicate := false
+		for _, existing := range result {
+			if equal(item, existing) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+func base64Encode(args []interface{}) (interface{}, error) {
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("argument must be a string")
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(str)), nil
+}
+
+func base64Decode(args []interface{}) (interface{}, error) {
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("argument must be a string")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64")
+	}
+
+	return string(decoded), nil
+}
+
+func hashFn(args []interface{}) (interface{}, error) {
+	var data string
+	switch v := args[0].(type) {
+	case string:
+		data = v
+	default:
+		encoded, err := encode(v)
+		if err != nil {
+			return nil, err
+		}
+		data = string(encoded)
+	}
+
+	algorithm, ok := args[1].(string)
+	if !ok {
+		return nil, errors.New("algorithm must be a string")
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "MD5":
+		h = md5.New()
+	case "SHA-1":
+		h = sha1.New()
+	case "SHA-256":
+		h = sha256.New()
+	case "SHA-384":
+		h = sha512.New384()
+	case "SHA-512":
+		h = sha512.New()
+	default:
+		return nil, errors.Errorf("unsupported algorithm '%s'", algorithm)
+	}
+
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func jsonMerge(args []interface{}) (interface{}, error) {
+	left, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("first argument must be an object")
+	}
+	right, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("second argument must be an object")
+	}
+	if deep, ok := args[2].(bool); !ok || deep {
+		return nil, errors.New("only shallow merges are supported, the third argument must be false")
+	}
+
+	result := map[string]interface{}{}
+	for k, v := range left {
+		result[k] = v
+	}
+	for k, v := range right {
+		result[k] = v
+	}
+
+	return result, nil
+}
+
+func mathRandom(args []interface{}) (interface{}, error) {
+	start, err := toInt(args[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid start")
+	}
+	end, err := toInt(args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid end")
+	}
+	if end <= start {
+		return nil, errors.New("end must be greater than start")
+	}
+
+	rnd := mathrand.Intn
+	if len(args) == 3 {
+		seed, err := toInt(args[2])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid seed")
+		}
+		rnd = mathrand.New(mathrand.NewSource(int64(seed))).Intn
+	}
+
+	return json.Number(fmt.Sprint(start + rnd(end-start))), nil
+}
+
+func mathAdd(args []interface{}) (interface{}, error) {
+	a, err := toInt(args[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid first argument")
+	}
+	b, err := toInt(args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid second argument")
+	}
+
+	return json.Number(fmt.Sprint(a + b)), nil
+}
+
+func stringSplit(args []interface{}) (interface{}, error) {
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("first argument must be a string")
+	}
+	delimiters, ok := args[1].(string)
+	if !ok {
+		return nil, errors.New("second argument must be a string")
+	}
+
+	result := []interface{}{}
+	for _, part := range strings.FieldsFunc(str, func(r rune) bool {
+		return strings.ContainsRune(delimiters, r)
+	}) {
+		result = append(result, part)
+	}
+
+	return result, nil
+}
+
+func uuid([]interface{}) (interface{}, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "error generating uuid")
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func toInt(value interface{}) (int, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+
+	f, err := number.Float64()
+	if err != nil || f != math.Trunc(f) {
+		return 0, errors.Errorf("'%s' is not an integer", number)
+	}
+
+	return int(f), nil
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,328 @@
+// Package intrinsic implements the AWS states language intrinsic functions e.g. States.Format('{}', $.name)
+package intrinsic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
+	"github.com/pkg/errors"
+)
+
+const prefix = "States."
+
+// Function represents a parsed intrinsic function invocation
+type Function interface {
+	Evaluate(input []byte) ([]byte, error)
+	// EvaluateDocument is equivalent to Evaluate for an input that has already been parsed. Path arguments
+	// may reference the document's variables.
+	EvaluateDocument(doc *jsonpath.Document) ([]byte, error)
+}
+
+// IsFunction reports whether the given string is an intrinsic function invocation rather than a path.
+func IsFunction(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), prefix)
+}
+
+// NewFunction parses an intrinsic function invocation
+func NewFunction(input string) (Function, error) {
+	p := &parser{input: input}
+	fn, err := p.parseFunction()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid intrinsic function '%s'", input)
+	}
+
+	p.skipWhitespace()
+	if p.pos != len(p.input) {
+		return nil, errors.Errorf("invalid intrinsic function '%s': unexpected '%s'", input, p.input[p.pos:])
+	}
+
+	return fn, nil
+}
+
+// Error is returned when evaluating an intrinsic function fails at runtime
+type Error struct {
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+func newError(format string, args ...interface{}) error {
+	return Error{
+		Message: errors.Errorf(format, args...).Error(),
+	}
+}
+
+// argument is a single intrinsic function argument which resolves to a value against a state input
+type argument interface {
+	resolve(doc *jsonpath.Document) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) resolve(*jsonpath.Document) (interface{}, error) {
+	return l.value, nil
+}
+
+// stringLiteral is a quoted string argument. The raw form retains escape sequences so that
+// States.Format can distinguish escaped braces from placeholders.
+type stringLiteral struct {
+	value string
+	raw   string
+}
+
+func (s stringLiteral) resolve(*jsonpath.Document) (interface{}, error) {
+	return s.value, nil
+}
+
+type path struct {
+	exp jsonpath.Expression
+	raw string
+}
+
+func (p path) resolve(doc *jsonpath.Document) (interface{}, error) {
+	result, err := p.exp.SearchDocument(doc)
+	if err != nil {
+		return nil, newError("error resolving path '%s': %s", p.raw, err)
+	}
+
+	return decode(result)
+}
+
+type function struct {
+	name string
+	spec spec
+	args []argument
+}
+
+func (f function) resolve(doc *jsonpath.Document) (interface{}, error) {
+	args := make([]interface{}, len(f.args))
+	for i, arg := range f.args {
+		if str, ok := arg.(stringLiteral); ok && i == 0 && f.spec.template {
+			args[i] = template(str.raw)
+			continue
+		}
+
+		value, err := arg.resolve(doc)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	result, err := f.spec.impl(args)
+	if err != nil {
+		return nil, newError("%s: %s", f.name, err)
+	}
+
+	return result, nil
+}
+
+func (f function) Evaluate(input []byte) ([]byte, error) {
+	doc, err := jsonpath.NewDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.EvaluateDocument(doc)
+}
+
+func (f function) EvaluateDocument(doc *jsonpath.Document) ([]byte, error) {
+	result, err := f.resolve(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(result)
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parseFunction() (function, error) {
+	p.skipWhitespace()
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '(' {
+		p.pos++
+	}
+	name := strings.TrimSpace(p.input[start:p.pos])
+
+	spec, ok := functions[name]
+	if !ok {
+		return function{}, errors.Errorf("unknown function '%s'", name)
+	}
+
+	if p.pos == len(p.input) {
+		return function{}, errors.New("missing '('")
+	}
+	p.pos++ // (
+
+	args := []argument{}
+	p.skipWhitespace()
+	if p.peek() == ')' {
+		p.pos++
+		return function{name: name, spec: spec, args: args}, spec.validateArity(len(args))
+	}
+
+	for {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return function{}, err
+		}
+		args = append(args, arg)
+
+		p.skipWhitespace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return function{name: name, spec: spec, args: args}, spec.validateArity(len(args))
+		default:
+			return function{}, errors.Errorf("expected ',' or ')' at position %d", p.pos)
+		}
+	}
+}
+
+func (p *parser) parseArgument() (argument, error) {
+	p.skipWhitespace()
+
+	switch c := p.peek(); {
+	case c == '\'':
+		return p.parseString()
+	case c == '$':
+		raw := p.scanPath()
+		exp, err := jsonpath.NewExpression(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path '%s'", raw)
+		}
+		return path{exp: exp, raw: raw}, nil
+	case strings.HasPrefix(p.input[p.pos:], prefix):
+		return p.parseFunction()
+	default:
+		return p.parseLiteral()
+	}
+}
+
+func (p *parser) parseString() (stringLiteral, error) {
+	start := p.pos
+	p.pos++ // opening quote
+
+	var buf bytes.Buffer
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch c {
+		case '\\':
+			if p.pos+1 == len(p.input) {
+				return stringLiteral{}, errors.New("unterminated escape sequence")
+			}
+			buf.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case '\'':
+			p.pos++
+			return stringLiteral{
+				value: buf.String(),
+				raw:   p.input[start+1 : p.pos-1],
+			}, nil
+		default:
+			buf.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	return stringLiteral{}, errors.New("unterminated string literal")
+}
+
+// scanPath consumes a path argument up to the next top level ',' or ')'
+func (p *parser) scanPath() string {
+	start := p.pos
+	depth := 0
+	var quote byte
+	for ; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case (c == ']' || c == ')') && depth > 0:
+			depth--
+		case (c == ',' || c == ')') && depth == 0:
+			return strings.TrimSpace(p.input[start:p.pos])
+		}
+	}
+
+	return strings.TrimSpace(p.input[start:p.pos])
+}
+
+func (p *parser) parseLiteral() (argument, error) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != ',' && p.input[p.pos] != ')' {
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.input[start:p.pos])
+
+	switch raw {
+	case "true":
+		return literal{true}, nil
+	case "false":
+		return literal{false}, nil
+	case "null":
+		return literal{nil}, nil
+	}
+
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		return nil, errors.Errorf("invalid argument '%s'", raw)
+	}
+
+	return literal{json.Number(raw)}, nil
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) skipWhitespace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func decode(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Wrap(err, "error decoding json")
+	}
+
+	return value, nil
+}
+
+func encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, errors.Wrap(err, "error encoding json")
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
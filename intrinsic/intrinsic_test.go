@@ -0,0 +1,109 @@
+// +build unit
+
+package intrinsic_test
+
+import (
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/intrinsic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunction(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			title string
+			input string
+		}{
+			{"unknown function", "States.Unknown()"},
+			{"missing parenthesis", "States.Format('test'"},
+			{"unterminated string", "States.Format('test)"},
+			{"invalid path", "States.Format('{}', $invalid[)"},
+			{"invalid argument", "States.Format('{}', invalid)"},
+			{"too few arguments", "States.ArrayGetItem($.items)"},
+			{"too many arguments", "States.UUID(1)"},
+			{"trailing input", "States.UUID() extra"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				_, err := intrinsic.NewFunction(tt.input)
+				require.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Evaluate", func(t *testing.T) {
+		input := []byte(`{"name":"world","count":3,"items":["a","b","a"],"nested":{"key":"value"},"json":"{\"a\":1}"}`)
+
+		tests := []struct {
+			title          string
+			function       string
+			expectedOutput string
+		}{
+			{"Format", `States.Format('hello {}, you have {} items', $.name, $.count)`, `"hello world, you have 3 items"`},
+			{"Format escaped braces", `States.Format('\{\} {}', $.name)`, `"{} world"`},
+			{"Format escaped quote", `States.Format('it\'s {}', $.name)`, `"it's world"`},
+			{"StringToJson", `States.StringToJson($.json)`, `{"a":1}`},
+			{"JsonToString", `States.JsonToString($.nested)`, `"{\"key\":\"value\"}"`},
+			{"Array", `States.Array('a', 1, true, null, $.nested)`, `["a",1,true,null,{"key":"value"}]`},
+			{"ArrayPartition", `States.ArrayPartition($.items, 2)`, `[["a","b"],["a"]]`},
+			{"ArrayContains", `States.ArrayContains($.items, 'b')`, `true`},
+			{"ArrayRange", `States.ArrayRange(1, 9, 2)`, `[1,3,5,7,9]`},
+			{"ArrayGetItem", `States.ArrayGetItem($.items, 1)`, `"b"`},
+			{"ArrayLength", `States.ArrayLength($.items)`, `3`},
+			{"ArrayUnique", `States.ArrayUnique($.items)`, `["a","b"]`},
+			{"Base64Encode", `States.Base64Encode('hello')`, `"aGVsbG8="`},
+			{"Base64Decode", `States.Base64Decode('aGVsbG8=')`, `"hello"`},
+			{"Hash", `States.Hash('hello', 'SHA-256')`, `"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"`},
+			{"JsonMerge", `States.JsonMerge($.nested, States.StringToJson($.json), false)`, `{"key":"value","a":1}`},
+			{"MathRandom seeded", `States.MathRandom(1, 2, 1234)`, `1`},
+			{"MathAdd", `States.MathAdd($.count, -1)`, `2`},
+			{"StringSplit", `States.StringSplit('a,b;c', ',;')`, `["a","b","c"]`},
+			{"nested functions", `States.Format('{}', States.ArrayLength(States.Array(1, 2)))`, `"2"`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				fn, err := intrinsic.NewFunction(tt.function)
+				require.NoError(t, err)
+
+				result, err := fn.Evaluate(input)
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(result))
+			})
+		}
+
+		t.Run("UUID", func(t *testing.T) {
+			fn, err := intrinsic.NewFunction("States.UUID()")
+			require.NoError(t, err)
+
+			result, err := fn.Evaluate(input)
+			require.NoError(t, err)
+			require.Regexp(t, `^"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"$`, string(result))
+		})
+
+		t.Run("errors", func(t *testing.T) {
+			tests := []struct {
+				title    string
+				function string
+			}{
+				{"path not found", `States.Format('{}', $.missing)`},
+				{"placeholder count mismatch", `States.Format('{} {}', $.name)`},
+				{"index out of range", `States.ArrayGetItem($.items, 5)`},
+				{"invalid type", `States.ArrayLength($.name)`},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					fn, err := intrinsic.NewFunction(tt.function)
+					require.NoError(t, err)
+
+					_, err = fn.Evaluate(input)
+					require.Error(t, err)
+					require.IsType(t, intrinsic.Error{}, err)
+				})
+			}
+		})
+	})
+}
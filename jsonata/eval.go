@@ -0,0 +1,649 @@
+package jsonata
+
+import (
+	"math"
+	"reflect"
+	"sort"
+)
+
+// sequence is the result of evaluating a path. Unlike an array in the input, a sequence with a single
+// value collapses to that value and a sequence of arrays is flattened.
+type sequence []interface{}
+
+// environment holds variable bindings. Blocks and function calls create a new environment which can see
+// the bindings of their parent.
+type environment struct {
+	vars   map[string]interface{}
+	parent *environment
+	root   interface{}
+}
+
+func newEnvironment(parent *environment) *environment {
+	env := &environment{
+		vars:   map[string]interface{}{},
+		parent: parent,
+	}
+	if parent != nil {
+		env.root = parent.root
+	}
+	return env
+}
+
+func (e *environment) lookup(name string) (interface{}, bool) {
+	for env := e; env != nil; env = env.parent {
+		if v, ok := env.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (l literal) eval(_ interface{}, _ *environment) (interface{}, error) {
+	return l.value, nil
+}
+
+func (n name) eval(input interface{}, _ *environment) (interface{}, error) {
+	if arr, ok := asArray(input); ok {
+		result := sequence{}
+		for _, item := range arr {
+			v, _ := n.eval(item, nil)
+			result = appendFlattened(result, v)
+		}
+		return result, nil
+	}
+
+	obj, ok := input.(map[string]interface{})
+	if !ok {
+		return Undefined, nil
+	}
+
+	v, ok := obj[n.field]
+	if !ok {
+		return Undefined, nil
+	}
+	return v, nil
+}
+
+func (v variable) eval(input interface{}, env *environment) (interface{}, error) {
+	switch v.name {
+	case "":
+		return input, nil
+	case "$":
+		return env.root, nil
+	}
+
+	if value, ok := env.lookup(v.name); ok {
+		return value, nil
+	}
+	if fn, ok := functions[v.name]; ok {
+		return fn, nil
+	}
+	return Undefined, nil
+}
+
+func (wildcard) eval(input interface{}, _ *environment) (interface{}, error) {
+	result := sequence{}
+	for _, item := range items(input) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = appendFlattened(result, obj[key])
+		}
+	}
+	return result, nil
+}
+
+func (p path) eval(input interface{}, env *environment) (interface{}, error) {
+	current, err := p.steps[0].eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, step := range p.steps[1:] {
+		result := sequence{}
+		for _, item := range items(current) {
+			v, err := step.eval(item, env)
+			if err != nil {
+				return nil, err
+			}
+			if v == Undefined {
+				continue
+			}
+			result = append(result, v)
+		}
+
+		// an array selected by the last step is returned as is rather than flattened
+		if i == len(p.steps)-2 && len(result) == 1 {
+			if arr, ok := result[0].([]interface{}); ok {
+				return arr, nil
+			}
+		}
+
+		flattened := sequence{}
+		for _, v := range result {
+			flattened = appendFlattened(flattened, v)
+		}
+		current = flattened
+	}
+
+	return current, nil
+}
+
+func (p predicate) eval(input interface{}, env *environment) (interface{}, error) {
+	v, err := p.expr.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := items(v)
+	result := sequence{}
+	for i, item := range candidates {
+		match, err := p.pred.eval(item, env)
+		if err != nil {
+			return nil, err
+		}
+
+		if indices, ok := numbers(collapse(match)); ok {
+			for _, index := range indices {
+				index = math.Floor(index)
+				if index < 0 {
+					index += float64(len(candidates))
+				}
+				if int(index) == i {
+					result = append(result, item)
+					break
+				}
+			}
+			continue
+		}
+
+		if truthy(match) {
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+func (b binary) eval(input interface{}, env *environment) (interface{}, error) {
+	left, err := b.left.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+	left = collapse(left)
+
+	switch b.op {
+	case "and":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := b.right.eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(collapse(right)), nil
+	case "or":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := b.right.eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(collapse(right)), nil
+	}
+
+	right, err := b.right.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+	right = collapse(right)
+
+	switch b.op {
+	case "+", "-", "*", "/", "%":
+		return arithmetic(b.op, left, right)
+	case "&":
+		l, err := stringify(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := stringify(right)
+		if err != nil {
+			return nil, err
+		}
+		return l + r, nil
+	case "=":
+		if left == Undefined || right == Undefined {
+			return false, nil
+		}
+		return deepEqual(left, right), nil
+	case "!=":
+		if left == Undefined || right == Undefined {
+			return false, nil
+		}
+		return !deepEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		return comparison(b.op, left, right)
+	case "in":
+		if left == Undefined || right == Undefined {
+			return false, nil
+		}
+		for _, item := range items(right) {
+			if deepEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return nil, newError("unsupported operator '%s'", b.op)
+}
+
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	if left == Undefined || right == Undefined {
+		return Undefined, nil
+	}
+
+	l, ok := left.(float64)
+	if !ok {
+		return nil, newError("the left side of the '%s' operator must evaluate to a number", op)
+	}
+	r, ok := right.(float64)
+	if !ok {
+		return nil, newError("the right side of the '%s' operator must evaluate to a number", op)
+	}
+
+	var result float64
+	switch op {
+	case "+":
+		result = l + r
+	case "-":
+		result = l - r
+	case "*":
+		result = l * r
+	case "/":
+		result = l / r
+	case "%":
+		result = math.Mod(l, r)
+	}
+
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil, newError("number out of range")
+	}
+	return result, nil
+}
+
+func comparison(op string, left, right interface{}) (interface{}, error) {
+	if left == Undefined || right == Undefined {
+		return false, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, newError("the values either side of the '%s' operator must be of the same type", op)
+		}
+		cmp = compareFloats(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, newError("the values either side of the '%s' operator must be of the same type", op)
+		}
+		cmp = compareStrings(l, r)
+	default:
+		return nil, newError("the values either side of the '%s' operator must be numbers or strings", op)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+func (n negation) eval(input interface{}, env *environment) (interface{}, error) {
+	v, err := n.expr.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	v = collapse(v)
+	if v == Undefined {
+		return Undefined, nil
+	}
+
+	f, ok := v.(float64)
+	if !ok {
+		return nil, newError("cannot negate a non-numeric value")
+	}
+	return -f, nil
+}
+
+func (a arrayConstructor) eval(input interface{}, env *environment) (interface{}, error) {
+	result := []interface{}{}
+	for _, item := range a.items {
+		v, err := item.eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+
+		switch value := v.(type) {
+		case sequence:
+			// ranges and paths contribute their values rather than a nested array
+			result = append(result, value...)
+		default:
+			if v != Undefined {
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
+
+// maxRange is the largest number of values a range can produce
+const maxRange = 10000000
+
+func (r rangeOp) eval(input interface{}, env *environment) (interface{}, error) {
+	from, err := r.from.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.to.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to = collapse(from), collapse(to)
+	if from == Undefined || to == Undefined {
+		return sequence{}, nil
+	}
+
+	start, ok := from.(float64)
+	if !ok || start != math.Trunc(start) {
+		return nil, newError("the start of a range must be an integer")
+	}
+	end, ok := to.(float64)
+	if !ok || end != math.Trunc(end) {
+		return nil, newError("the end of a range must be an integer")
+	}
+	if end-start > maxRange {
+		return nil, newError("a range can't contain more than %d values", maxRange)
+	}
+
+	result := sequence{}
+	for i := start; i <= end; i++ {
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func (o objectConstructor) eval(input interface{}, env *environment) (interface{}, error) {
+	result := map[string]interface{}{}
+	for i := range o.keys {
+		k, err := o.keys[i].eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := collapse(k).(string)
+		if !ok {
+			return nil, newError("object keys must evaluate to strings")
+		}
+
+		v, err := o.values[i].eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+		if v = collapse(v); v != Undefined {
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+func (b block) eval(input interface{}, env *environment) (interface{}, error) {
+	scope := newEnvironment(env)
+
+	var result interface{} = Undefined
+	for _, expr := range b.exprs {
+		v, err := expr.eval(input, scope)
+		if err != nil {
+			return nil, err
+		}
+		result = v
+	}
+	return result, nil
+}
+
+func (c condition) eval(input interface{}, env *environment) (interface{}, error) {
+	cond, err := c.cond.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(collapse(cond)) {
+		return c.then.eval(input, env)
+	}
+	if c.otherwise == nil {
+		return Undefined, nil
+	}
+	return c.otherwise.eval(input, env)
+}
+
+func (b bind) eval(input interface{}, env *environment) (interface{}, error) {
+	v, err := b.value.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	env.vars[b.name] = collapse(v)
+	return v, nil
+}
+
+func (c call) eval(input interface{}, env *environment) (interface{}, error) {
+	fn, err := c.fn.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, len(c.args))
+	for _, arg := range c.args {
+		v, err := arg.eval(input, env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, collapse(v))
+	}
+
+	switch f := fn.(type) {
+	case builtin:
+		if len(args) == 0 && f.contextArg {
+			args = append(args, collapse(input))
+		}
+		return f.fn(args)
+	case lambda:
+		return f.call(args)
+	}
+
+	if v, ok := c.fn.(variable); ok {
+		return nil, newError("attempted to invoke a non-function '$%s'", v.name)
+	}
+	return nil, newError("attempted to invoke a non-function")
+}
+
+// lambda is a user defined function which closes over the input and environment it was defined in
+type lambda struct {
+	def   lambdaDef
+	input interface{}
+	env   *environment
+}
+
+func (l lambdaDef) eval(input interface{}, env *environment) (interface{}, error) {
+	return lambda{def: l, input: input, env: env}, nil
+}
+
+func (l lambda) call(args []interface{}) (interface{}, error) {
+	scope := newEnvironment(l.env)
+	for i, param := range l.def.params {
+		if i < len(args) {
+			scope.vars[param] = args[i]
+		} else {
+			scope.vars[param] = Undefined
+		}
+	}
+
+	v, err := l.def.body.eval(l.input, scope)
+	if err != nil {
+		return nil, err
+	}
+	return collapse(v), nil
+}
+
+// apply calls a function value, either a builtin or a lambda, with as many of the arguments as it accepts
+func apply(fn interface{}, args ...interface{}) (interface{}, error) {
+	switch f := fn.(type) {
+	case builtin:
+		return f.fn(args)
+	case lambda:
+		if len(f.def.params) < len(args) {
+			args = args[:len(f.def.params)]
+		}
+		return f.call(args)
+	}
+	return nil, newError("argument must be a function")
+}
+
+// collapse converts a sequence into its value. An empty sequence is undefined and a sequence of one value
+// is that value.
+func collapse(v interface{}) interface{} {
+	seq, ok := v.(sequence)
+	if !ok {
+		return v
+	}
+
+	switch len(seq) {
+	case 0:
+		return Undefined
+	case 1:
+		return seq[0]
+	}
+	return []interface{}(seq)
+}
+
+// items returns the values an array or sequence contains, or the value itself
+func items(v interface{}) []interface{} {
+	if arr, ok := asArray(v); ok {
+		return arr
+	}
+	if v == Undefined {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+func asArray(v interface{}) ([]interface{}, bool) {
+	switch arr := v.(type) {
+	case sequence:
+		return arr, true
+	case []interface{}:
+		return arr, true
+	}
+	return nil, false
+}
+
+func appendFlattened(seq sequence, v interface{}) sequence {
+	if v == Undefined {
+		return seq
+	}
+	if arr, ok := asArray(v); ok {
+		for _, item := range arr {
+			if item != Undefined {
+				seq = append(seq, item)
+			}
+		}
+		return seq
+	}
+	return append(seq, v)
+}
+
+// numbers returns the value as a list of numbers if it's a number or an array of numbers
+func numbers(v interface{}) ([]float64, bool) {
+	if f, ok := v.(float64); ok {
+		return []float64{f}, true
+	}
+
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil, false
+	}
+
+	result := []float64{}
+	for _, item := range arr {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, f)
+	}
+	return result, true
+}
+
+// truthy casts a value to a boolean using the JSONata rules
+func truthy(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		return value != ""
+	case float64:
+		return value != 0
+	case []interface{}:
+		for _, item := range value {
+			if truthy(item) {
+				return true
+			}
+		}
+		return false
+	case sequence:
+		return truthy([]interface{}(value))
+	case map[string]interface{}:
+		return len(value) > 0
+	}
+	return false
+}
+
+func deepEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
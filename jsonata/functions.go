@@ -0,0 +1,1020 @@
+package jsonata
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	mathrand "math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// builtin is a function provided by the implementation. Functions with contextArg set are passed the
+// context value when called without arguments e.g. $uppercase() is equivalent to $uppercase($).
+type builtin struct {
+	fn         func(args []interface{}) (interface{}, error)
+	contextArg bool
+}
+
+var functions map[string]builtin
+
+func init() {
+	// assigned in init as $map etc. call functions which refer back to this map
+	functions = map[string]builtin{
+		// string
+		"string":          {fn: fnString, contextArg: true},
+		"length":          {fn: fnLength, contextArg: true},
+		"substring":       {fn: fnSubstring},
+		"substringBefore": {fn: fnSubstringBefore},
+		"substringAfter":  {fn: fnSubstringAfter},
+		"uppercase":       {fn: fnUppercase, contextArg: true},
+		"lowercase":       {fn: fnLowercase, contextArg: true},
+		"trim":            {fn: fnTrim, contextArg: true},
+		"contains":        {fn: fnContains},
+		"split":           {fn: fnSplit},
+		"join":            {fn: fnJoin},
+		"replace":         {fn: fnReplace},
+		"base64encode":    {fn: fnBase64Encode, contextArg: true},
+		"base64decode":    {fn: fnBase64Decode, contextArg: true},
+		// numeric
+		"number":  {fn: fnNumber, contextArg: true},
+		"abs":     {fn: fnAbs, contextArg: true},
+		"floor":   {fn: fnFloor, contextArg: true},
+		"ceil":    {fn: fnCeil, contextArg: true},
+		"round":   {fn: fnRound},
+		"power":   {fn: fnPower},
+		"sqrt":    {fn: fnSqrt, contextArg: true},
+		"sum":     {fn: fnSum},
+		"max":     {fn: fnMax},
+		"min":     {fn: fnMin},
+		"average": {fn: fnAverage},
+		// boolean
+		"boolean": {fn: fnBoolean, contextArg: true},
+		"not":     {fn: fnNot, contextArg: true},
+		"exists":  {fn: fnExists},
+		// array
+		"count":    {fn: fnCount},
+		"append":   {fn: fnAppend},
+		"sort":     {fn: fnSort},
+		"reverse":  {fn: fnReverse},
+		"distinct": {fn: fnDistinct},
+		// object
+		"keys":   {fn: fnKeys},
+		"lookup": {fn: fnLookup},
+		"merge":  {fn: fnMerge},
+		"type":   {fn: fnType},
+		// higher order
+		"map":    {fn: fnMap},
+		"filter": {fn: fnFilter},
+		"reduce": {fn: fnReduce},
+		// date/time
+		"now":    {fn: fnNow},
+		"millis": {fn: fnMillis},
+		// AWS states language
+		"partition": {fn: fnPartition},
+		"range":     {fn: fnRange},
+		"hash":      {fn: fnHash},
+		"random":    {fn: fnRandom},
+		"uuid":      {fn: fnUUID},
+		"parse":     {fn: fnParse},
+	}
+}
+
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return Undefined
+}
+
+func validateArity(name string, args []interface{}, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return newError("$%s: invalid number of arguments", name)
+	}
+	return nil
+}
+
+func stringArg(name string, args []interface{}, i int) (string, bool, error) {
+	v := arg(args, i)
+	if v == Undefined {
+		return "", false, nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", false, newError("$%s: argument %d must be a string", name, i+1)
+	}
+	return str, true, nil
+}
+
+func numberArg(name string, args []interface{}, i int) (float64, bool, error) {
+	v := arg(args, i)
+	if v == Undefined {
+		return 0, false, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return 0, false, newError("$%s: argument %d must be a number", name, i+1)
+	}
+	return f, true, nil
+}
+
+// arrayArg returns the argument as an array, wrapping a single value
+func arrayArg(args []interface{}, i int) ([]interface{}, bool) {
+	v := arg(args, i)
+	if v == Undefined {
+		return nil, false
+	}
+	if arr, ok := asArray(v); ok {
+		return arr, true
+	}
+	return []interface{}{v}, true
+}
+
+func numbersArg(name string, args []interface{}) ([]float64, bool, error) {
+	if err := validateArity(name, args, 1, 1); err != nil {
+		return nil, false, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return nil, false, nil
+	}
+
+	result := make([]float64, 0, len(arr))
+	for _, item := range arr {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, false, newError("$%s: argument must be an array of numbers", name)
+		}
+		result = append(result, f)
+	}
+	return result, true, nil
+}
+
+// stringify converts a value to a string as $string does
+func stringify(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case undefined:
+		return "", nil
+	case string:
+		return value, nil
+	case float64:
+		return formatNumber(value), nil
+	case builtin, lambda:
+		return "", nil
+	}
+
+	b, err := Encode(v)
+	if err != nil {
+		return "", newError("$string: %s", err)
+	}
+	return string(b), nil
+}
+
+// formatNumber formats a number the same way as JavaScript
+func formatNumber(f float64) string {
+	abs := math.Abs(f)
+	if abs != 0 && (abs >= 1e21 || abs < 1e-7) {
+		return strconv.FormatFloat(f, 'g', 15, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func fnString(args []interface{}) (interface{}, error) {
+	if err := validateArity("string", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == Undefined {
+		return Undefined, nil
+	}
+	return stringify(args[0])
+}
+
+func fnLength(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("length", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	return float64(utf8.RuneCountInString(str)), nil
+}
+
+func fnSubstring(args []interface{}) (interface{}, error) {
+	if err := validateArity("substring", args, 2, 3); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("substring", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	start, _, err := numberArg("substring", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(str)
+	from := int(start)
+	if from < 0 {
+		from = len(runes) + from
+	}
+	from = clamp(from, 0, len(runes))
+
+	to := len(runes)
+	if length, ok, err := numberArg("substring", args, 2); err != nil {
+		return nil, err
+	} else if ok {
+		to = clamp(from+int(length), from, len(runes))
+	}
+
+	return string(runes[from:to]), nil
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+func fnSubstringBefore(args []interface{}) (interface{}, error) {
+	if err := validateArity("substringBefore", args, 2, 2); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("substringBefore", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	chars, _, err := stringArg("substringBefore", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := strings.Index(str, chars); i >= 0 {
+		return str[:i], nil
+	}
+	return str, nil
+}
+
+func fnSubstringAfter(args []interface{}) (interface{}, error) {
+	if err := validateArity("substringAfter", args, 2, 2); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("substringAfter", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	chars, _, err := stringArg("substringAfter", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := strings.Index(str, chars); i >= 0 {
+		return str[i+len(chars):], nil
+	}
+	return str, nil
+}
+
+func fnUppercase(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("uppercase", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	return strings.ToUpper(str), nil
+}
+
+func fnLowercase(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("lowercase", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	return strings.ToLower(str), nil
+}
+
+func fnTrim(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("trim", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	return strings.Join(strings.Fields(str), " "), nil
+}
+
+func fnContains(args []interface{}) (interface{}, error) {
+	if err := validateArity("contains", args, 2, 2); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("contains", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	substr, _, err := stringArg("contains", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(str, substr), nil
+}
+
+func fnSplit(args []interface{}) (interface{}, error) {
+	if err := validateArity("split", args, 2, 3); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("split", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	separator, _, err := stringArg("split", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(str, separator)
+	if limit, ok, err := numberArg("split", args, 2); err != nil {
+		return nil, err
+	} else if ok && int(limit) < len(parts) {
+		parts = parts[:int(math.Max(limit, 0))]
+	}
+
+	result := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		result = append(result, part)
+	}
+	return result, nil
+}
+
+func fnJoin(args []interface{}) (interface{}, error) {
+	if err := validateArity("join", args, 1, 2); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+	separator, _, err := stringArg("join", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, 0, len(arr))
+	for _, item := range arr {
+		str, ok := item.(string)
+		if !ok {
+			return nil, newError("$join: argument 1 must be an array of strings")
+		}
+		strs = append(strs, str)
+	}
+	return strings.Join(strs, separator), nil
+}
+
+func fnReplace(args []interface{}) (interface{}, error) {
+	if err := validateArity("replace", args, 3, 4); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("replace", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	pattern, _, err := stringArg("replace", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		return nil, newError("$replace: argument 2 can't be an empty string")
+	}
+	replacement, _, err := stringArg("replace", args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := -1
+	if l, ok, err := numberArg("replace", args, 3); err != nil {
+		return nil, err
+	} else if ok {
+		limit = int(l)
+	}
+	return strings.Replace(str, pattern, replacement, limit), nil
+}
+
+func fnBase64Encode(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("base64encode", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(str)), nil
+}
+
+func fnBase64Decode(args []interface{}) (interface{}, error) {
+	str, ok, err := stringArg("base64decode", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, newError("$base64decode: invalid base64 string")
+	}
+	return string(decoded), nil
+}
+
+func fnNumber(args []interface{}) (interface{}, error) {
+	if err := validateArity("number", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch v := args[0].(type) {
+	case undefined:
+		return Undefined, nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, newError("$number: unable to cast '%s' to a number", v)
+		}
+		return f, nil
+	}
+	return nil, newError("$number: unable to cast value to a number")
+}
+
+func unaryMath(name string, fn func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if err := validateArity(name, args, 1, 1); err != nil {
+			return nil, err
+		}
+		f, ok, err := numberArg(name, args, 0)
+		if err != nil || !ok {
+			return Undefined, err
+		}
+		return fn(f), nil
+	}
+}
+
+var (
+	fnAbs   = unaryMath("abs", math.Abs)
+	fnFloor = unaryMath("floor", math.Floor)
+	fnCeil  = unaryMath("ceil", math.Ceil)
+)
+
+func fnSqrt(args []interface{}) (interface{}, error) {
+	f, ok, err := numberArg("sqrt", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	if f < 0 {
+		return nil, newError("$sqrt: argument can't be negative")
+	}
+	return math.Sqrt(f), nil
+}
+
+// fnRound rounds half to even, as JSONata does
+func fnRound(args []interface{}) (interface{}, error) {
+	if err := validateArity("round", args, 1, 2); err != nil {
+		return nil, err
+	}
+	f, ok, err := numberArg("round", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	precision, _, err := numberArg("round", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := math.Pow(10, precision)
+	return math.RoundToEven(f*shift) / shift, nil
+}
+
+func fnPower(args []interface{}) (interface{}, error) {
+	if err := validateArity("power", args, 2, 2); err != nil {
+		return nil, err
+	}
+	base, ok, err := numberArg("power", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	exponent, _, err := numberArg("power", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := math.Pow(base, exponent)
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil, newError("$power: result out of range")
+	}
+	return result, nil
+}
+
+func fnSum(args []interface{}) (interface{}, error) {
+	nums, ok, err := numbersArg("sum", args)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+
+	sum := float64(0)
+	for _, f := range nums {
+		sum += f
+	}
+	return sum, nil
+}
+
+func fnMax(args []interface{}) (interface{}, error) {
+	nums, ok, err := numbersArg("max", args)
+	if err != nil || !ok || len(nums) == 0 {
+		return Undefined, err
+	}
+
+	max := nums[0]
+	for _, f := range nums[1:] {
+		max = math.Max(max, f)
+	}
+	return max, nil
+}
+
+func fnMin(args []interface{}) (interface{}, error) {
+	nums, ok, err := numbersArg("min", args)
+	if err != nil || !ok || len(nums) == 0 {
+		return Undefined, err
+	}
+
+	min := nums[0]
+	for _, f := range nums[1:] {
+		min = math.Min(min, f)
+	}
+	return min, nil
+}
+
+func fnAverage(args []interface{}) (interface{}, error) {
+	nums, ok, err := numbersArg("average", args)
+	if err != nil || !ok || len(nums) == 0 {
+		return Undefined, err
+	}
+
+	sum := float64(0)
+	for _, f := range nums {
+		sum += f
+	}
+	return sum / float64(len(nums)), nil
+}
+
+func fnBoolean(args []interface{}) (interface{}, error) {
+	if err := validateArity("boolean", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == Undefined {
+		return Undefined, nil
+	}
+	return truthy(args[0]), nil
+}
+
+func fnNot(args []interface{}) (interface{}, error) {
+	if err := validateArity("not", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == Undefined {
+		return Undefined, nil
+	}
+	return !truthy(args[0]), nil
+}
+
+func fnExists(args []interface{}) (interface{}, error) {
+	if err := validateArity("exists", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return args[0] != Undefined, nil
+}
+
+func fnCount(args []interface{}) (interface{}, error) {
+	if err := validateArity("count", args, 1, 1); err != nil {
+		return nil, err
+	}
+	arr, _ := arrayArg(args, 0)
+	return float64(len(arr)), nil
+}
+
+func fnAppend(args []interface{}) (interface{}, error) {
+	if err := validateArity("append", args, 2, 2); err != nil {
+		return nil, err
+	}
+	first, ok := arrayArg(args, 0)
+	if !ok {
+		return args[1], nil
+	}
+	second, ok := arrayArg(args, 1)
+	if !ok {
+		return args[0], nil
+	}
+
+	result := make([]interface{}, 0, len(first)+len(second))
+	result = append(result, first...)
+	return append(result, second...), nil
+}
+
+func fnSort(args []interface{}) (interface{}, error) {
+	if err := validateArity("sort", args, 1, 2); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	result := make([]interface{}, len(arr))
+	copy(result, arr)
+
+	var sortErr error
+	if len(args) == 2 {
+		// the function returns true if the first value should come after the second
+		sort.SliceStable(result, func(i, j int) bool {
+			swap, err := apply(args[1], result[j], result[i])
+			if err != nil {
+				sortErr = err
+			}
+			return truthy(swap)
+		})
+		return result, sortErr
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		switch a := result[i].(type) {
+		case float64:
+			if b, ok := result[j].(float64); ok {
+				return a < b
+			}
+		case string:
+			if b, ok := result[j].(string); ok {
+				return a < b
+			}
+		}
+		sortErr = newError("$sort: argument 1 must be an array of strings or an array of numbers")
+		return false
+	})
+	return result, sortErr
+}
+
+func fnReverse(args []interface{}) (interface{}, error) {
+	if err := validateArity("reverse", args, 1, 1); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	result := make([]interface{}, len(arr))
+	for i, item := range arr {
+		result[len(arr)-1-i] = item
+	}
+	return result, nil
+}
+
+func fnDistinct(args []interface{}) (interface{}, error) {
+	if err := validateArity("distinct", args, 1, 1); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	result := []interface{}{}
+	for _, item := range arr {
+		duplicate := false
+		for _, existing := range result {
+			if deepEqual(item, existing) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func fnKeys(args []interface{}) (interface{}, error) {
+	if err := validateArity("keys", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	keys := []string{}
+	for _, item := range items(args[0]) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range obj {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return Undefined, nil
+	}
+
+	sort.Strings(keys)
+	result := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key)
+	}
+	return collapse(sequence(result)), nil
+}
+
+func fnLookup(args []interface{}) (interface{}, error) {
+	if err := validateArity("lookup", args, 2, 2); err != nil {
+		return nil, err
+	}
+	key, _, err := stringArg("lookup", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := name{key}.eval(args[0], nil)
+	return collapse(v), nil
+}
+
+func fnMerge(args []interface{}) (interface{}, error) {
+	if err := validateArity("merge", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	for _, item := range items(args[0]) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, newError("$merge: argument 1 must be an array of objects")
+		}
+		for key, value := range obj {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func fnType(args []interface{}) (interface{}, error) {
+	if err := validateArity("type", args, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch args[0].(type) {
+	case undefined:
+		return Undefined, nil
+	case nil:
+		return "null", nil
+	case float64:
+		return "number", nil
+	case string:
+		return "string", nil
+	case bool:
+		return "boolean", nil
+	case []interface{}, sequence:
+		return "array", nil
+	case builtin, lambda:
+		return "function", nil
+	}
+	return "object", nil
+}
+
+func fnMap(args []interface{}) (interface{}, error) {
+	if err := validateArity("map", args, 2, 2); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	result := sequence{}
+	for i, item := range arr {
+		v, err := apply(args[1], item, float64(i), arr)
+		if err != nil {
+			return nil, err
+		}
+		if v != Undefined {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func fnFilter(args []interface{}) (interface{}, error) {
+	if err := validateArity("filter", args, 2, 2); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	result := sequence{}
+	for i, item := range arr {
+		v, err := apply(args[1], item, float64(i), arr)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(v) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func fnReduce(args []interface{}) (interface{}, error) {
+	if err := validateArity("reduce", args, 2, 3); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+
+	var acc interface{}
+	start := 0
+	if len(args) == 3 {
+		acc = args[2]
+	} else if len(arr) > 0 {
+		acc = arr[0]
+		start = 1
+	} else {
+		return Undefined, nil
+	}
+
+	for i := start; i < len(arr); i++ {
+		v, err := apply(args[1], acc, arr[i], float64(i), arr)
+		if err != nil {
+			return nil, err
+		}
+		acc = v
+	}
+	return acc, nil
+}
+
+func fnNow(args []interface{}) (interface{}, error) {
+	if err := validateArity("now", args, 0, 0); err != nil {
+		return nil, err
+	}
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), nil
+}
+
+func fnMillis(args []interface{}) (interface{}, error) {
+	if err := validateArity("millis", args, 0, 0); err != nil {
+		return nil, err
+	}
+	return float64(time.Now().UnixNano() / int64(time.Millisecond)), nil
+}
+
+func fnPartition(args []interface{}) (interface{}, error) {
+	if err := validateArity("partition", args, 2, 2); err != nil {
+		return nil, err
+	}
+	arr, ok := arrayArg(args, 0)
+	if !ok {
+		return Undefined, nil
+	}
+	size, _, err := numberArg("partition", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if size < 1 || size != math.Trunc(size) {
+		return nil, newError("$partition: argument 2 must be a positive integer")
+	}
+
+	result := []interface{}{}
+	for i := 0; i < len(arr); i += int(size) {
+		end := i + int(size)
+		if end > len(arr) {
+			end = len(arr)
+		}
+		chunk := make([]interface{}, end-i)
+		copy(chunk, arr[i:end])
+		result = append(result, chunk)
+	}
+	return result, nil
+}
+
+// maxRangeItems is the largest array $range can produce
+const maxRangeItems = 1000
+
+func fnRange(args []interface{}) (interface{}, error) {
+	if err := validateArity("range", args, 3, 3); err != nil {
+		return nil, err
+	}
+
+	bounds := make([]float64, 3)
+	for i := range bounds {
+		f, ok, err := numberArg("range", args, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || f != math.Trunc(f) {
+			return nil, newError("$range: argument %d must be an integer", i+1)
+		}
+		bounds[i] = f
+	}
+
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return nil, newError("$range: step can't be 0")
+	}
+
+	result := []interface{}{}
+	for i := start; (step > 0 && i <= end) || (step < 0 && i >= end); i += step {
+		if len(result) == maxRangeItems {
+			return nil, newError("$range: can't produce more than %d items", maxRangeItems)
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"MD5":     md5.New,
+	"SHA-1":   sha1.New,
+	"SHA-256": sha256.New,
+	"SHA-384": sha512.New384,
+	"SHA-512": sha512.New,
+}
+
+func fnHash(args []interface{}) (interface{}, error) {
+	if err := validateArity("hash", args, 2, 2); err != nil {
+		return nil, err
+	}
+	data, ok, err := stringArg("hash", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+	algorithm, _, err := stringArg("hash", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return nil, newError("$hash: unsupported algorithm '%s'", algorithm)
+	}
+
+	h := newHash()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fnRandom(args []interface{}) (interface{}, error) {
+	if err := validateArity("random", args, 0, 1); err != nil {
+		return nil, err
+	}
+	seed, ok, err := numberArg("random", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return mathrand.New(mathrand.NewSource(int64(seed))).Float64(), nil
+	}
+	return mathrand.Float64(), nil
+}
+
+func fnUUID(args []interface{}) (interface{}, error) {
+	if err := validateArity("uuid", args, 0, 0); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, newError("$uuid: %s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func fnParse(args []interface{}) (interface{}, error) {
+	if err := validateArity("parse", args, 1, 1); err != nil {
+		return nil, err
+	}
+	str, ok, err := stringArg("parse", args, 0)
+	if err != nil || !ok {
+		return Undefined, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(str), &v); err != nil {
+		return nil, newError("$parse: argument 1 is not valid JSON")
+	}
+	return v, nil
+}
@@ -0,0 +1,101 @@
+// Package jsonata implements the subset of the JSONata query language used by the AWS states language
+// (https://docs.jsonata.org).
+//
+// Supported are path navigation, predicates, wildcards, arithmetic, comparison, boolean, string
+// concatenation and conditional operators, array and object constructors, ranges, blocks with variable
+// bindings, user defined functions, the function chaining operator and the commonly used built in
+// functions, including the AWS additions $partition, $range, $hash, $random, $uuid and $parse. Values
+// are decoded JSON i.e. float64, string, bool, nil, []interface{} and map[string]interface{}.
+package jsonata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type undefined struct{}
+
+// Undefined is the result of an expression which doesn't select a value e.g. a field which doesn't exist.
+// It's distinct from null.
+var Undefined interface{} = undefined{}
+
+// Error represents an error evaluating an expression
+type Error struct {
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+func newError(format string, args ...interface{}) Error {
+	return Error{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+type Expression interface {
+	// Evaluate evaluates the expression against the input. The bindings are available to the expression
+	// as variables e.g. a binding of "states" is referenced as $states.
+	Evaluate(input interface{}, bindings map[string]interface{}) (interface{}, error)
+}
+
+// Compile parses an expression
+func Compile(input string) (Expression, error) {
+	root, err := parse(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid JSONata expression '%s'", input)
+	}
+
+	return expression{
+		root: root,
+	}, nil
+}
+
+type expression struct {
+	root node
+}
+
+func (e expression) Evaluate(input interface{}, bindings map[string]interface{}) (interface{}, error) {
+	env := newEnvironment(nil)
+	env.root = input
+	for name, value := range bindings {
+		env.vars[name] = value
+	}
+
+	result, err := e.root.eval(input, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return collapse(result), nil
+}
+
+// Decode unmarshals JSON into a value which expressions can be evaluated against
+func Decode(input []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(input, &value); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling json")
+	}
+
+	return value, nil
+}
+
+// Encode marshals the result of an expression. Functions can't be encoded and undefined is an error.
+func Encode(value interface{}) ([]byte, error) {
+	if value == Undefined {
+		return []byte{}, errors.New("unable to encode an undefined value")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return []byte{}, errors.Wrap(err, "error marshaling json")
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,218 @@
+// +build unit
+
+package jsonata_test
+
+import (
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/jsonata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONata(t *testing.T) {
+	input := []byte(`{
+		"name": "order",
+		"customer": {"first": "Jane", "last": "Doe", "vip": true},
+		"items": [
+			{"sku": "a", "price": 10, "qty": 2},
+			{"sku": "b", "price": 5.5, "qty": 1},
+			{"sku": "c", "price": 1, "qty": 10}
+		],
+		"single": [7],
+		"tags": ["x", "y"],
+		"nothing": null
+	}`)
+
+	t.Run("Evaluate", func(t *testing.T) {
+		tests := []struct {
+			title          string
+			expression     string
+			expectedResult string
+		}{
+			{"literal number", `42`, `42`},
+			{"literal string", `"hello"`, `"hello"`},
+			{"literal null", `null`, `null`},
+			{"field", `name`, `"order"`},
+			{"nested field", `customer.first`, `"Jane"`},
+			{"context", `$.customer.last`, `"Doe"`},
+			{"root", `items.($$.name)`, `["order","order","order"]`},
+			{"null field", `nothing`, `null`},
+			{"array field", `tags`, `["x","y"]`},
+			{"single value array kept", `single`, `[7]`},
+			{"mapped field", `items.sku`, `["a","b","c"]`},
+			{"index", `items[0].sku`, `"a"`},
+			{"negative index", `items[-1].sku`, `"c"`},
+			{"filter", `items[price > 5].sku`, `["a","b"]`},
+			{"filter single match", `items[sku = "b"].price`, `5.5`},
+			{"wildcard", `customer.*`, `["Jane","Doe",true]`},
+			{"arithmetic", `items[0].price * items[0].qty + 1`, `21`},
+			{"precedence", `1 + 2 * 3`, `7`},
+			{"modulo", `7 % 3`, `1`},
+			{"negation", `-items[0].price`, `-10`},
+			{"concatenation", `customer.first & " " & customer.last`, `"Jane Doe"`},
+			{"concatenate number", `"n" & 1.5`, `"n1.5"`},
+			{"comparison", `items[0].price >= 10`, `true`},
+			{"string comparison", `"a" < "b"`, `true`},
+			{"equality", `customer = {"first": "Jane", "last": "Doe", "vip": true}`, `true`},
+			{"and or", `customer.vip and (false or name = "order")`, `true`},
+			{"in", `"y" in tags`, `true`},
+			{"conditional", `customer.vip ? "gold" : "standard"`, `"gold"`},
+			{"array constructor", `[name, 1, [2, 3]]`, `["order",1,[2,3]]`},
+			{"array constructor flattens paths", `[items.qty]`, `[2,1,10]`},
+			{"range", `[1..4]`, `[1,2,3,4]`},
+			{"object constructor", `{"sku": items[0].sku, "missing": nope}`, `{"sku":"a"}`},
+			{"block with bindings", `($total := $sum(items.(price * qty)); $total * 2)`, `71`},
+			{"lambda", `($double := function($x) { $x * 2 }; $double(4))`, `8`},
+			{"chain", `tags ~> $join(",")`, `"x,y"`},
+			{"bindings", `$states.input.value`, `1`},
+			{"quoted name", "customer.`first`", `"Jane"`},
+			{"comment", `/* comment */ name`, `"order"`},
+			{"$string", `$string(customer.vip)`, `"true"`},
+			{"$string object", `$string({"a": 1})`, `"{\"a\":1}"`},
+			{"$string context", `name.$string()`, `"order"`},
+			{"$length", `$length(name)`, `5`},
+			{"$substring", `$substring(name, 1, 3)`, `"rde"`},
+			{"$substringBefore", `$substringBefore("a-b", "-")`, `"a"`},
+			{"$substringAfter", `$substringAfter("a-b", "-")`, `"b"`},
+			{"$uppercase", `$uppercase(name)`, `"ORDER"`},
+			{"$lowercase", `$lowercase("ABC")`, `"abc"`},
+			{"$trim", `$trim("  a   b ")`, `"a b"`},
+			{"$contains", `$contains(name, "rd")`, `true`},
+			{"$split", `$split("a,b,c", ",")`, `["a","b","c"]`},
+			{"$join", `$join(tags, "-")`, `"x-y"`},
+			{"$replace", `$replace("aXbX", "X", "_")`, `"a_b_"`},
+			{"$base64encode", `$base64encode("hello")`, `"aGVsbG8="`},
+			{"$base64decode", `$base64decode("aGVsbG8=")`, `"hello"`},
+			{"$number", `$number("1.5")`, `1.5`},
+			{"$abs", `$abs(-2)`, `2`},
+			{"$floor", `$floor(1.7)`, `1`},
+			{"$ceil", `$ceil(1.2)`, `2`},
+			{"$round", `$round(2.5)`, `2`},
+			{"$round precision", `$round(1.2345, 2)`, `1.23`},
+			{"$power", `$power(2, 3)`, `8`},
+			{"$sqrt", `$sqrt(9)`, `3`},
+			{"$sum", `$sum(items.price)`, `16.5`},
+			{"$max", `$max(items.qty)`, `10`},
+			{"$min", `$min(items.qty)`, `1`},
+			{"$average", `$average([1, 2, 3])`, `2`},
+			{"$boolean", `$boolean("")`, `false`},
+			{"$not", `$not(customer.vip)`, `false`},
+			{"$exists", `[$exists(name), $exists(nope)]`, `[true,false]`},
+			{"$count", `$count(items)`, `3`},
+			{"$append", `$append(tags, "z")`, `["x","y","z"]`},
+			{"$sort", `$sort(items.qty)`, `[1,2,10]`},
+			{"$sort function", `$sort(items, function($a, $b) { $a.price > $b.price }).sku`, `["c","b","a"]`},
+			{"$reverse", `$reverse(tags)`, `["y","x"]`},
+			{"$distinct", `$distinct([1, 2, 1])`, `[1,2]`},
+			{"$keys", `$keys(customer)`, `["first","last","vip"]`},
+			{"$lookup", `$lookup(customer, "first")`, `"Jane"`},
+			{"$merge", `$merge([{"a": 1}, {"b": 2}])`, `{"a":1,"b":2}`},
+			{"$type", `$type(items)`, `"array"`},
+			{"$map", `$map(items, function($v, $i) { $v.sku & $i })`, `["a0","b1","c2"]`},
+			{"$filter", `$filter(items, function($v) { $v.qty > 1 }).sku`, `["a","c"]`},
+			{"$reduce", `$reduce([1, 2, 3], function($acc, $v) { $acc + $v })`, `6`},
+			{"$partition", `$partition([1, 2, 3], 2)`, `[[1,2],[3]]`},
+			{"$range", `$range(0, 10, 5)`, `[0,5,10]`},
+			{"$hash", `$hash("input", "SHA-256")`, `"c96c6d5be8d08a12e7b5cdc1b207fa6b2430974c86803d8891675e76fd992c20"`},
+			{"$random seed", `$random(1) = $random(1)`, `true`},
+			{"$parse", `$parse('{"a":[1]}')`, `{"a":[1]}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				exp, err := jsonata.Compile(tt.expression)
+				require.NoError(t, err)
+
+				doc, err := jsonata.Decode(input)
+				require.NoError(t, err)
+
+				bindings := map[string]interface{}{
+					"states": map[string]interface{}{
+						"input": map[string]interface{}{"value": float64(1)},
+					},
+				}
+				result, err := exp.Evaluate(doc, bindings)
+				require.NoError(t, err)
+
+				encoded, err := jsonata.Encode(result)
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedResult, string(encoded))
+			})
+		}
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		for _, expression := range []string{`nope`, `items[price > 100]`, `nope + 1`, `$missing`} {
+			exp, err := jsonata.Compile(expression)
+			require.NoError(t, err)
+
+			doc, err := jsonata.Decode(input)
+			require.NoError(t, err)
+
+			result, err := exp.Evaluate(doc, nil)
+			require.NoError(t, err)
+			require.Equal(t, jsonata.Undefined, result)
+
+			_, err = jsonata.Encode(result)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("$uuid", func(t *testing.T) {
+		exp, err := jsonata.Compile(`$uuid()`)
+		require.NoError(t, err)
+
+		result, err := exp.Evaluate(nil, nil)
+		require.NoError(t, err)
+		require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, result)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []string{
+			``,
+			`(`,
+			`[1, 2`,
+			`{"a" 1}`,
+			`"unterminated`,
+			`a.`,
+			`1 +`,
+			`1 := 2`,
+			`function(x) { x }`,
+			`#`,
+		}
+
+		for _, expression := range invalid {
+			t.Run(expression, func(t *testing.T) {
+				_, err := jsonata.Compile(expression)
+				require.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("runtime errors", func(t *testing.T) {
+		failing := []string{
+			`name + 1`,
+			`name < 1`,
+			`$number("abc")`,
+			`$notAFunction()`,
+			`$substring(1, 2)`,
+			`$sqrt(-1)`,
+			`$hash("a", "unknown")`,
+			`$range(0, 2000, 1)`,
+		}
+
+		for _, expression := range failing {
+			t.Run(expression, func(t *testing.T) {
+				exp, err := jsonata.Compile(expression)
+				require.NoError(t, err)
+
+				doc, err := jsonata.Decode(input)
+				require.NoError(t, err)
+
+				_, err = exp.Evaluate(doc, nil)
+				require.Error(t, err)
+				require.IsType(t, jsonata.Error{}, err)
+			})
+		}
+	})
+}
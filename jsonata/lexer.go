@@ -0,0 +1,202 @@
+package jsonata
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+type tokenType int
+
+const (
+	tokenEnd tokenType = iota
+	tokenOperator
+	tokenName
+	tokenVariable
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	typ   tokenType
+	value string
+	num   float64
+	pos   int
+}
+
+// operators are matched longest first
+var operators = []string{
+	":=", "!=", "<=", ">=", "..", "~>", "**",
+	".", "[", "]", "{", "}", "(", ")", ",", ":", ";", "?",
+	"+", "-", "*", "/", "%", "&", "=", "<", ">", "|",
+}
+
+// lex splits an expression into tokens
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	pos := 0
+
+	for pos < len(input) {
+		r, size := utf8.DecodeRuneInString(input[pos:])
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+			continue
+		case strings.HasPrefix(input[pos:], "/*"):
+			end := strings.Index(input[pos+2:], "*/")
+			if end == -1 {
+				return nil, errors.Errorf("position %d: unterminated comment", pos)
+			}
+			pos += end + 4
+			continue
+		case r == '"' || r == '\'':
+			str, next, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokenString, value: str, pos: pos})
+			pos = next
+			continue
+		case r == '`':
+			end := strings.IndexByte(input[pos+1:], '`')
+			if end == -1 {
+				return nil, errors.Errorf("position %d: unterminated quoted name", pos)
+			}
+			tokens = append(tokens, token{typ: tokenName, value: input[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+			continue
+		case r >= '0' && r <= '9':
+			start := pos
+			pos = lexNumber(input, pos)
+			num, err := strconv.ParseFloat(input[start:pos], 64)
+			if err != nil {
+				return nil, errors.Errorf("position %d: invalid number '%s'", start, input[start:pos])
+			}
+			tokens = append(tokens, token{typ: tokenNumber, value: input[start:pos], num: num, pos: start})
+			continue
+		case r == '$':
+			start := pos
+			pos++
+			for pos < len(input) {
+				r, size := utf8.DecodeRuneInString(input[pos:])
+				if !isNameRune(r) && !(r == '$' && pos == start+1) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{typ: tokenVariable, value: input[start+1 : pos], pos: start})
+			continue
+		case isNameRune(r):
+			start := pos
+			for pos < len(input) {
+				r, size := utf8.DecodeRuneInString(input[pos:])
+				if !isNameRune(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{typ: tokenName, value: input[start:pos], pos: start})
+			continue
+		}
+
+		matched := false
+		for _, op := range operators {
+			if strings.HasPrefix(input[pos:], op) {
+				tokens = append(tokens, token{typ: tokenOperator, value: op, pos: pos})
+				pos += len(op)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("position %d: unexpected character '%c'", pos, r)
+		}
+	}
+
+	return append(tokens, token{typ: tokenEnd, pos: pos}), nil
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func lexNumber(input string, pos int) int {
+	digits := func() {
+		for pos < len(input) && input[pos] >= '0' && input[pos] <= '9' {
+			pos++
+		}
+	}
+
+	digits()
+	// a '.' is only part of the number when followed by a digit so that ranges e.g. [1..5] lex correctly
+	if pos+1 < len(input) && input[pos] == '.' && input[pos+1] >= '0' && input[pos+1] <= '9' {
+		pos++
+		digits()
+	}
+	if pos < len(input) && (input[pos] == 'e' || input[pos] == 'E') {
+		next := pos + 1
+		if next < len(input) && (input[next] == '+' || input[next] == '-') {
+			next++
+		}
+		if next < len(input) && input[next] >= '0' && input[next] <= '9' {
+			pos = next
+			digits()
+		}
+	}
+
+	return pos
+}
+
+func lexString(input string, pos int) (string, int, error) {
+	quote := input[pos]
+	start := pos
+	pos++
+
+	var b strings.Builder
+	for pos < len(input) {
+		c := input[pos]
+		switch {
+		case c == quote:
+			return b.String(), pos + 1, nil
+		case c == '\\':
+			if pos+1 >= len(input) {
+				return "", 0, errors.Errorf("position %d: unterminated string", start)
+			}
+			pos++
+			switch esc := input[pos]; esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if pos+4 >= len(input) {
+					return "", 0, errors.Errorf("position %d: invalid unicode escape", pos)
+				}
+				code, err := strconv.ParseUint(input[pos+1:pos+5], 16, 32)
+				if err != nil {
+					return "", 0, errors.Errorf("position %d: invalid unicode escape", pos)
+				}
+				b.WriteRune(rune(code))
+				pos += 4
+			default:
+				b.WriteByte(esc)
+			}
+			pos++
+		default:
+			b.WriteByte(c)
+			pos++
+		}
+	}
+
+	return "", 0, errors.Errorf("position %d: unterminated string", start)
+}
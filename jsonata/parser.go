@@ -0,0 +1,408 @@
+package jsonata
+
+import (
+	"github.com/pkg/errors"
+)
+
+// node is a node of the syntax tree of an expression
+type node interface {
+	eval(input interface{}, env *environment) (interface{}, error)
+}
+
+type (
+	literal struct {
+		value interface{}
+	}
+	name struct {
+		field string
+	}
+	variable struct {
+		name string // "" is the context value and "$" the root
+	}
+	wildcard struct{}
+	path     struct {
+		steps []node
+	}
+	predicate struct {
+		expr, pred node
+	}
+	binary struct {
+		op          string
+		left, right node
+	}
+	negation struct {
+		expr node
+	}
+	arrayConstructor struct {
+		items []node
+	}
+	rangeOp struct {
+		from, to node
+	}
+	objectConstructor struct {
+		keys, values []node
+	}
+	block struct {
+		exprs []node
+	}
+	condition struct {
+		cond, then, otherwise node
+	}
+	bind struct {
+		name  string
+		value node
+	}
+	call struct {
+		fn   node
+		args []node
+	}
+	lambdaDef struct {
+		params []string
+		body   node
+	}
+)
+
+// binding powers of infix operators
+var bindingPowers = map[string]int{
+	".":   75,
+	"[":   80,
+	"(":   80,
+	"*":   60,
+	"/":   60,
+	"%":   60,
+	"+":   50,
+	"-":   50,
+	"&":   50,
+	"=":   40,
+	"!=":  40,
+	"<":   40,
+	"<=":  40,
+	">":   40,
+	">=":  40,
+	"in":  40,
+	"~>":  40,
+	"and": 30,
+	"or":  25,
+	"?":   20,
+	"..":  20,
+	":=":  10,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(input string) (node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.typ != tokenEnd {
+		return nil, errors.Errorf("position %d: unexpected '%s'", tok.pos, tok.value)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.typ != tokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOperator(value string) bool {
+	tok := p.peek()
+	return tok.typ == tokenOperator && tok.value == value
+}
+
+func (p *parser) expect(value string) error {
+	tok := p.next()
+	if tok.typ != tokenOperator || tok.value != value {
+		if tok.typ == tokenEnd {
+			return errors.Errorf("position %d: expected '%s' before end of expression", tok.pos, value)
+		}
+		return errors.Errorf("position %d: expected '%s' but found '%s'", tok.pos, value, tok.value)
+	}
+	return nil
+}
+
+// infixPower returns the binding power of the next token if it's an infix operator
+func (p *parser) infixPower() int {
+	tok := p.peek()
+	switch tok.typ {
+	case tokenOperator:
+		return bindingPowers[tok.value]
+	case tokenName:
+		if tok.value == "and" || tok.value == "or" || tok.value == "in" {
+			return bindingPowers[tok.value]
+		}
+	}
+	return 0
+}
+
+func (p *parser) expression(rbp int) (node, error) {
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for rbp < p.infixPower() {
+		left, err = p.infix(left)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) prefix() (node, error) {
+	tok := p.next()
+
+	switch tok.typ {
+	case tokenEnd:
+		return nil, errors.Errorf("position %d: unexpected end of expression", tok.pos)
+	case tokenNumber:
+		return literal{tok.num}, nil
+	case tokenString:
+		return literal{tok.value}, nil
+	case tokenVariable:
+		return variable{tok.value}, nil
+	case tokenName:
+		switch tok.value {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		case "function":
+			if p.isOperator("(") {
+				return p.lambda()
+			}
+		}
+		return name{tok.value}, nil
+	}
+
+	switch tok.value {
+	case "-":
+		expr, err := p.expression(70)
+		if err != nil {
+			return nil, err
+		}
+		return negation{expr}, nil
+	case "*":
+		return wildcard{}, nil
+	case "(":
+		exprs := []node{}
+		for !p.isOperator(")") {
+			expr, err := p.expression(0)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+			if !p.isOperator(";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return block{exprs}, nil
+	case "[":
+		items, err := p.list("]")
+		if err != nil {
+			return nil, err
+		}
+		return arrayConstructor{items}, nil
+	case "{":
+		return p.object()
+	}
+
+	return nil, errors.Errorf("position %d: unexpected '%s'", tok.pos, tok.value)
+}
+
+func (p *parser) infix(left node) (node, error) {
+	tok := p.next()
+	power := bindingPowers[tok.value]
+
+	switch tok.value {
+	case ".":
+		right, err := p.expression(power)
+		if err != nil {
+			return nil, err
+		}
+		return newPath(left, right), nil
+	case "[":
+		if p.isOperator("]") {
+			return nil, errors.Errorf("position %d: empty predicate", tok.pos)
+		}
+		pred, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return predicate{expr: left, pred: pred}, nil
+	case "(":
+		args, err := p.list(")")
+		if err != nil {
+			return nil, err
+		}
+		return call{fn: left, args: args}, nil
+	case "?":
+		then, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		var otherwise node
+		if p.isOperator(":") {
+			p.next()
+			if otherwise, err = p.expression(0); err != nil {
+				return nil, err
+			}
+		}
+		return condition{cond: left, then: then, otherwise: otherwise}, nil
+	case ":=":
+		v, ok := left.(variable)
+		if !ok || v.name == "" || v.name == "$" {
+			return nil, errors.Errorf("position %d: the left side of ':=' must be a variable name", tok.pos)
+		}
+		// right associative
+		value, err := p.expression(power - 1)
+		if err != nil {
+			return nil, err
+		}
+		return bind{name: v.name, value: value}, nil
+	case "..":
+		right, err := p.expression(power)
+		if err != nil {
+			return nil, err
+		}
+		return rangeOp{from: left, to: right}, nil
+	case "~>":
+		right, err := p.expression(power)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := right.(call); ok {
+			return call{fn: c.fn, args: append([]node{left}, c.args...)}, nil
+		}
+		return call{fn: right, args: []node{left}}, nil
+	}
+
+	right, err := p.expression(power)
+	if err != nil {
+		return nil, err
+	}
+	return binary{op: tok.value, left: left, right: right}, nil
+}
+
+// list parses a comma separated list of expressions up to and including the closing token
+func (p *parser) list(closing string) ([]node, error) {
+	items := []node{}
+	for !p.isOperator(closing) {
+		item, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.isOperator(",") {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (p *parser) object() (node, error) {
+	obj := objectConstructor{}
+	for !p.isOperator("}") {
+		key, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.expression(0)
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		obj.values = append(obj.values, value)
+
+		if !p.isOperator(",") {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (p *parser) lambda() (node, error) {
+	p.next() // (
+	params := []string{}
+	for !p.isOperator(")") {
+		tok := p.next()
+		if tok.typ != tokenVariable || tok.value == "" {
+			return nil, errors.Errorf("position %d: function parameters must be variable names", tok.pos)
+		}
+		params = append(params, tok.value)
+		if !p.isOperator(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	return lambdaDef{params: params, body: body}, nil
+}
+
+// newPath joins two path expressions into a single path
+func newPath(left, right node) path {
+	steps := []node{}
+	if l, ok := left.(path); ok {
+		steps = append(steps, l.steps...)
+	} else {
+		steps = append(steps, left)
+	}
+	return path{steps: append(steps, right)}
+}
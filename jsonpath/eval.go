@@ -0,0 +1,382 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// segment selects zero or more child nodes from each of the given nodes
+type segment interface {
+	selectNodes(root interface{}, nodes []interface{}) []interface{}
+	definite() bool
+}
+
+type child struct {
+	names []string
+}
+
+func (c child) selectNodes(_ interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, name := range c.names {
+			if value, ok := obj[name]; ok {
+				selected = append(selected, value)
+			}
+		}
+	}
+	return selected
+}
+
+func (c child) definite() bool {
+	return len(c.names) == 1
+}
+
+type index struct {
+	indices []int
+}
+
+func (i index) selectNodes(_ interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		arr, ok := node.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, idx := range i.indices {
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				selected = append(selected, arr[idx])
+			}
+		}
+	}
+	return selected
+}
+
+func (i index) definite() bool {
+	return len(i.indices) == 1
+}
+
+type wildcard struct{}
+
+func (wildcard) selectNodes(_ interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		selected = append(selected, children(node)...)
+	}
+	return selected
+}
+
+func (wildcard) definite() bool {
+	return false
+}
+
+type slice struct {
+	start, end, step *int
+}
+
+func (s slice) selectNodes(_ interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		arr, ok := node.([]interface{})
+		if !ok {
+			continue
+		}
+
+		step := 1
+		if s.step != nil {
+			step = *s.step
+		}
+
+		length := len(arr)
+		start, end := 0, length
+		if step < 0 {
+			start, end = length-1, -length-1
+		}
+		if s.start != nil {
+			start = normalise(*s.start, length)
+		}
+		if s.end != nil {
+			end = normalise(*s.end, length)
+		}
+
+		if step > 0 {
+			for i := max(start, 0); i < end && i < length; i += step {
+				selected = append(selected, arr[i])
+			}
+		} else {
+			for i := min(start, length-1); i > end && i >= 0; i += step {
+				selected = append(selected, arr[i])
+			}
+		}
+	}
+	return selected
+}
+
+func (slice) definite() bool {
+	return false
+}
+
+type descendant struct {
+	inner segment
+}
+
+func (d descendant) selectNodes(root interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		for _, n := range descendants(node) {
+			selected = append(selected, d.inner.selectNodes(root, []interface{}{n})...)
+		}
+	}
+	return selected
+}
+
+func (descendant) definite() bool {
+	return false
+}
+
+type filter struct {
+	expr expr
+}
+
+func (f filter) selectNodes(root interface{}, nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		for _, c := range children(node) {
+			if f.expr.eval(root, c) {
+				selected = append(selected, c)
+			}
+		}
+	}
+	return selected
+}
+
+func (filter) definite() bool {
+	return false
+}
+
+// evaluate applies the segments to the document returning the matched nodes
+func evaluate(segments []segment, root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	for _, seg := range segments {
+		nodes = seg.selectNodes(root, nodes)
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+func isDefinite(segments []segment) bool {
+	for _, seg := range segments {
+		if !seg.definite() {
+			return false
+		}
+	}
+	return true
+}
+
+// children returns the values of an object, in key order, or the elements of an array
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, 0, len(v))
+		for _, key := range keys {
+			values = append(values, v[key])
+		}
+		return values
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// descendants returns the node and all nodes beneath it
+func descendants(node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	for _, c := range children(node) {
+		nodes = append(nodes, descendants(c)...)
+	}
+	return nodes
+}
+
+// expr is a filter expression
+type expr interface {
+	eval(root, current interface{}) bool
+}
+
+type logical struct {
+	op          string
+	left, right expr
+}
+
+func (l logical) eval(root, current interface{}) bool {
+	if l.op == "&&" {
+		return l.left.eval(root, current) && l.right.eval(root, current)
+	}
+	return l.left.eval(root, current) || l.right.eval(root, current)
+}
+
+type not struct {
+	inner expr
+}
+
+func (n not) eval(root, current interface{}) bool {
+	return !n.inner.eval(root, current)
+}
+
+type exists struct {
+	operand operand
+}
+
+func (e exists) eval(root, current interface{}) bool {
+	_, ok := e.operand.value(root, current)
+	return ok
+}
+
+type comparison struct {
+	op          string
+	left, right operand
+}
+
+func (c comparison) eval(root, current interface{}) bool {
+	left, ok := c.left.value(root, current)
+	if !ok {
+		return false
+	}
+	right, ok := c.right.value(root, current)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// operand is a filter expression operand
+type operand interface {
+	value(root, current interface{}) (interface{}, bool)
+}
+
+type literalOperand struct {
+	v interface{}
+}
+
+func (l literalOperand) value(_, _ interface{}) (interface{}, bool) {
+	return l.v, true
+}
+
+type pathOperand struct {
+	relative bool
+	segments []segment
+}
+
+func (p pathOperand) value(root, current interface{}) (interface{}, bool) {
+	start := root
+	if p.relative {
+		start = current
+	}
+
+	nodes := evaluate(p.segments, start)
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	return nodes[0], true
+}
+
+func equal(a, b interface{}) bool {
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares two numbers or two strings
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		if errx != nil || erry != nil {
+			return 0, false
+		}
+		switch {
+		case fx < fy:
+			return -1, true
+		case fx > fy:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func normalise(i, length int) int {
+	if i < 0 {
+		return i + length
+	}
+	return i
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -1,41 +1,235 @@
+// Package jsonpath implements the JSONPath syntax used by the AWS states language.
+//
+// Paths (e.g. InputPath, Parameters) support the full syntax including wildcards, unions, slices, deep
+// scans and filters, and return an array of matches if they can select more than one node. Reference
+// paths (e.g. ResultPath, Choice rule Variables) are limited to dot and bracket notation identifying a
+// single node.
+//
+// A path may also be rooted at a variable of the document it's evaluated against e.g. "$order.id" selects
+// the id field of the variable "order". A path rooted at "$$" e.g. "$$.Task.Token" selects from the context
+// object, which is the variable ContextVariable.
 package jsonpath
 
 import (
+	"bytes"
 	"encoding/json"
 
-	"github.com/oliveagle/jsonpath"
+	"github.com/pkg/errors"
+)
+
+// ContextVariable is the name of the variable paths rooted at "$$" are evaluated against
+const ContextVariable = "$"
+
+var (
+	// ErrNotFound is returned when a path which selects a single node doesn't match the input
+	ErrNotFound = errors.New("path not found")
 )
 
 type Expression interface {
 	Search(json []byte) ([]byte, error)
+	SearchDocument(doc *Document) ([]byte, error)
+	Set(json, value []byte) ([]byte, error)
+	Delete(json []byte) ([]byte, error)
 }
 
+// NewExpression parses a path
 func NewExpression(input string) (Expression, error) {
-	exp := expression(input)
-	return exp, exp.compile()
+	variable, path := splitVariable(input)
+	segments, err := parse(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return expression{
+		raw:      input,
+		variable: variable,
+		segments: segments,
+	}, nil
 }
 
-type expression string
+// NewReferencePath parses a reference path
+func NewReferencePath(input string) (Expression, error) {
+	variable, path := splitVariable(input)
+	segments, err := parse(path, true)
+	if err != nil {
+		return nil, err
+	}
 
+	return expression{
+		raw:      input,
+		variable: variable,
+		segments: segments,
+	}, nil
+}
+
+type expression struct {
+	raw      string
+	variable string // variable the path is rooted at, if any
+	segments []segment
+}
+
+// IsVariable reports whether a path is rooted at a variable e.g. "$order.id"
+func IsVariable(input string) bool {
+	variable, _ := splitVariable(input)
+	return variable != ""
+}
+
+// splitVariable separates the variable a path is rooted at from the remainder of the path e.g. "$order.id"
+// is split into "order" and "$.id"
+func splitVariable(input string) (string, string) {
+	if len(input) >= 2 && input[:2] == "$$" {
+		return ContextVariable, "$" + input[2:]
+	}
+
+	if len(input) < 2 || input[0] != '$' || !isVariableStart(input[1]) {
+		return "", input
+	}
+
+	end := 2
+	for end < len(input) && (isVariableStart(input[end]) || (input[end] >= '0' && input[end] <= '9')) {
+		end++
+	}
+
+	return input[1:end], "$" + input[end:]
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Search returns the JSON selected by the expression. A value of null is returned as "null" whereas a
+// path that selects a single node and doesn't match results in ErrNotFound.
 func (e expression) Search(inputJSON []byte) ([]byte, error) {
-	var input interface{}
-	if err := json.Unmarshal(inputJSON, &input); err != nil {
-		return []byte{}, err
+	if len(e.segments) == 0 && e.variable == "" {
+		return inputJSON, nil
 	}
 
-	res, _ := jsonpath.JsonPathLookup(input, string(e))
-	outputJSON, err := json.Marshal(res)
+	doc, err := NewDocument(inputJSON)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	if string(outputJSON) == "null" {
-		return nil, nil
+	return e.SearchDocument(doc)
+}
+
+// SearchDocument is equivalent to Search for a document that has already been parsed
+func (e expression) SearchDocument(doc *Document) ([]byte, error) {
+	root := doc.root
+	if e.variable != "" {
+		value, err := doc.variable(e.variable)
+		if err != nil {
+			return []byte{}, err
+		}
+		root = value
+	}
+
+	nodes := evaluate(e.segments, root)
+	if !isDefinite(e.segments) {
+		return encode(nodes)
 	}
-	return outputJSON, nil
+
+	if len(nodes) == 0 {
+		return []byte{}, errors.Wrapf(ErrNotFound, "'%s'", e.raw)
+	}
+
+	return encode(nodes[0])
 }
 
-func (e expression) compile() error {
-	_, err := jsonpath.Compile(string(e))
-	return err
+// Document is a parsed JSON document which any number of expressions can be evaluated against without
+// it being unmarshaled each time
+type Document struct {
+	raw          []byte
+	root         interface{}
+	variables    map[string]interface{}
+	rawVariables map[string]json.RawMessage
+}
+
+func NewDocument(input []byte) (*Document, error) {
+	root, err := decode(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		raw:          input,
+		root:         root,
+		variables:    map[string]interface{}{},
+		rawVariables: map[string]json.RawMessage{},
+	}, nil
+}
+
+// JSON returns the JSON the document was parsed from
+func (d *Document) JSON() []byte {
+	return d.raw
+}
+
+// SetVariable makes a value available to paths rooted at the variable of the given name. The value is
+// only decoded once a path references the variable, so variables that aren't referenced e.g. a large
+// context object cost nothing to set.
+func (d *Document) SetVariable(name string, value json.RawMessage) error {
+	delete(d.variables, name)
+	d.rawVariables[name] = value
+	return nil
+}
+
+// variable returns the decoded value of a variable, which is decoded the first time it's referenced
+func (d *Document) variable(name string) (interface{}, error) {
+	if value, ok := d.variables[name]; ok {
+		return value, nil
+	}
+
+	raw, ok := d.rawVariables[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrNotFound, "variable '$%s' isn't defined", name)
+	}
+
+	value, err := decode(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decoding variable '%s'", name)
+	}
+
+	d.variables[name] = value
+	return value, nil
+}
+
+// Variables returns the variables of the document
+func (d *Document) Variables() map[string]json.RawMessage {
+	return d.rawVariables
+}
+
+// Search evaluates each of the expressions against the document, returning the results in the same order
+func (d *Document) Search(exps ...Expression) ([][]byte, error) {
+	results := make([][]byte, 0, len(exps))
+	for _, exp := range exps {
+		result, err := exp.SearchDocument(d)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func decode(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling json")
+	}
+
+	return value, nil
+}
+
+func encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return []byte{}, errors.Wrap(err, "error marshaling json")
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
 }
@@ -6,14 +6,28 @@ import (
 	"testing"
 
 	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
 func TestExpression(t *testing.T) {
 	t.Run("invalid JSON path", func(t *testing.T) {
-		invalid := "invalid"
-		_, err := jsonpath.NewExpression(invalid)
-		require.Error(t, err)
+		invalid := []string{
+			"invalid",
+			"$.",
+			"$a.",
+			"$[",
+			"$['unterminated]",
+			"$[abc]",
+			"$[1:2:0]",
+			"$[?(@.a ==)]",
+		}
+		for _, path := range invalid {
+			t.Run(path, func(t *testing.T) {
+				_, err := jsonpath.NewExpression(path)
+				require.Error(t, err)
+			})
+		}
 	})
 
 	t.Run("Search", func(t *testing.T) {
@@ -56,9 +70,307 @@ func TestExpression(t *testing.T) {
 			exp, err := jsonpath.NewExpression("$.hello")
 			require.NoError(t, err)
 
-			result, err := exp.Search([]byte(`{}`))
+			_, err = exp.Search([]byte(`{}`))
+			require.Equal(t, jsonpath.ErrNotFound, errors.Cause(err))
+		})
+
+		t.Run("null", func(t *testing.T) {
+			exp, err := jsonpath.NewExpression("$.hello")
+			require.NoError(t, err)
+
+			result, err := exp.Search([]byte(`{"hello":null}`))
+			require.NoError(t, err)
+			require.Equal(t, "null", string(result))
+		})
+
+		t.Run("paths", func(t *testing.T) {
+			input := []byte(`{
+				"store": {
+					"book": [
+						{"title": "one", "price": 8.95, "isbn": "1"},
+						{"title": "two", "price": 12.99},
+						{"title": "three", "price": 22.99, "isbn": "3"}
+					],
+					"bicycle": {"colour": "red", "price": 19.95}
+				},
+				"special.key": {"with space": 1, "quote'd": 2},
+				"big": 12345678901234567890
+			}`)
+
+			tests := []struct {
+				title          string
+				path           string
+				expectedResult string
+			}{
+				{"dot notation", "$.store.bicycle.colour", `"red"`},
+				{"bracket notation", "$['store']['bicycle']['colour']", `"red"`},
+				{"double quoted bracket notation", `$["store"]["bicycle"]`, `{"colour":"red","price":19.95}`},
+				{"special characters", "$['special.key']['with space']", `1`},
+				{"escaped quote", `$['special.key']['quote\'d']`, `2`},
+				{"index", "$.store.book[1].title", `"two"`},
+				{"negative index", "$.store.book[-1].title", `"three"`},
+				{"number precision", "$.big", `12345678901234567890`},
+				{"wildcard", "$.store.book[*].title", `["one","two","three"]`},
+				{"dot wildcard", "$.store.bicycle.*", `["red",19.95]`},
+				{"index union", "$.store.book[0,2].title", `["one","three"]`},
+				{"name union", "$.store.bicycle['colour','price']", `["red",19.95]`},
+				{"slice", "$.store.book[1:].title", `["two","three"]`},
+				{"slice with step", "$.store.book[::2].title", `["one","three"]`},
+				{"deep scan", "$..price", `[19.95,8.95,12.99,22.99]`},
+				{"filter comparison", "$.store.book[?(@.price < 10)].title", `["one"]`},
+				{"filter existence", "$.store.book[?(@.isbn)].title", `["one","three"]`},
+				{"filter logical", "$.store.book[?(@.price > 10 && @.title != 'three')].title", `["two"]`},
+				{"filter root reference", "$.store.book[?(@.price > $.store.bicycle.price)].title", `["three"]`},
+				{"indefinite no match", "$.store.book[?(@.price > 100)]", `[]`},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					exp, err := jsonpath.NewExpression(tt.path)
+					require.NoError(t, err)
+
+					result, err := exp.Search(input)
+					require.NoError(t, err)
+					require.JSONEq(t, tt.expectedResult, string(result))
+				})
+			}
+		})
+	})
+
+	t.Run("NewReferencePath", func(t *testing.T) {
+		valid := []string{
+			"$",
+			"$.a.b",
+			"$['a.b'].c",
+			"$.a[0].b",
+		}
+		for _, path := range valid {
+			t.Run(path, func(t *testing.T) {
+				_, err := jsonpath.NewReferencePath(path)
+				require.NoError(t, err)
+			})
+		}
+
+		invalid := []string{
+			"$.a[*]",
+			"$.a.*",
+			"$..a",
+			"$.a[0,1]",
+			"$.a[1:2]",
+			"$.a[-1]",
+			"$.a[?(@.b)]",
+			"$['a','b']",
+		}
+		for _, path := range invalid {
+			t.Run(path, func(t *testing.T) {
+				_, err := jsonpath.NewReferencePath(path)
+				require.Error(t, err)
+
+				_, err = jsonpath.NewExpression(path)
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		tests := []struct {
+			title          string
+			path           string
+			input          string
+			value          string
+			expectedOutput string
+		}{
+			{"root replacement", "$", `{"a":1}`, `[1,2]`, `[1,2]`},
+			{"new field", "$.b", `{"a":1}`, `{"c":2}`, `{"a":1,"b":{"c":2}}`},
+			{"existing field", "$.a", `{"a":1}`, `2`, `{"a":2}`},
+			{"nested objects", "$.a.b.c", `{"a":{"x":1}}`, `true`, `{"a":{"x":1,"b":{"c":true}}}`},
+			{"bracket notation", "$['a.b']", `{}`, `1`, `{"a.b":1}`},
+			{"array element", "$.a[1]", `{"a":[1,2,3]}`, `"two"`, `{"a":[1,"two",3]}`},
+			{"field in array element", "$.a[0].b", `{"a":[{"b":1}]}`, `2`, `{"a":[{"b":2}]}`},
+			{"negative index", "$[-1]", `[1,2,3]`, `4`, `[1,2,4]`},
+			{"null value", "$.a", `{"a":1}`, `null`, `{"a":null}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				exp, err := jsonpath.NewExpression(tt.path)
+				require.NoError(t, err)
+
+				output, err := exp.Set([]byte(tt.input), []byte(tt.value))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(output))
+			})
+		}
+
+		errorTests := []struct {
+			title string
+			path  string
+			input string
+		}{
+			{"non object input", "$.a", `[1]`},
+			{"non object in the way", "$.a.b", `{"a":"string"}`},
+			{"non array", "$.a[0]", `{"a":{}}`},
+			{"missing array", "$.a[0]", `{}`},
+			{"index out of range", "$.a[3]", `{"a":[1,2,3]}`},
+			{"indefinite path", "$.a[*]", `{"a":[1]}`},
+			{"invalid input", "$.a", `{`},
+		}
+
+		for _, tt := range errorTests {
+			t.Run(tt.title, func(t *testing.T) {
+				exp, err := jsonpath.NewExpression(tt.path)
+				require.NoError(t, err)
+
+				_, err = exp.Set([]byte(tt.input), []byte(`1`))
+				require.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		tests := []struct {
+			title          string
+			path           string
+			input          string
+			expectedOutput string
+		}{
+			{"field", "$.a", `{"a":1,"b":2}`, `{"b":2}`},
+			{"nested field", "$.a.b", `{"a":{"b":1,"c":2}}`, `{"a":{"c":2}}`},
+			{"array element", "$.a[1]", `{"a":[1,2,3]}`, `{"a":[1,3]}`},
+			{"field in array element", "$[0].a", `[{"a":1,"b":2}]`, `[{"b":2}]`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				exp, err := jsonpath.NewExpression(tt.path)
+				require.NoError(t, err)
+
+				output, err := exp.Delete([]byte(tt.input))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(output))
+			})
+		}
+
+		t.Run("not found", func(t *testing.T) {
+			for _, path := range []string{"$.missing", "$.a.missing", "$.a[5]"} {
+				exp, err := jsonpath.NewExpression(path)
+				require.NoError(t, err)
+
+				_, err = exp.Delete([]byte(`{"a":[1]}`))
+				require.Equal(t, jsonpath.ErrNotFound, errors.Cause(err))
+			}
+		})
+
+		t.Run("root", func(t *testing.T) {
+			exp, err := jsonpath.NewExpression("$")
+			require.NoError(t, err)
+
+			_, err = exp.Delete([]byte(`{}`))
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("Document", func(t *testing.T) {
+		doc, err := jsonpath.NewDocument([]byte(`{"a":1,"b":{"c":"two"},"d":[1,2]}`))
+		require.NoError(t, err)
+
+		paths := []string{"$", "$.a", "$.b.c", "$.d[*]"}
+		exps := []jsonpath.Expression{}
+		for _, path := range paths {
+			exp, err := jsonpath.NewExpression(path)
+			require.NoError(t, err)
+			exps = append(exps, exp)
+		}
+
+		results, err := doc.Search(exps...)
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+		require.JSONEq(t, `{"a":1,"b":{"c":"two"},"d":[1,2]}`, string(results[0]))
+		require.JSONEq(t, `1`, string(results[1]))
+		require.JSONEq(t, `"two"`, string(results[2]))
+		require.JSONEq(t, `[1,2]`, string(results[3]))
+
+		t.Run("not found", func(t *testing.T) {
+			exp, err := jsonpath.NewExpression("$.missing")
+			require.NoError(t, err)
+
+			_, err = doc.Search(exps[1], exp)
+			require.Equal(t, jsonpath.ErrNotFound, errors.Cause(err))
+		})
+
+		t.Run("invalid json", func(t *testing.T) {
+			_, err := jsonpath.NewDocument([]byte(`{`))
+			require.Error(t, err)
+		})
+
+		t.Run("variables", func(t *testing.T) {
+			doc, err := jsonpath.NewDocument([]byte(`{"a":1}`))
+			require.NoError(t, err)
+			require.NoError(t, doc.SetVariable("order", []byte(`{"id":"o-1","items":[{"sku":"x"},{"sku":"y"}]}`)))
+			require.NoError(t, doc.SetVariable("count_2", []byte(`2`)))
+
+			tests := map[string]string{
+				"$order":              `{"id":"o-1","items":[{"sku":"x"},{"sku":"y"}]}`,
+				"$order.id":           `"o-1"`,
+				"$order.items[*].sku": `["x","y"]`,
+				"$count_2":            `2`,
+				"$.a":                 `1`,
+			}
+			for path, expected := range tests {
+				exp, err := jsonpath.NewExpression(path)
+				require.NoError(t, err)
+
+				result, err := exp.SearchDocument(doc)
+				require.NoError(t, err)
+				require.JSONEq(t, expected, string(result))
+			}
+
+			require.True(t, jsonpath.IsVariable("$order.id"))
+			require.False(t, jsonpath.IsVariable("$.order"))
+			require.JSONEq(t, `2`, string(doc.Variables()["count_2"]))
+
+			exp, err := jsonpath.NewExpression("$missing")
+			require.NoError(t, err)
+			_, err = exp.SearchDocument(doc)
+			require.Equal(t, jsonpath.ErrNotFound, errors.Cause(err))
+
+			_, err = exp.Search([]byte(`{"missing":1}`))
+			require.Equal(t, jsonpath.ErrNotFound, errors.Cause(err))
+
+			ref, err := jsonpath.NewReferencePath("$order.id")
+			require.NoError(t, err)
+			_, err = ref.Set([]byte(`{}`), []byte(`1`))
+			require.Error(t, err)
+		})
+
+		t.Run("variables decoded when referenced", func(t *testing.T) {
+			doc, err := jsonpath.NewDocument([]byte(`{"a":1}`))
+			require.NoError(t, err)
+			require.NoError(t, doc.SetVariable("invalid", []byte(`{`)))
+
+			exp, err := jsonpath.NewExpression("$.a")
+			require.NoError(t, err)
+			result, err := exp.SearchDocument(doc)
+			require.NoError(t, err)
+			require.JSONEq(t, `1`, string(result))
+
+			exp, err = jsonpath.NewExpression("$invalid")
+			require.NoError(t, err)
+			_, err = exp.SearchDocument(doc)
+			require.Error(t, err)
+		})
+
+		t.Run("context object", func(t *testing.T) {
+			doc, err := jsonpath.NewDocument([]byte(`{"a":1}`))
+			require.NoError(t, err)
+			require.NoError(t, doc.SetVariable(jsonpath.ContextVariable, []byte(`{"Task":{"Token":"t-1"}}`)))
+
+			exp, err := jsonpath.NewExpression("$$.Task.Token")
+			require.NoError(t, err)
+			result, err := exp.SearchDocument(doc)
 			require.NoError(t, err)
-			require.Nil(t, result)
+			require.JSONEq(t, `"t-1"`, string(result))
+			require.True(t, jsonpath.IsVariable("$$.Task"))
 		})
 	})
 }
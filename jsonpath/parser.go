@@ -0,0 +1,464 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parser parses the JSONPath syntax supported by the AWS states language into a list of segments
+type parser struct {
+	input     string
+	pos       int
+	reference bool // only permit reference path syntax
+}
+
+func parse(input string, reference bool) ([]segment, error) {
+	p := &parser{
+		input:     input,
+		reference: reference,
+	}
+
+	segments, err := p.parsePath()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid JSON path '%s'", input)
+	}
+
+	return segments, nil
+}
+
+func (p *parser) parsePath() ([]segment, error) {
+	if !strings.HasPrefix(p.input, "$") {
+		return nil, errors.New("must begin with '$'")
+	}
+	p.pos++
+
+	segments := []segment{}
+	for p.pos < len(p.input) {
+		seg, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
+
+func (p *parser) parseSegment() (segment, error) {
+	switch p.input[p.pos] {
+	case '.':
+		p.pos++
+		if p.peek() == '.' {
+			if p.reference {
+				return nil, p.errorf("deep scan is not permitted in a reference path")
+			}
+			p.pos++
+			inner, err := p.parseDescendantSelector()
+			if err != nil {
+				return nil, err
+			}
+			return descendant{inner}, nil
+		}
+		return p.parseDotSelector()
+	case '[':
+		return p.parseBracketSelector()
+	}
+
+	return nil, p.errorf("unexpected '%c'", p.input[p.pos])
+}
+
+func (p *parser) parseDescendantSelector() (segment, error) {
+	if p.peek() == '[' {
+		return p.parseBracketSelector()
+	}
+	return p.parseDotSelector()
+}
+
+func (p *parser) parseDotSelector() (segment, error) {
+	if p.peek() == '*' {
+		if p.reference {
+			return nil, p.errorf("wildcards are not permitted in a reference path")
+		}
+		p.pos++
+		return wildcard{}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '.' && p.input[p.pos] != '[' {
+		p.pos++
+	}
+
+	name := p.input[start:p.pos]
+	if name == "" {
+		return nil, p.errorf("expected field name")
+	}
+	if strings.ContainsAny(name, " ]'\"") {
+		return nil, p.errorf("invalid field name '%s', use bracket notation for special characters", name)
+	}
+
+	return child{names: []string{name}}, nil
+}
+
+func (p *parser) parseBracketSelector() (segment, error) {
+	p.pos++ // [
+	p.skipWhitespace()
+
+	var seg segment
+	var err error
+	switch c := p.peek(); {
+	case c == '*':
+		if p.reference {
+			return nil, p.errorf("wildcards are not permitted in a reference path")
+		}
+		p.pos++
+		seg = wildcard{}
+	case c == '?':
+		if p.reference {
+			return nil, p.errorf("filters are not permitted in a reference path")
+		}
+		seg, err = p.parseFilter()
+	case c == '\'' || c == '"':
+		seg, err = p.parseNames()
+	default:
+		seg, err = p.parseIndices()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+	if p.peek() != ']' {
+		return nil, p.errorf("expected ']'")
+	}
+	p.pos++
+
+	return seg, nil
+}
+
+func (p *parser) parseNames() (segment, error) {
+	names := []string{}
+	for {
+		name, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		p.skipWhitespace()
+		if p.peek() != ',' {
+			break
+		}
+		if p.reference {
+			return nil, p.errorf("unions are not permitted in a reference path")
+		}
+		p.pos++
+		p.skipWhitespace()
+	}
+
+	return child{names: names}, nil
+}
+
+func (p *parser) parseQuoted() (string, error) {
+	quote := p.peek()
+	if quote != '\'' && quote != '"' {
+		return "", p.errorf("expected quoted string")
+	}
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			b.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) parseIndices() (segment, error) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != ']' {
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.input[start:p.pos])
+	if raw == "" {
+		return nil, p.errorf("expected index")
+	}
+
+	if strings.Contains(raw, ":") {
+		if p.reference {
+			return nil, p.errorf("slices are not permitted in a reference path")
+		}
+		return parseSlice(raw)
+	}
+
+	indices := []int{}
+	for _, part := range strings.Split(raw, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.Errorf("invalid index '%s'", part)
+		}
+		indices = append(indices, index)
+	}
+
+	if p.reference {
+		if len(indices) > 1 {
+			return nil, p.errorf("unions are not permitted in a reference path")
+		}
+		if indices[0] < 0 {
+			return nil, p.errorf("negative indices are not permitted in a reference path")
+		}
+	}
+
+	return index{indices: indices}, nil
+}
+
+func parseSlice(raw string) (segment, error) {
+	parts := strings.Split(raw, ":")
+	if len(parts) > 3 {
+		return nil, errors.Errorf("invalid slice '%s'", raw)
+	}
+
+	bounds := make([]*int, 3)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Errorf("invalid slice '%s'", raw)
+		}
+		bounds[i] = &n
+	}
+
+	if bounds[2] != nil && *bounds[2] == 0 {
+		return nil, errors.Errorf("invalid slice step '%s'", raw)
+	}
+
+	return slice{start: bounds[0], end: bounds[1], step: bounds[2]}, nil
+}
+
+func (p *parser) parseFilter() (segment, error) {
+	p.pos++ // ?
+	p.skipWhitespace()
+	if p.peek() != '(' {
+		return nil, p.errorf("expected '(' after '?'")
+	}
+	p.pos++
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+	if p.peek() != ')' {
+		return nil, p.errorf("expected ')'")
+	}
+	p.pos++
+
+	return filter{expr}, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipWhitespace()
+		if !strings.HasPrefix(p.input[p.pos:], "||") {
+			return left, nil
+		}
+		p.pos += 2
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipWhitespace()
+		if !strings.HasPrefix(p.input[p.pos:], "&&") {
+			return left, nil
+		}
+		p.pos += 2
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	p.skipWhitespace()
+
+	switch {
+	case p.peek() == '!':
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{inner}, nil
+	case p.peek() == '(':
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+		if p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *parser) parseComparison() (expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+	for _, op := range comparisonOperators {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparison{op: op, left: left, right: right}, nil
+		}
+	}
+
+	// an operand on its own tests for existence
+	return exists{left}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	p.skipWhitespace()
+
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && !strings.ContainsRune(" )=!<>&|", rune(p.input[p.pos])) {
+			if p.input[p.pos] == '[' {
+				if err := p.skipBrackets(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			p.pos++
+		}
+
+		raw := p.input[start:p.pos]
+		segments, err := parse("$"+raw[1:], false)
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{relative: c == '@', segments: segments}, nil
+	case c == '\'' || c == '"':
+		str, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return literalOperand{str}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(" )=!<>&|", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	raw := p.input[start:p.pos]
+
+	switch raw {
+	case "true":
+		return literalOperand{true}, nil
+	case "false":
+		return literalOperand{false}, nil
+	case "null":
+		return literalOperand{nil}, nil
+	}
+
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		return nil, p.errorf("invalid operand '%s'", raw)
+	}
+
+	return literalOperand{json.Number(raw)}, nil
+}
+
+func (p *parser) skipBrackets() error {
+	depth := 0
+	var quote byte
+	for ; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return nil
+			}
+		}
+	}
+
+	return p.errorf("unterminated '['")
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) skipWhitespace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("position %d: %s", p.pos, errors.Errorf(format, args...))
+}
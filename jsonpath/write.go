@@ -0,0 +1,179 @@
+package jsonpath
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Set returns a copy of the document with value placed at the node the expression identifies. Missing
+// intermediate objects are created, whereas a non-object (or non-array for indices) in the way results
+// in an error. An expression of "$" replaces the document entirely.
+func (e expression) Set(docJSON, valueJSON []byte) ([]byte, error) {
+	if e.variable != "" {
+		return []byte{}, errors.Errorf("unable to set '%s', variables are read only", e.raw)
+	}
+
+	value, err := decode(valueJSON)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if len(e.segments) == 0 {
+		return encode(value)
+	}
+
+	doc, err := decode(docJSON)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	doc, err = set(doc, e.segments, value)
+	if err != nil {
+		return []byte{}, errors.Wrapf(err, "unable to set '%s'", e.raw)
+	}
+
+	return encode(doc)
+}
+
+// Delete returns a copy of the document with the node the expression identifies removed. Deleting a
+// node that doesn't exist results in ErrNotFound.
+func (e expression) Delete(docJSON []byte) ([]byte, error) {
+	if e.variable != "" {
+		return []byte{}, errors.Errorf("unable to delete '%s', variables are read only", e.raw)
+	}
+
+	if len(e.segments) == 0 {
+		return []byte{}, errors.Errorf("unable to delete '%s', the root can't be deleted", e.raw)
+	}
+
+	doc, err := decode(docJSON)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	doc, err = remove(doc, e.segments)
+	if err != nil {
+		return []byte{}, errors.Wrapf(err, "unable to delete '%s'", e.raw)
+	}
+
+	return encode(doc)
+}
+
+func set(node interface{}, segments []segment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	switch seg := segments[0].(type) {
+	case child:
+		if !seg.definite() {
+			break
+		}
+
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("can't set field '%s' on a non object", seg.names[0])
+		}
+
+		next, ok := obj[seg.names[0]]
+		if !ok {
+			next = map[string]interface{}{}
+		}
+
+		updated, err := set(next, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		obj[seg.names[0]] = updated
+		return obj, nil
+	case index:
+		if !seg.definite() {
+			break
+		}
+
+		arr, i, err := element(node, seg.indices[0])
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := set(arr[i], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		arr[i] = updated
+		return arr, nil
+	}
+
+	return nil, errors.New("a value can only be set using a path that identifies a single node")
+}
+
+func remove(node interface{}, segments []segment) (interface{}, error) {
+	switch seg := segments[0].(type) {
+	case child:
+		if !seg.definite() {
+			break
+		}
+
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, ErrNotFound
+		}
+
+		next, ok := obj[seg.names[0]]
+		if !ok {
+			return nil, ErrNotFound
+		}
+
+		if len(segments) == 1 {
+			delete(obj, seg.names[0])
+			return obj, nil
+		}
+
+		updated, err := remove(next, segments[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		obj[seg.names[0]] = updated
+		return obj, nil
+	case index:
+		if !seg.definite() {
+			break
+		}
+
+		arr, i, err := element(node, seg.indices[0])
+		if err != nil {
+			return nil, ErrNotFound
+		}
+
+		if len(segments) == 1 {
+			return append(arr[:i], arr[i+1:]...), nil
+		}
+
+		updated, err := remove(arr[i], segments[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		arr[i] = updated
+		return arr, nil
+	}
+
+	return nil, errors.New("a value can only be deleted using a path that identifies a single node")
+}
+
+// element returns the array and the normalised position of the index within it
+func element(node interface{}, i int) ([]interface{}, int, error) {
+	arr, ok := node.([]interface{})
+	if !ok {
+		return nil, 0, errors.Errorf("can't index %d on a non array", i)
+	}
+
+	pos := normalise(i, len(arr))
+	if pos < 0 || pos >= len(arr) {
+		return nil, 0, errors.Errorf("index %d is out of range", i)
+	}
+
+	return arr, pos, nil
+}
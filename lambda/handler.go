@@ -0,0 +1,292 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+const (
+	// LatestVersion is the version of a function's unpublished handler
+	LatestVersion = "$LATEST"
+
+	// UnhandledFunctionError is the FunctionError of an invocation whose handler returned an error
+	UnhandledFunctionError = "Unhandled"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FunctionErrorPayload is the payload of an invocation whose handler returned an error
+type FunctionErrorPayload struct {
+	ErrorMessage string `json:"errorMessage"`
+	ErrorType    string `json:"errorType"`
+}
+
+// NewFunctionErrorPayload returns the payload of an error as the Go runtime does, typed by the name of
+// the error's type
+func NewFunctionErrorPayload(err error) []byte {
+	payload, _ := json.Marshal(FunctionErrorPayload{
+		ErrorMessage: err.Error(),
+		ErrorType:    typeName(err),
+	})
+
+	return payload
+}
+
+// handler is a Go handler function of one of the signatures supported by the Go runtime e.g.
+// func(context.Context, TIn) (TOut, error)
+type handler struct {
+	fn reflect.Value
+}
+
+func newHandler(fn interface{}) (handler, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return handler{}, errors.Errorf("handler kind %s is not %s", fnValue.Kind(), reflect.Func)
+	}
+
+	fnType := fnValue.Type()
+	if fnType.NumIn() > 2 {
+		return handler{}, errors.Errorf("handlers may not take more than two arguments, but handler takes %d", fnType.NumIn())
+	}
+	if fnType.NumIn() == 2 && !fnType.In(0).Implements(contextType) {
+		return handler{}, errors.New("handler takes two arguments, but the first is not Context")
+	}
+
+	switch fnType.NumOut() {
+	case 0:
+	case 1:
+		if !fnType.Out(0).Implements(errorType) {
+			return handler{}, errors.New("handler returns a single value, but it does not implement error")
+		}
+	case 2:
+		if !fnType.Out(1).Implements(errorType) {
+			return handler{}, errors.New("handler returns two values, but the second does not implement error")
+		}
+	default:
+		return handler{}, errors.Errorf("handlers may not return more than two values, but handler returns %d", fnType.NumOut())
+	}
+
+	return handler{fn: fnValue}, nil
+}
+
+// invoke calls the handler with the payload unmarshaled as its event and returns its marshaled result. A
+// handler that returns an error or panics fails with the payload of its error.
+func (h handler) invoke(ctx context.Context, payload []byte) (result []byte, functionErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			functionErr = panicError{value: r}
+		}
+	}()
+
+	fnType := h.fn.Type()
+	args := []reflect.Value{}
+	if fnType.NumIn() > 0 && fnType.In(0).Implements(contextType) {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+
+	if fnType.NumIn() > len(args) {
+		event := reflect.New(fnType.In(fnType.NumIn() - 1))
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, event.Interface()); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, event.Elem())
+	}
+
+	out := h.fn.Call(args)
+	if len(out) > 0 {
+		if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+			return nil, err
+		}
+	}
+
+	if len(out) < 2 {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(out[0].Interface())
+}
+
+// panicError is the error of a handler that panicked, typed by the panic's value
+type panicError struct {
+	value interface{}
+}
+
+func (p panicError) Error() string {
+	return fmt.Sprintf("%v", p.value)
+}
+
+func typeName(err error) string {
+	if p, ok := err.(panicError); ok {
+		return valueTypeName(p.value)
+	}
+
+	return valueTypeName(err)
+}
+
+func valueTypeName(value interface{}) string {
+	t := reflect.TypeOf(value)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		return t.Elem().Name()
+	}
+
+	return t.Name()
+}
+
+// function is a registered function's versions, of which LatestVersion is unpublished, and aliases
+type function struct {
+	versions map[string]handler
+	aliases  map[string]string
+}
+
+// HandlerClient is a Client which invokes Go handler functions registered by function name. Functions
+// can be invoked by name, partial or full ARN, qualified by version or alias in the name or Qualifier.
+type HandlerClient struct {
+	mu        sync.RWMutex
+	functions map[string]*function
+}
+
+func NewHandlerClient() *HandlerClient {
+	return &HandlerClient{
+		functions: map[string]*function{},
+	}
+}
+
+// Register registers the handler as the $LATEST version of the function
+func (c *HandlerClient) Register(name string, fn interface{}) error {
+	return c.RegisterVersion(name, LatestVersion, fn)
+}
+
+// RegisterVersion registers the handler as a published version of the function e.g. "1"
+func (c *HandlerClient) RegisterVersion(name, version string, fn interface{}) error {
+	h, err := newHandler(fn)
+	if err != nil {
+		return errors.Wrapf(err, "error registering handler of %s:%s", name, version)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.function(name).versions[version] = h
+	return nil
+}
+
+// RegisterAlias registers an alias of a version of the function e.g. "live" for version "1"
+func (c *HandlerClient) RegisterAlias(name, alias, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.function(name).aliases[alias] = version
+}
+
+func (c *HandlerClient) function(name string) *function {
+	f, ok := c.functions[name]
+	if !ok {
+		f = &function{
+			versions: map[string]handler{},
+			aliases:  map[string]string{},
+		}
+		c.functions[name] = f
+	}
+
+	return f
+}
+
+// Invoke invokes the handler of the function version the input resolves to. An Event invocation invokes
+// the handler asynchronously and a DryRun invocation doesn't invoke it.
+func (c *HandlerClient) Invoke(input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	name, qualifier := ParseFunctionName(aws.StringValue(input.FunctionName))
+	if input.Qualifier != nil {
+		qualifier = aws.StringValue(input.Qualifier)
+	}
+
+	h, version, err := c.resolve(name, qualifier)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &lambda.InvokeOutput{
+		ExecutedVersion: aws.String(version),
+	}
+
+	switch aws.StringValue(input.InvocationType) {
+	case lambda.InvocationTypeDryRun:
+		output.StatusCode = aws.Int64(http.StatusNoContent)
+		return output, nil
+	case lambda.InvocationTypeEvent:
+		go h.invoke(context.Background(), input.Payload)
+		output.StatusCode = aws.Int64(http.StatusAccepted)
+		return output, nil
+	}
+
+	output.StatusCode = aws.Int64(http.StatusOK)
+	output.Payload, err = h.invoke(context.Background(), input.Payload)
+	if err != nil {
+		output.FunctionError = aws.String(UnhandledFunctionError)
+		output.Payload = NewFunctionErrorPayload(err)
+	}
+
+	return output, nil
+}
+
+func (c *HandlerClient) resolve(name, qualifier string) (handler, string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	version := qualifier
+	if version == "" {
+		version = LatestVersion
+	}
+
+	f, ok := c.functions[name]
+	if ok {
+		if aliasVersion, isAlias := f.aliases[version]; isAlias {
+			version = aliasVersion
+		}
+
+		if h, ok := f.versions[version]; ok {
+			return h, version, nil
+		}
+	}
+
+	if qualifier != "" {
+		name += ":" + qualifier
+	}
+
+	return handler{}, "", awserr.New(lambda.ErrCodeResourceNotFoundException, "Function not found: "+name, nil)
+}
+
+// ParseFunctionName returns the name and qualifier of a function from its name, partial ARN or ARN e.g.
+// test, test:1, 123456789012:function:test or arn:aws:lambda:us-east-1:123456789012:function:test:live
+func ParseFunctionName(functionName string) (string, string) {
+	parts := strings.Split(functionName, ":")
+	for i, part := range parts {
+		if part == "function" && i+1 < len(parts) {
+			parts = parts[i+1:]
+			break
+		}
+	}
+
+	if len(parts) > 1 {
+		return parts[0], parts[1]
+	}
+
+	return parts[0], ""
+}
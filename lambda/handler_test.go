@@ -0,0 +1,157 @@
+// +build unit
+
+package lambda_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	Name string `json:"name"`
+}
+
+type greeting struct {
+	Message string `json:"message"`
+}
+
+type MyCustomError struct{}
+
+func (MyCustomError) Error() string {
+	return "custom error"
+}
+
+func TestHandlerClient(t *testing.T) {
+	client := lambda.NewHandlerClient()
+	require.NoError(t, client.Register("greet", func(ctx context.Context, e event) (greeting, error) {
+		return greeting{Message: "hello " + e.Name}, nil
+	}))
+	require.NoError(t, client.RegisterVersion("greet", "1", func(e event) (greeting, error) {
+		return greeting{Message: "hi " + e.Name}, nil
+	}))
+	client.RegisterAlias("greet", "live", "1")
+	require.NoError(t, client.Register("fail", func() error {
+		return &MyCustomError{}
+	}))
+	require.NoError(t, client.Register("panic", func() {
+		panic("boom")
+	}))
+
+	t.Run("register", func(t *testing.T) {
+		tests := []struct {
+			title   string
+			handler interface{}
+		}{
+			{"not a function", "handler"},
+			{"too many arguments", func(context.Context, event, event) {}},
+			{"first argument not context", func(event, event) {}},
+			{"single value not error", func() greeting { return greeting{} }},
+			{"second value not error", func() (greeting, greeting) { return greeting{}, greeting{} }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				require.Error(t, client.Register("invalid", tt.handler))
+			})
+		}
+	})
+
+	t.Run("invoke", func(t *testing.T) {
+		tests := []struct {
+			title           string
+			input           *awslambda.InvokeInput
+			expectedVersion string
+			expectedPayload string
+		}{
+			{
+				"name",
+				&awslambda.InvokeInput{FunctionName: aws.String("greet"), Payload: []byte(`{"name":"world"}`)},
+				lambda.LatestVersion,
+				`{"message":"hello world"}`,
+			},
+			{
+				"ARN",
+				&awslambda.InvokeInput{FunctionName: aws.String("arn:aws:lambda:us-east-1:123456789012:function:greet"), Payload: []byte(`{"name":"world"}`)},
+				lambda.LatestVersion,
+				`{"message":"hello world"}`,
+			},
+			{
+				"qualified ARN",
+				&awslambda.InvokeInput{FunctionName: aws.String("arn:aws:lambda:us-east-1:123456789012:function:greet:1"), Payload: []byte(`{"name":"world"}`)},
+				"1",
+				`{"message":"hi world"}`,
+			},
+			{
+				"alias qualifier",
+				&awslambda.InvokeInput{FunctionName: aws.String("greet"), Qualifier: aws.String("live"), Payload: []byte(`{"name":"world"}`)},
+				"1",
+				`{"message":"hi world"}`,
+			},
+			{
+				"no result",
+				&awslambda.InvokeInput{FunctionName: aws.String("123456789012:function:panic"), InvocationType: aws.String(awslambda.InvocationTypeDryRun)},
+				lambda.LatestVersion,
+				``,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				output, err := client.Invoke(tt.input)
+				require.NoError(t, err)
+				require.Nil(t, output.FunctionError)
+				require.Equal(t, tt.expectedVersion, aws.StringValue(output.ExecutedVersion))
+				if tt.expectedPayload != "" {
+					require.JSONEq(t, tt.expectedPayload, string(output.Payload))
+				}
+			})
+		}
+	})
+
+	t.Run("function error", func(t *testing.T) {
+		tests := []struct {
+			title           string
+			functionName    string
+			expectedPayload lambda.FunctionErrorPayload
+		}{
+			{
+				"error",
+				"fail",
+				lambda.FunctionErrorPayload{ErrorMessage: "custom error", ErrorType: "MyCustomError"},
+			},
+			{
+				"panic",
+				"panic",
+				lambda.FunctionErrorPayload{ErrorMessage: "boom", ErrorType: "string"},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				output, err := client.Invoke(&awslambda.InvokeInput{FunctionName: aws.String(tt.functionName)})
+				require.NoError(t, err)
+				require.Equal(t, lambda.UnhandledFunctionError, aws.StringValue(output.FunctionError))
+
+				payload := lambda.FunctionErrorPayload{}
+				require.NoError(t, json.Unmarshal(output.Payload, &payload))
+				require.Equal(t, tt.expectedPayload, payload)
+			})
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.Invoke(&awslambda.InvokeInput{FunctionName: aws.String("greet:2")})
+		require.Error(t, err)
+		awsErr, ok := errors.Cause(err).(awserr.Error)
+		require.True(t, ok)
+		require.Equal(t, awslambda.ErrCodeResourceNotFoundException, awsErr.Code())
+	})
+}
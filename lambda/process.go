@@ -0,0 +1,260 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StdioProtocol is the protocol of a process which reads its event from stdin and writes its result
+	// to stdout. A process which exits with a non-zero status fails with the error it writes to stdout,
+	// if any, or stderr.
+	StdioProtocol = "stdio"
+	// RuntimeAPIProtocol is the protocol of a process which polls the Lambda Runtime API for its event
+	// e.g. a Go binary built with aws-lambda-go or a provided.al2 bootstrap
+	RuntimeAPIProtocol = "runtime-api"
+
+	// DefaultFunctionTimeout is the default timeout of a lambda function
+	DefaultFunctionTimeout = 3 * time.Second
+
+	ErrTypeTimedOut  = "Sandbox.Timedout"
+	ErrTypeExitError = "Runtime.ExitError"
+	ErrTypeUnknown   = "Runtime.Unknown"
+
+	// maxLogResult is the maximum size of the log tail returned by an invocation with the Tail log type
+	maxLogResult = 4 * 1024
+)
+
+// ProcessFunction is a function implemented by a local executable, which is run for each invocation
+type ProcessFunction struct {
+	Command  string
+	Args     []string
+	Env      map[string]string // set in addition to the environment of the client and the lambda variables
+	Timeout  time.Duration     // DefaultFunctionTimeout if 0
+	Protocol string            // StdioProtocol if empty
+}
+
+// ProcessClient is a Client which invokes functions by running their executables
+type ProcessClient struct {
+	mu        sync.RWMutex
+	functions map[string]ProcessFunction
+}
+
+func NewProcessClient() *ProcessClient {
+	return &ProcessClient{
+		functions: map[string]ProcessFunction{},
+	}
+}
+
+// Register registers the executable of the function
+func (c *ProcessClient) Register(name string, fn ProcessFunction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.functions[name] = fn
+}
+
+// Invoke runs the executable of the function with the payload. A process that exits unsuccessfully or
+// doesn't complete within the function's timeout fails the invocation with a function error.
+func (c *ProcessClient) Invoke(input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	name, _ := ParseFunctionName(aws.StringValue(input.FunctionName))
+
+	c.mu.RLock()
+	fn, ok := c.functions[name]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, awserr.New(lambda.ErrCodeResourceNotFoundException, "Function not found: "+name, nil)
+	}
+
+	output := &lambda.InvokeOutput{
+		ExecutedVersion: aws.String(LatestVersion),
+	}
+
+	switch aws.StringValue(input.InvocationType) {
+	case lambda.InvocationTypeDryRun:
+		output.StatusCode = aws.Int64(http.StatusNoContent)
+		return output, nil
+	case lambda.InvocationTypeEvent:
+		go fn.run(name, input.Payload)
+		output.StatusCode = aws.Int64(http.StatusAccepted)
+		return output, nil
+	}
+
+	result, logs, err := fn.run(name, input.Payload)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error running %s", name)
+	}
+
+	output.StatusCode = aws.Int64(http.StatusOK)
+	output.Payload = result.payload
+	if result.functionErr {
+		output.FunctionError = aws.String(UnhandledFunctionError)
+	}
+
+	if aws.StringValue(input.LogType) == lambda.LogTypeTail {
+		if len(logs) > maxLogResult {
+			logs = logs[len(logs)-maxLogResult:]
+		}
+		output.LogResult = aws.String(base64.StdEncoding.EncodeToString(logs))
+	}
+
+	return output, nil
+}
+
+// processResult is the result of an invocation, whose payload is an error payload if it failed
+type processResult struct {
+	payload     []byte
+	functionErr bool
+}
+
+// run runs the executable and returns its result and logs
+func (fn ProcessFunction) run(name string, payload []byte) (processResult, []byte, error) {
+	timeout := fn.Timeout
+	if timeout == 0 {
+		timeout = DefaultFunctionTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, fn.Command, fn.Args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = fn.environ(name, timeout)
+
+	var (
+		result processResult
+		err    error
+	)
+	if fn.Protocol == RuntimeAPIProtocol {
+		result, err = fn.runRuntimeAPI(ctx, cmd, name, timeout, payload)
+		// the runtime's output is its logs
+		stderr.Write(stdout.Bytes())
+	} else {
+		result, err = fn.runStdio(cmd, payload, stdout, stderr)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return timeoutResult(timeout), stderr.Bytes(), nil
+	}
+
+	return result, stderr.Bytes(), err
+}
+
+func (fn ProcessFunction) runStdio(cmd *exec.Cmd, payload []byte, stdout, stderr *bytes.Buffer) (processResult, error) {
+	cmd.Stdin = bytes.NewReader(payload)
+
+	err := cmd.Run()
+	if err == nil {
+		return processResult{payload: stdout.Bytes()}, nil
+	}
+
+	if _, ok := err.(*exec.ExitError); !ok {
+		return processResult{}, err
+	}
+
+	// a process may write its own error payload
+	functionErr := FunctionErrorPayload{}
+	if json.Unmarshal(stdout.Bytes(), &functionErr) == nil && functionErr.ErrorType != "" {
+		return processResult{payload: stdout.Bytes(), functionErr: true}, nil
+	}
+
+	message := strings.TrimSpace(stderr.String())
+	if message == "" {
+		message = err.Error()
+	}
+
+	return errorResult(ErrTypeExitError, message), nil
+}
+
+// runRuntimeAPI runs a runtime for a single invocation, which it polls for from a runtime API
+func (fn ProcessFunction) runRuntimeAPI(ctx context.Context, cmd *exec.Cmd, name string, timeout time.Duration, payload []byte) (processResult, error) {
+	api, err := NewRuntimeAPI(name, timeout)
+	if err != nil {
+		return processResult{}, err
+	}
+	defer api.Close()
+
+	cmd.Env = append(cmd.Env, "AWS_LAMBDA_RUNTIME_API="+api.Address())
+	if err := cmd.Start(); err != nil {
+		return processResult{}, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	type invokeResult struct {
+		result processResult
+		err    error
+	}
+	results := make(chan invokeResult, 1)
+	go func() {
+		result, err := api.invoke(ctx, payload)
+		results <- invokeResult{result, err}
+	}()
+
+	select {
+	case res := <-results:
+		cmd.Process.Kill()
+		<-exited
+		return res.result, res.err
+	case exitErr := <-exited:
+		// a runtime that failed to initialise exits with its init error as the result of the invocation
+		api.Close()
+		if res := <-results; res.err == nil || ctx.Err() != nil {
+			return res.result, nil
+		}
+
+		message := "Runtime exited without providing a reason"
+		if exitErr != nil {
+			message = "Runtime exited with error: " + exitErr.Error()
+		}
+		return errorResult(ErrTypeExitError, message), nil
+	}
+}
+
+// environ returns the environment of the process, which includes the lambda variables of the function
+func (fn ProcessFunction) environ(name string, timeout time.Duration) []string {
+	env := append(
+		os.Environ(),
+		"AWS_LAMBDA_FUNCTION_NAME="+name,
+		"AWS_LAMBDA_FUNCTION_VERSION="+LatestVersion,
+		fmt.Sprintf("AWS_LAMBDA_FUNCTION_TIMEOUT=%d", int(timeout.Seconds())),
+	)
+
+	for key, value := range fn.Env {
+		env = append(env, key+"="+value)
+	}
+
+	return env
+}
+
+func errorResult(errorType, message string) processResult {
+	payload, _ := json.Marshal(FunctionErrorPayload{
+		ErrorMessage: message,
+		ErrorType:    errorType,
+	})
+
+	return processResult{payload: payload, functionErr: true}
+}
+
+func timeoutResult(timeout time.Duration) processResult {
+	return errorResult(ErrTypeTimedOut, fmt.Sprintf("Task timed out after %.2f seconds", timeout.Seconds()))
+}
@@ -0,0 +1,164 @@
+// +build unit
+
+package lambda_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess isn't a test, it's a runtime run by the process client in the other tests
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("LAMBDA_HELPER_PROCESS")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API") + "/2018-06-01/runtime/invocation/"
+	resp, err := http.Get(api + "next")
+	if err != nil {
+		os.Exit(2)
+	}
+	payload, _ := ioutil.ReadAll(resp.Body)
+	requestID := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+	fmt.Println("handling", requestID)
+
+	switch mode {
+	case "echo":
+		result, _ := json.Marshal(map[string]string{
+			"event":    string(payload),
+			"function": os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+			"stage":    os.Getenv("STAGE"),
+		})
+		http.Post(api+requestID+"/response", "application/json", bytes.NewReader(result))
+	case "error":
+		http.Post(api+requestID+"/error", "application/json", bytes.NewReader([]byte(`{"errorType":"MyCustomError","errorMessage":"custom error"}`)))
+	case "exit":
+		os.Exit(1)
+	}
+
+	// a runtime polls for invocations until it's stopped
+	http.Get(api + "next")
+}
+
+func TestProcessClient(t *testing.T) {
+	client := lambda.NewProcessClient()
+	client.Register("cat", lambda.ProcessFunction{Command: "cat"})
+	client.Register("fail", lambda.ProcessFunction{Command: "sh", Args: []string{"-c", "echo failed >&2; exit 1"}})
+	client.Register("custom-error", lambda.ProcessFunction{
+		Command: "sh",
+		Args:    []string{"-c", `echo '{"errorType":"MyCustomError","errorMessage":"custom error"}'; exit 1`},
+	})
+	client.Register("env", lambda.ProcessFunction{
+		Command: "sh",
+		Args:    []string{"-c", `echo "{\"function\":\"$AWS_LAMBDA_FUNCTION_NAME\",\"stage\":\"$STAGE\"}"; echo logged >&2`},
+		Env:     map[string]string{"STAGE": "test"},
+	})
+	client.Register("sleep", lambda.ProcessFunction{Command: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond})
+	for _, mode := range []string{"echo", "error", "exit"} {
+		client.Register("runtime-"+mode, lambda.ProcessFunction{
+			Command:  os.Args[0],
+			Args:     []string{"-test.run=TestHelperProcess"},
+			Env:      map[string]string{"LAMBDA_HELPER_PROCESS": mode, "STAGE": "test"},
+			Protocol: lambda.RuntimeAPIProtocol,
+		})
+	}
+
+	tests := []struct {
+		title                 string
+		functionName          string
+		expectedPayload       string
+		expectedFunctionError bool
+	}{
+		{
+			"stdio",
+			"cat",
+			`{"a":1}`,
+			false,
+		},
+		{
+			"stdio exit error",
+			"fail",
+			`{"errorType":"Runtime.ExitError","errorMessage":"failed"}`,
+			true,
+		},
+		{
+			"stdio error payload",
+			"custom-error",
+			`{"errorType":"MyCustomError","errorMessage":"custom error"}`,
+			true,
+		},
+		{
+			"stdio environment",
+			"arn:aws:lambda:us-east-1:123456789012:function:env",
+			`{"function":"env","stage":"test"}`,
+			false,
+		},
+		{
+			"timeout",
+			"sleep",
+			`{"errorType":"Sandbox.Timedout","errorMessage":"Task timed out after 0.10 seconds"}`,
+			true,
+		},
+		{
+			"runtime API",
+			"runtime-echo",
+			`{"event":"{\"a\":1}","function":"runtime-echo","stage":"test"}`,
+			false,
+		},
+		{
+			"runtime API error",
+			"runtime-error",
+			`{"errorType":"MyCustomError","errorMessage":"custom error"}`,
+			true,
+		},
+		{
+			"runtime API exit",
+			"runtime-exit",
+			`{"errorType":"Runtime.ExitError","errorMessage":"Runtime exited with error: exit status 1"}`,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			output, err := client.Invoke(&awslambda.InvokeInput{
+				FunctionName: aws.String(tt.functionName),
+				Payload:      []byte(`{"a":1}`),
+			})
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedPayload, string(output.Payload))
+			require.Equal(t, tt.expectedFunctionError, output.FunctionError != nil)
+		})
+	}
+
+	t.Run("log tail", func(t *testing.T) {
+		output, err := client.Invoke(&awslambda.InvokeInput{
+			FunctionName: aws.String("env"),
+			LogType:      aws.String(awslambda.LogTypeTail),
+		})
+		require.NoError(t, err)
+
+		logs, err := base64.StdEncoding.DecodeString(aws.StringValue(output.LogResult))
+		require.NoError(t, err)
+		require.Equal(t, "logged\n", string(logs))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.Invoke(&awslambda.InvokeInput{FunctionName: aws.String("missing")})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,321 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	runtimeAPIPath = "/2018-06-01/runtime"
+
+	// invokedFunctionARNPrefix is the prefix of the ARNs of the functions invoked by runtimes, in the
+	// region and account of the step functions' contexts
+	invokedFunctionARNPrefix = "arn:aws:lambda:us-east-1:123456789012:function:"
+)
+
+// ErrRuntimeAPIClosed is returned by the invocations of a closed runtime API
+var ErrRuntimeAPIClosed = errors.New("runtime API closed")
+
+// invocation is an invocation of a function served to a runtime, which sends its result
+type invocation struct {
+	requestID    string
+	functionName string
+	payload      []byte
+	deadline     time.Time
+	result       chan processResult
+}
+
+func newInvocation(functionName string, payload []byte, deadline time.Time) invocation {
+	return invocation{
+		requestID:    uuid.New(),
+		functionName: functionName,
+		payload:      payload,
+		deadline:     deadline,
+		result:       make(chan processResult, 1),
+	}
+}
+
+// writeTo writes the invocation as the response to a request for the next invocation
+func (i invocation) writeTo(w http.ResponseWriter) {
+	w.Header().Set("Lambda-Runtime-Aws-Request-Id", i.requestID)
+	w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(i.deadline.UnixNano()/int64(time.Millisecond), 10))
+	w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", invokedFunctionARNPrefix+i.functionName)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(i.payload)
+}
+
+// RuntimeAPI is a local Lambda Runtime API endpoint of a function. Its invocations are queued until a
+// runtime polls for the next invocation, e.g. any runtime binary run with AWS_LAMBDA_RUNTIME_API set to
+// the endpoint's address, and the runtime's response or error is their result. RuntimeAPI is a Client.
+type RuntimeAPI struct {
+	functionName string
+	timeout      time.Duration
+	listener     net.Listener
+	server       *http.Server
+	invocations  chan invocation
+	done         chan struct{}
+	closeOnce    sync.Once
+
+	mu         sync.Mutex
+	inProgress map[string]invocation
+	initErr    *processResult
+	initFailed chan struct{}
+}
+
+// NewRuntimeAPI starts a runtime API of the function on a local port. Invocations that don't complete
+// within the timeout, DefaultFunctionTimeout if 0, time out.
+func NewRuntimeAPI(functionName string, timeout time.Duration) (*RuntimeAPI, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "error listening for runtime API requests")
+	}
+
+	if timeout == 0 {
+		timeout = DefaultFunctionTimeout
+	}
+
+	r := &RuntimeAPI{
+		functionName: functionName,
+		timeout:      timeout,
+		listener:     listener,
+		invocations:  make(chan invocation),
+		done:         make(chan struct{}),
+		inProgress:   map[string]invocation{},
+		initFailed:   make(chan struct{}),
+	}
+	r.server = &http.Server{Handler: r}
+	go r.server.Serve(listener)
+
+	return r, nil
+}
+
+// Address returns the host and port of the endpoint, the value of AWS_LAMBDA_RUNTIME_API
+func (r *RuntimeAPI) Address() string {
+	return r.listener.Addr().String()
+}
+
+// Close stops the endpoint and fails the invocations in progress
+func (r *RuntimeAPI) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.server.Close()
+	})
+
+	return err
+}
+
+// Invoke queues an invocation of the function and waits for its result, unless it's an Event
+// invocation. An invocation fails with the runtime's error and times out if the runtime doesn't respond
+// within the function's timeout. Only the $LATEST version of the endpoint's function can be invoked.
+func (r *RuntimeAPI) Invoke(input *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+	name, qualifier := ParseFunctionName(aws.StringValue(input.FunctionName))
+	if input.Qualifier != nil {
+		qualifier = aws.StringValue(input.Qualifier)
+	}
+
+	if name != r.functionName || (qualifier != "" && qualifier != LatestVersion) {
+		if qualifier != "" {
+			name += ":" + qualifier
+		}
+		return nil, awserr.New(lambda.ErrCodeResourceNotFoundException, "Function not found: "+name, nil)
+	}
+
+	output := &lambda.InvokeOutput{
+		ExecutedVersion: aws.String(LatestVersion),
+	}
+
+	switch aws.StringValue(input.InvocationType) {
+	case lambda.InvocationTypeDryRun:
+		output.StatusCode = aws.Int64(http.StatusNoContent)
+		return output, nil
+	case lambda.InvocationTypeEvent:
+		go r.invoke(context.Background(), input.Payload)
+		output.StatusCode = aws.Int64(http.StatusAccepted)
+		return output, nil
+	}
+
+	result, err := r.invoke(context.Background(), input.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	output.StatusCode = aws.Int64(http.StatusOK)
+	output.Payload = result.payload
+	if result.functionErr {
+		output.FunctionError = aws.String(UnhandledFunctionError)
+	}
+
+	return output, nil
+}
+
+// invoke queues an invocation and waits for its result
+func (r *RuntimeAPI) invoke(ctx context.Context, payload []byte) (processResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	inv := newInvocation(r.functionName, payload, deadline)
+
+	select {
+	case r.invocations <- inv:
+	case <-r.initFailed:
+		return *r.initError(), nil
+	case <-ctx.Done():
+		return timeoutResult(r.timeout), nil
+	case <-r.done:
+		return r.closed()
+	}
+
+	select {
+	case result := <-inv.result:
+		return result, nil
+	case <-r.initFailed:
+		return *r.initError(), nil
+	case <-ctx.Done():
+		return timeoutResult(r.timeout), nil
+	case <-r.done:
+		return r.closed()
+	}
+}
+
+// closed returns the error of an invocation that was in progress when the endpoint closed, which is the
+// init error of a runtime that failed to initialise
+func (r *RuntimeAPI) closed() (processResult, error) {
+	if initErr := r.initError(); initErr != nil {
+		return *initErr, nil
+	}
+
+	return processResult{}, ErrRuntimeAPIClosed
+}
+
+func (r *RuntimeAPI) initError() *processResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.initErr
+}
+
+func (r *RuntimeAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, runtimeAPIPath)
+
+	switch {
+	case req.Method == http.MethodGet && path == "/invocation/next":
+		r.next(w, req)
+	case req.Method == http.MethodPost && path == "/init/error":
+		r.failInit(w, req)
+	case req.Method == http.MethodPost && strings.HasPrefix(path, "/invocation/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/invocation/"), "/")
+		if len(parts) != 2 || (parts[1] != "response" && parts[1] != "error") {
+			http.NotFound(w, req)
+			return
+		}
+		r.respond(w, req, parts[0], parts[1] == "error")
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// next serves the next invocation, blocking until there is one
+func (r *RuntimeAPI) next(w http.ResponseWriter, req *http.Request) {
+	select {
+	case inv := <-r.invocations:
+		r.mu.Lock()
+		r.inProgress[inv.requestID] = inv
+		r.mu.Unlock()
+
+		inv.writeTo(w)
+	case <-req.Context().Done():
+	case <-r.done:
+	}
+}
+
+func (r *RuntimeAPI) respond(w http.ResponseWriter, req *http.Request, requestID string, functionErr bool) {
+	r.mu.Lock()
+	inv, ok := r.inProgress[requestID]
+	delete(r.inProgress, requestID)
+	r.mu.Unlock()
+
+	if !ok {
+		writeRuntimeAPIError(w, http.StatusBadRequest, "InvalidRequestID", "Invalid request ID: "+requestID)
+		return
+	}
+
+	result, err := readRuntimeResult(req, functionErr)
+	if err != nil {
+		writeRuntimeAPIError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	inv.result <- result
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// failInit fails the invocations of a runtime that failed to initialise with its error
+func (r *RuntimeAPI) failInit(w http.ResponseWriter, req *http.Request) {
+	result, err := readRuntimeResult(req, true)
+	if err != nil {
+		writeRuntimeAPIError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	r.mu.Lock()
+	if r.initErr == nil {
+		r.initErr = &result
+		close(r.initFailed)
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// readRuntimeResult reads the result of an invocation from a runtime. An error is typed by the
+// Lambda-Runtime-Function-Error-Type header if its payload isn't.
+func readRuntimeResult(req *http.Request, functionErr bool) (processResult, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return processResult{}, err
+	}
+
+	if !functionErr {
+		return processResult{payload: body}, nil
+	}
+
+	payload := FunctionErrorPayload{}
+	if json.Unmarshal(body, &payload) == nil && payload.ErrorType != "" {
+		return processResult{payload: body, functionErr: true}, nil
+	}
+
+	errorType := req.Header.Get("Lambda-Runtime-Function-Error-Type")
+	if errorType == "" {
+		errorType = ErrTypeUnknown
+	}
+	if payload.ErrorMessage == "" {
+		payload.ErrorMessage = string(body)
+	}
+
+	return errorResult(errorType, payload.ErrorMessage), nil
+}
+
+func writeRuntimeAPIError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	payload, _ := json.Marshal(FunctionErrorPayload{
+		ErrorMessage: message,
+		ErrorType:    errorType,
+	})
+	w.Write(payload)
+}
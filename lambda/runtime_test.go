@@ -0,0 +1,178 @@
+// +build unit
+
+package lambda_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runtime polls the runtime API for an invocation in a new goroutine and posts the result of the handler
+// to the path it returns e.g. "response". The returned channel is closed once the result is posted.
+func runtime(t *testing.T, address string, handler func(payload []byte) (string, []byte)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		api := "http://" + address + "/2018-06-01/runtime/invocation/"
+
+		resp, err := http.Get(api + "next")
+		if !assert.NoError(t, err) {
+			return
+		}
+		payload, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		requestID := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		assert.NotEmpty(t, requestID)
+		assert.NotEmpty(t, resp.Header.Get("Lambda-Runtime-Deadline-Ms"))
+		assert.Equal(t, "arn:aws:lambda:us-east-1:123456789012:function:test", resp.Header.Get("Lambda-Runtime-Invoked-Function-Arn"))
+
+		path, result := handler(payload)
+		resp, err = http.Post(api+requestID+"/"+path, "application/json", bytes.NewReader(result))
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		}
+	}()
+
+	return done
+}
+
+func TestRuntimeAPI(t *testing.T) {
+	newRuntimeAPI := func(t *testing.T, timeout time.Duration) *lambda.RuntimeAPI {
+		api, err := lambda.NewRuntimeAPI("test", timeout)
+		require.NoError(t, err)
+		return api
+	}
+	invoke := func(client lambda.Client) (*awslambda.InvokeOutput, error) {
+		return client.Invoke(&awslambda.InvokeInput{
+			FunctionName: aws.String("test"),
+			Payload:      []byte(`{"a":1}`),
+		})
+	}
+
+	t.Run("response", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		done := runtime(t, api.Address(), func(payload []byte) (string, []byte) {
+			return "response", append([]byte(`[`), append(payload, ']')...)
+		})
+
+		output, err := invoke(api)
+		require.NoError(t, err)
+		require.Nil(t, output.FunctionError)
+		require.JSONEq(t, `[{"a":1}]`, string(output.Payload))
+		<-done
+	})
+
+	t.Run("error", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		done := runtime(t, api.Address(), func([]byte) (string, []byte) {
+			return "error", []byte(`{"errorType":"MyCustomError","errorMessage":"custom error"}`)
+		})
+
+		output, err := invoke(api)
+		require.NoError(t, err)
+		require.Equal(t, lambda.UnhandledFunctionError, aws.StringValue(output.FunctionError))
+		require.JSONEq(t, `{"errorType":"MyCustomError","errorMessage":"custom error"}`, string(output.Payload))
+		<-done
+	})
+
+	t.Run("error type header", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			resp, err := http.Get("http://" + api.Address() + "/2018-06-01/runtime/invocation/next")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"http://"+api.Address()+"/2018-06-01/runtime/invocation/"+resp.Header.Get("Lambda-Runtime-Aws-Request-Id")+"/error",
+				bytes.NewReader([]byte(`oops`)),
+			)
+			if !assert.NoError(t, err) {
+				return
+			}
+			req.Header.Set("Lambda-Runtime-Function-Error-Type", "Runtime.HandlerError")
+			_, err = http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+		}()
+
+		output, err := invoke(api)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"errorType":"Runtime.HandlerError","errorMessage":"oops"}`, string(output.Payload))
+		<-done
+	})
+
+	t.Run("init error", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		resp, err := http.Post(
+			"http://"+api.Address()+"/2018-06-01/runtime/init/error",
+			"application/json",
+			bytes.NewReader([]byte(`{"errorType":"Runtime.InvalidEntrypoint","errorMessage":"no handler"}`)),
+		)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		output, err := invoke(api)
+		require.NoError(t, err)
+		require.Equal(t, lambda.UnhandledFunctionError, aws.StringValue(output.FunctionError))
+		require.JSONEq(t, `{"errorType":"Runtime.InvalidEntrypoint","errorMessage":"no handler"}`, string(output.Payload))
+	})
+
+	t.Run("invalid request ID", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		resp, err := http.Post("http://"+api.Address()+"/2018-06-01/runtime/invocation/unknown/response", "application/json", bytes.NewReader([]byte(`{}`)))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		api := newRuntimeAPI(t, 50*time.Millisecond)
+		defer api.Close()
+
+		output, err := invoke(api)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"errorType":"Sandbox.Timedout","errorMessage":"Task timed out after 0.05 seconds"}`, string(output.Payload))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		defer api.Close()
+
+		for _, functionName := range []string{"other", "test:1", "arn:aws:lambda:us-east-1:123456789012:function:other"} {
+			_, err := api.Invoke(&awslambda.InvokeInput{FunctionName: aws.String(functionName)})
+			awsErr, ok := err.(awserr.Error)
+			require.True(t, ok, functionName)
+			require.Equal(t, awslambda.ErrCodeResourceNotFoundException, awsErr.Code(), functionName)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		api := newRuntimeAPI(t, 0)
+		require.NoError(t, api.Close())
+
+		_, err := invoke(api)
+		require.Equal(t, lambda.ErrRuntimeAPIClosed, err)
+	})
+}
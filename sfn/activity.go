@@ -0,0 +1,153 @@
+package sfn
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ActivityResource is the pattern of activity ARNs
+	ActivityResource = "arn:aws:states:::activity"
+
+	// ActivityPollTimeout is how long GetActivityTask waits for a task, as the API long polls
+	ActivityPollTimeout = 60 * time.Second
+)
+
+// ScheduledActivity is an activity task a worker polled for, which completes when the worker sends the
+// outcome of its token
+type ScheduledActivity struct {
+	TaskToken string
+	Input     []byte
+}
+
+// Activities queues the tasks of activities until workers poll for them with GetActivityTask. Workers
+// send the outcome of the tasks they poll with the task tokens API.
+type Activities struct {
+	*TaskTokens
+	mu     sync.Mutex
+	queues map[string]chan ScheduledActivity
+}
+
+func NewActivities(tokens *TaskTokens) *Activities {
+	return &Activities{
+		TaskTokens: tokens,
+		queues:     map[string]chan ScheduledActivity{},
+	}
+}
+
+// Handler returns the resource handler of activity tasks e.g. to register as the ActivityResource
+func (a *Activities) Handler() ResourceHandler {
+	return func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewActivityTask(def, resource, a), nil
+	}
+}
+
+// GetActivityTask waits for a task of the activity, returning an empty task if there isn't one before
+// the context is done
+func (a *Activities) GetActivityTask(ctx context.Context, activityARN string) (ScheduledActivity, error) {
+	if _, err := arn.Parse(activityARN); err != nil {
+		return ScheduledActivity{}, errors.Wrap(err, "invalid activity arn")
+	}
+
+	select {
+	case task := <-a.queue(activityARN):
+		return task, nil
+	case <-ctx.Done():
+		return ScheduledActivity{}, nil
+	}
+}
+
+func (a *Activities) queue(activityARN string) chan ScheduledActivity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	queue, ok := a.queues[activityARN]
+	if !ok {
+		queue = make(chan ScheduledActivity)
+		a.queues[activityARN] = queue
+	}
+
+	return queue
+}
+
+// ServeHTTP serves the GetActivityTask action of the Step Functions JSON API and the actions of the task
+// tokens API e.g. to the SDK clients of workers
+func (a *Activities) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if apiAction(req) != "GetActivityTask" {
+		a.TaskTokens.ServeHTTP(w, req)
+		return
+	}
+
+	var body struct {
+		ActivityARN string `json:"activityArn"`
+	}
+	if err := decodeAPIRequest(req, &body); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), ActivityPollTimeout)
+	defer cancel()
+
+	task, err := a.GetActivityTask(ctx, body.ActivityARN)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	response := map[string]string{}
+	if task.TaskToken != "" {
+		response["taskToken"] = task.TaskToken
+		response["input"] = string(task.Input)
+	}
+	writeAPIResponse(w, response)
+}
+
+// ActivityTask schedules a task of an activity and waits for a worker to complete it
+type ActivityTask struct {
+	definition state.TaskDefinition
+	arn        arn.ARN
+	activities *Activities
+}
+
+func NewActivityTask(def state.TaskDefinition, arn arn.ARN, activities *Activities) State {
+	return ActivityTask{
+		definition: def,
+		arn:        arn,
+		activities: activities,
+	}
+}
+
+// Run waits for a worker to poll for the task and send its outcome. The task times out if its outcome
+// isn't sent within TimeoutSeconds of it being scheduled, or a heartbeat within HeartbeatSeconds of it
+// being started or of the last heartbeat.
+func (a ActivityTask) Run(input []byte) ([]byte, error) {
+	var timeout <-chan time.Time
+	if a.definition.TimeoutSeconds > 0 {
+		timeout = time.After(time.Duration(a.definition.TimeoutSeconds) * time.Second)
+	}
+
+	task := a.activities.create()
+	select {
+	case a.activities.queue(a.arn.String()) <- ScheduledActivity{TaskToken: task.token, Input: input}:
+	case <-timeout:
+		a.activities.timeout(task)
+		return nil, state.NewError(state.ErrTimeoutCode, "activity task was not started before it timed out")
+	}
+
+	return a.activities.wait(task, timeout, a.definition.HeartbeatSeconds, systemClock{})
+}
+
+func (a ActivityTask) Next() string {
+	return a.definition.Next()
+}
+
+func (a ActivityTask) IsEnd() bool {
+	return a.definition.End()
+}
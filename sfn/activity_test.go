@@ -0,0 +1,167 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awssfn "github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const activityARN = "arn:aws:states:us-east-1:123456789012:activity:test"
+
+// activityMachine returns the definition of a machine whose only state is an activity task with the
+// given fields e.g. `,"TimeoutSeconds":1`
+func activityMachine(fields string) string {
+	return `{"StartAt":"activity","States":{"activity":{"Type":"Task","Resource":"` + activityARN + `","End":true` + fields + `}}}`
+}
+
+// registerActivities returns the registration of the activities' task resource
+func registerActivities(activities *sfn.Activities) func(sfn.ResourceRegistry) {
+	return func(resources sfn.ResourceRegistry) {
+		resources.Register(sfn.ActivityResource, activities.Handler())
+	}
+}
+
+func TestActivities(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+		fn := newStepFunctionWithResources(t, activityMachine(`,"HeartbeatSeconds":10`), registerActivities(activities))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			task, err := activities.GetActivityTask(context.Background(), activityARN)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.JSONEq(t, `{"a":1}`, string(task.Input))
+
+			assert.NoError(t, activities.SendTaskHeartbeat(task.TaskToken))
+			assert.NoError(t, activities.SendTaskSuccess(task.TaskToken, []byte(`{"b":2}`)))
+			assert.Equal(t, sfn.ErrTaskDoesNotExist, errors.Cause(activities.SendTaskSuccess(task.TaskToken, []byte(`{}`))))
+		}()
+
+		result, err := fn.StartExecution([]byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"b":2}`, string(result.Output))
+		<-done
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+		fn := newStepFunctionWithResources(t, activityMachine(``), registerActivities(activities))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			task, err := activities.GetActivityTask(context.Background(), activityARN)
+			if assert.NoError(t, err) {
+				assert.NoError(t, activities.SendTaskFailure(task.TaskToken, "MyCustomError", "custom error"))
+			}
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "MyCustomError", result.Error)
+		require.Equal(t, "custom error", result.Cause)
+		<-done
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+		fn := newStepFunctionWithResources(t, activityMachine(`,"TimeoutSeconds":1`), registerActivities(activities))
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrTimeoutCode, result.Error)
+	})
+
+	t.Run("heartbeat timeout", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+		fn := newStepFunctionWithResources(t, activityMachine(`,"HeartbeatSeconds":1`), registerActivities(activities))
+
+		type polled struct {
+			task sfn.ScheduledActivity
+			err  error
+		}
+		tasks := make(chan polled, 1)
+		go func() {
+			task, err := activities.GetActivityTask(context.Background(), activityARN)
+			tasks <- polled{task, err}
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrHeartbeatTimeoutCode, result.Error)
+
+		poll := <-tasks
+		require.NoError(t, poll.err)
+		require.Equal(t, sfn.ErrTaskTimedOut, errors.Cause(activities.SendTaskSuccess(poll.task.TaskToken, []byte(`{}`))))
+	})
+
+	t.Run("no task", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		task, err := activities.GetActivityTask(ctx, activityARN)
+		require.NoError(t, err)
+		require.Empty(t, task.TaskToken)
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		activities := sfn.NewActivities(sfn.NewTaskTokens())
+		fn := newStepFunctionWithResources(t, activityMachine(``), registerActivities(activities))
+
+		server := httptest.NewServer(activities)
+		defer server.Close()
+
+		client := awssfn.New(session.Must(session.NewSession(&aws.Config{
+			Endpoint:    aws.String(server.URL),
+			Region:      aws.String("us-east-1"),
+			Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		})))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			task, err := client.GetActivityTask(&awssfn.GetActivityTaskInput{
+				ActivityArn: aws.String(activityARN),
+				WorkerName:  aws.String("worker"),
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.JSONEq(t, `{"a":1}`, aws.StringValue(task.Input))
+
+			_, err = client.SendTaskHeartbeat(&awssfn.SendTaskHeartbeatInput{TaskToken: task.TaskToken})
+			assert.NoError(t, err)
+			_, err = client.SendTaskSuccess(&awssfn.SendTaskSuccessInput{TaskToken: task.TaskToken, Output: aws.String(`{"b":2}`)})
+			assert.NoError(t, err)
+
+			_, err = client.SendTaskSuccess(&awssfn.SendTaskSuccessInput{TaskToken: task.TaskToken, Output: aws.String(`{}`)})
+			if awsErr, ok := err.(awserr.Error); assert.True(t, ok) {
+				assert.Equal(t, awssfn.ErrCodeTaskDoesNotExist, awsErr.Code())
+			}
+		}()
+
+		result, err := fn.StartExecution([]byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"b":2}`, string(result.Output))
+		<-done
+	})
+}
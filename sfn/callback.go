@@ -0,0 +1,65 @@
+package sfn
+
+import (
+	"time"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+)
+
+// WaitForTaskTokenTask runs the integration of a task with the .waitForTaskToken pattern and pauses the
+// execution until the outcome of its task token is sent with SendTaskSuccess or SendTaskFailure
+type WaitForTaskTokenTask struct {
+	definition state.TaskDefinition
+	task       State
+	tokens     *TaskTokens
+	token      *tokenTask
+	clock      Clock
+}
+
+// NewWaitForTaskTokenTask returns a task wrapping its integration. Its token is created with the task so
+// it can be passed to the integration in its parameters, but is only registered with the tokens once the
+// task runs, so a state that fails before then leaves no token behind. The task is timed out with the
+// clock of its execution.
+func NewWaitForTaskTokenTask(def state.TaskDefinition, task State, tokens *TaskTokens, clock Clock) *WaitForTaskTokenTask {
+	return &WaitForTaskTokenTask{
+		definition: def,
+		task:       task,
+		tokens:     tokens,
+		token:      newTokenTask(),
+		clock:      clock,
+	}
+}
+
+func (w *WaitForTaskTokenTask) TaskToken() string {
+	return w.token.token
+}
+
+func (w *WaitForTaskTokenTask) Run(input []byte) ([]byte, error) {
+	return w.RunWithVariables(input, state.Variables{})
+}
+
+// RunWithVariables runs the integration with the variables of its scope, ignoring its result, then waits
+// for the outcome of the token. The task times out if its outcome isn't sent within TimeoutSeconds of it
+// starting, or a heartbeat within HeartbeatSeconds of it starting or of the last heartbeat.
+func (w *WaitForTaskTokenTask) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
+	var timeout <-chan time.Time
+	if w.definition.TimeoutSeconds > 0 {
+		timeout = w.clock.After(time.Duration(w.definition.TimeoutSeconds) * time.Second)
+	}
+
+	w.tokens.register(w.token)
+	if _, err := runState(w.task, input, variables); err != nil {
+		w.tokens.remove(w.token)
+		return nil, err
+	}
+
+	return w.tokens.wait(w.token, timeout, w.definition.HeartbeatSeconds, w.clock)
+}
+
+func (w *WaitForTaskTokenTask) Next() string {
+	return w.definition.Next()
+}
+
+func (w *WaitForTaskTokenTask) IsEnd() bool {
+	return w.definition.End()
+}
@@ -0,0 +1,268 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const callbackResource = "arn:aws:states:::sqs:sendMessage.waitForTaskToken"
+
+// registerCallback registers the callback task resource, which sends the token of each task it runs to
+// the channel
+func registerCallback(tokens chan<- string) func(sfn.ResourceRegistry) {
+	return func(resources sfn.ResourceRegistry) {
+		resources.Register(callbackResource, func(def state.TaskDefinition, _ arn.ARN) (sfn.State, error) {
+			return sfn.NewOverrideTask(def, func(input []byte) ([]byte, error) {
+				var params struct {
+					Token string `json:"token"`
+				}
+				if err := json.Unmarshal(input, &params); err != nil {
+					return nil, err
+				}
+
+				tokens <- params.Token
+				return []byte(`{"MessageId":"m-1"}`), nil
+			}), nil
+		})
+	}
+}
+
+func TestWaitForTaskToken(t *testing.T) {
+	task := func(fields string) string {
+		return `{"Type":"Task","Resource":"` + callbackResource + `","Parameters":{"token.$":"$$.Task.Token"},"End":true` + fields + `}`
+	}
+	machine := func(task string) string {
+		return `{"StartAt":"callback","States":{"callback":` + task + `}}`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(task(`,"HeartbeatSeconds":10`)), registerCallback(tokens))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			token := <-tokens
+			assert.NotEmpty(t, token)
+			assert.NoError(t, fn.TaskTokens().SendTaskHeartbeat(token))
+			assert.NoError(t, fn.TaskTokens().SendTaskSuccess(token, []byte(`{"b":2}`)))
+			assert.Equal(t, sfn.ErrTaskDoesNotExist, errors.Cause(fn.TaskTokens().SendTaskSuccess(token, []byte(`{}`))))
+		}()
+
+		result, err := fn.StartExecution([]byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"b":2}`, string(result.Output))
+		<-done
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(task(``)), registerCallback(tokens))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			assert.NoError(t, fn.TaskTokens().SendTaskFailure(<-tokens, "MyCustomError", "custom error"))
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "MyCustomError", result.Error)
+		require.Equal(t, "custom error", result.Cause)
+		<-done
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(task(`,"TimeoutSeconds":1`)), registerCallback(tokens))
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrTimeoutCode, result.Error)
+		require.Equal(t, sfn.ErrTaskTimedOut, errors.Cause(fn.TaskTokens().SendTaskSuccess(<-tokens, []byte(`{}`))))
+	})
+
+	t.Run("timeout with the execution's clock", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(task(`,"TimeoutSeconds":3600`)), registerCallback(tokens))
+		fn.SetClock(&fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrTimeoutCode, result.Error)
+		require.Equal(t, time.Hour, result.End.Sub(result.Start))
+		require.Equal(t, sfn.ErrTaskTimedOut, errors.Cause(fn.TaskTokens().SendTaskSuccess(<-tokens, []byte(`{}`))))
+	})
+
+	t.Run("failure before running", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(`{"Type":"Task","Resource":"`+callbackResource+`","Parameters":{"token.$":"$$.Task.Token","id.$":"$.missing"},"End":true}`), registerCallback(tokens))
+		registry := sfn.NewTaskTokens()
+		fn.SetTaskTokens(registry)
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrParameterPathFailureCode, result.Error)
+		require.Equal(t, 0, sfn.PendingTasks(registry))
+		require.Len(t, tokens, 0)
+	})
+
+	t.Run("shared registry", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, machine(task(``)), registerCallback(tokens))
+		registry := sfn.NewTaskTokens()
+		fn.SetTaskTokens(registry)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			assert.NoError(t, registry.SendTaskSuccess(<-tokens, []byte(`"done"`)))
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `"done"`, string(result.Output))
+		<-done
+	})
+
+	t.Run("parallel branch", func(t *testing.T) {
+		tokens := make(chan string, 1)
+		fn := newStepFunctionWithResources(t, `{"StartAt":"parallel","States":{
+			"parallel":{"Type":"Parallel","End":true,"Branches":[{"StartAt":"callback","States":{"callback":`+task(``)+`}}]}
+		}}`, registerCallback(tokens))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			assert.NoError(t, fn.TaskTokens().SendTaskSuccess(<-tokens, []byte(`{"b":2}`)))
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"b":2}]`, string(result.Output))
+		<-done
+	})
+}
+
+func TestWaitForTaskTokenTask(t *testing.T) {
+	t.Run("variables", func(t *testing.T) {
+		tokens := sfn.NewTaskTokens()
+		integration := &variableTask{tokens: tokens}
+		integration.callback = sfn.NewWaitForTaskTokenTask(state.TaskDefinition{}, integration, tokens, &fakeClock{})
+
+		variables := state.Variables{"a": []byte(`1`)}
+		output, err := integration.callback.RunWithVariables([]byte(`{}`), variables)
+		require.NoError(t, err)
+		require.JSONEq(t, `"done"`, string(output))
+		require.Equal(t, variables, integration.variables)
+	})
+}
+
+// variableTask is the integration of a callback task, which records the variables it's run with and
+// sends the outcome of the callback's token
+type variableTask struct {
+	callback  *sfn.WaitForTaskTokenTask
+	tokens    *sfn.TaskTokens
+	variables state.Variables
+}
+
+func (v *variableTask) Run(input []byte) ([]byte, error) {
+	return v.RunWithVariables(input, state.Variables{})
+}
+
+func (v *variableTask) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
+	v.variables = variables
+	return nil, v.tokens.SendTaskSuccess(v.callback.TaskToken(), []byte(`"done"`))
+}
+
+func (v *variableTask) Next() string {
+	return ""
+}
+
+func (v *variableTask) IsEnd() bool {
+	return true
+}
+
+func TestTaskTokens(t *testing.T) {
+	t.Run("timed out tokens are bounded", func(t *testing.T) {
+		tokens := sfn.NewTaskTokens()
+
+		first := sfn.TimeOutTask(tokens)
+		require.Equal(t, sfn.ErrTaskTimedOut, errors.Cause(tokens.SendTaskHeartbeat(first)))
+
+		var last string
+		for i := 0; i < sfn.MaxTimedOutTokens; i++ {
+			last = sfn.TimeOutTask(tokens)
+		}
+
+		require.Equal(t, sfn.ErrTaskDoesNotExist, errors.Cause(tokens.SendTaskHeartbeat(first)))
+		require.Equal(t, sfn.ErrTaskTimedOut, errors.Cause(tokens.SendTaskHeartbeat(last)))
+	})
+
+	t.Run("outcome sent as the task times out", func(t *testing.T) {
+		// the outcome and the timeout are both ready, so either can be selected first
+		for i := 0; i < 100; i++ {
+			tokens := sfn.NewTaskTokens()
+			token, wait := sfn.TimedOutWait(tokens)
+			require.NoError(t, tokens.SendTaskSuccess(token, []byte(`"done"`)))
+
+			output, err := wait()
+			require.NoError(t, err)
+			require.JSONEq(t, `"done"`, string(output))
+		}
+	})
+}
+
+func TestContextObject(t *testing.T) {
+	t.Run("JSONPath", func(t *testing.T) {
+		fn, err := sfn.New(state.MachineDefinition{
+			StartAt: "pass",
+			States: state.MachineStates{
+				"pass": []byte(`{"Type":"Pass","Parameters":{"state.$":"$$.State.Name","input.$":"$$.Execution.Input","id.$":"$$.Execution.Id"},"End":true}`),
+			},
+		}, nil)
+		require.NoError(t, err)
+
+		result, err := fn.StartExecution([]byte(`{"a":1}`))
+		require.NoError(t, err)
+
+		var output struct {
+			State string          `json:"state"`
+			Input json.RawMessage `json:"input"`
+			ID    string          `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.Equal(t, "pass", output.State)
+		require.JSONEq(t, `{"a":1}`, string(output.Input))
+		require.Contains(t, output.ID, ":execution:")
+		require.Equal(t, result.ExecutionARN, output.ID)
+		require.NotEmpty(t, result.StateMachineARN)
+		require.True(t, strings.HasPrefix(output.ID, strings.Replace(result.StateMachineARN, ":stateMachine:", ":execution:", 1)+":"))
+	})
+
+	t.Run("JSONata", func(t *testing.T) {
+		fn, err := sfn.New(state.MachineDefinition{
+			QueryLanguage: state.JSONataQueryLanguage,
+			StartAt:       "pass",
+			States: state.MachineStates{
+				"pass": []byte(`{"Type":"Pass","Output":"{% $states.context.State.Name %}","End":true}`),
+			},
+		}, nil)
+		require.NoError(t, err)
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `"pass"`, string(result.Output))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
 	"github.com/eggsbenjamin/stepFnLocal/state"
 	"github.com/pkg/errors"
 )
@@ -18,6 +19,7 @@ type ChoiceState struct {
 	def     state.ChoiceDefinition
 	choices []ChoiceRule
 	next    string
+	assign  state.AssignTemplate
 }
 
 func NewChoiceState(def state.ChoiceDefinition, choices ...ChoiceRule) State {
@@ -28,13 +30,27 @@ func NewChoiceState(def state.ChoiceDefinition, choices ...ChoiceRule) State {
 }
 
 func (c *ChoiceState) Run(input []byte) ([]byte, error) {
-	for _, choice := range c.choices {
-		result, err := choice.Run(input)
+	return c.RunWithVariables(input, nil)
+}
+
+// RunWithVariables evaluates the choice rules, which may reference variables, against the input
+func (c *ChoiceState) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
+	// parse the input once for all of the rules to be evaluated against
+	doc, err := variables.Document(input)
+	if err != nil {
+		return []byte{}, errors.Wrap(err, "error running choice state")
+	}
+
+	for i, choice := range c.choices {
+		result, err := choice.Evaluate(doc)
 		if err != nil {
 			return []byte{}, errors.Wrap(err, "error running choice state")
 		}
 		if result {
 			c.next = choice.Next()
+			if i < len(c.def.Choices) {
+				c.assign = c.def.Choices[i].Assign()
+			}
 			return input, nil
 		}
 	}
@@ -46,9 +62,16 @@ func (c *ChoiceState) Run(input []byte) ([]byte, error) {
 	}
 
 	c.next = c.def.DefaultState
+	c.assign = c.def.Assign()
 	return input, nil
 }
 
+// Assign returns the Assign of the matched choice rule or, if the Default state was transitioned to, of
+// the state
+func (c *ChoiceState) Assign() state.AssignTemplate {
+	return c.assign
+}
+
 func (c *ChoiceState) Next() string {
 	return c.next
 }
@@ -59,9 +82,20 @@ func (c ChoiceState) IsEnd() bool {
 
 type ChoiceRule interface {
 	Run([]byte) (bool, error)
+	Evaluate(*jsonpath.Document) (bool, error)
 	Next() string
 }
 
+// runChoiceRule evaluates a choice rule against raw JSON input
+func runChoiceRule(rule ChoiceRule, input []byte) (bool, error) {
+	doc, err := jsonpath.NewDocument(input)
+	if err != nil {
+		return false, errors.Wrap(err, "error parsing json input")
+	}
+
+	return rule.Evaluate(doc)
+}
+
 type ChoiceRuleFactory interface {
 	Create(state.ChoiceRuleDefinition) (ChoiceRule, error)
 }
@@ -132,6 +166,8 @@ func (c choiceRuleFactory) Create(def state.ChoiceRuleDefinition) (ChoiceRule, e
 			return nil, err
 		}
 		return NewNotChoiceRule(def, choiceRule), nil
+	case state.Condition:
+		return NewConditionChoiceRule(def), nil
 	}
 
 	return nil, nil
@@ -148,11 +184,15 @@ func NewStringEqualsChoiceRule(def state.ChoiceRuleDefinition) StringEqualsChoic
 }
 
 func (s StringEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s StringEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.StringEquals == nil {
 		return false, errors.Errorf("StringEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -180,11 +220,15 @@ func NewStringLessThanChoiceRule(def state.ChoiceRuleDefinition) StringLessThanC
 }
 
 func (s StringLessThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s StringLessThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.StringLessThan == nil {
 		return false, errors.Errorf("StringLessThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -212,11 +256,15 @@ func NewStringGreaterThanChoiceRule(def state.ChoiceRuleDefinition) StringGreate
 }
 
 func (s StringGreaterThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s StringGreaterThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.StringGreaterThan == nil {
 		return false, errors.Errorf("StringGreaterThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -244,11 +292,15 @@ func NewStringLessThanEqualsChoiceRule(def state.ChoiceRuleDefinition) StringLes
 }
 
 func (s StringLessThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s StringLessThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.StringLessThanEquals == nil {
 		return false, errors.Errorf("StringLessThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -276,11 +328,15 @@ func NewStringGreaterThanEqualsChoiceRule(def state.ChoiceRuleDefinition) String
 }
 
 func (s StringGreaterThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s StringGreaterThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.StringGreaterThanEquals == nil {
 		return false, errors.Errorf("StringGreaterThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -308,11 +364,15 @@ func NewNumericEqualsChoiceRule(def state.ChoiceRuleDefinition) NumericEqualsCho
 }
 
 func (s NumericEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s NumericEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.NumericEquals == nil {
 		return false, errors.Errorf("NumericEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -340,11 +400,15 @@ func NewNumericLessThanChoiceRule(def state.ChoiceRuleDefinition) NumericLessTha
 }
 
 func (s NumericLessThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s NumericLessThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.NumericLessThan == nil {
 		return false, errors.Errorf("NumericLessThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -372,11 +436,15 @@ func NewNumericGreaterThanChoiceRule(def state.ChoiceRuleDefinition) NumericGrea
 }
 
 func (s NumericGreaterThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s NumericGreaterThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.NumericGreaterThan == nil {
 		return false, errors.Errorf("NumericGreaterThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -404,11 +472,15 @@ func NewNumericLessThanEqualsChoiceRule(def state.ChoiceRuleDefinition) NumericL
 }
 
 func (s NumericLessThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s NumericLessThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.NumericLessThanEquals == nil {
 		return false, errors.Errorf("NumericLessThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -436,11 +508,15 @@ func NewNumericGreaterThanEqualsChoiceRule(def state.ChoiceRuleDefinition) Numer
 }
 
 func (s NumericGreaterThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s NumericGreaterThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.NumericGreaterThanEquals == nil {
 		return false, errors.Errorf("NumericGreaterThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -468,11 +544,15 @@ func NewBooleanEqualsChoiceRule(def state.ChoiceRuleDefinition) BooleanEqualsCho
 }
 
 func (s BooleanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s BooleanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.BooleanEquals == nil {
 		return false, errors.Errorf("BooleanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -500,11 +580,15 @@ func NewTimestampEqualsChoiceRule(def state.ChoiceRuleDefinition) TimestampEqual
 }
 
 func (s TimestampEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s TimestampEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.TimestampEquals == nil {
 		return false, errors.Errorf("TimestampEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -532,11 +616,15 @@ func NewTimestampLessThanChoiceRule(def state.ChoiceRuleDefinition) TimestampLes
 }
 
 func (s TimestampLessThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s TimestampLessThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.TimestampLessThan == nil {
 		return false, errors.Errorf("TimestampLessThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -564,11 +652,15 @@ func NewTimestampGreaterThanChoiceRule(def state.ChoiceRuleDefinition) Timestamp
 }
 
 func (s TimestampGreaterThanChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s TimestampGreaterThanChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.TimestampGreaterThan == nil {
 		return false, errors.Errorf("TimestampGreaterThan is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -596,11 +688,15 @@ func NewTimestampLessThanEqualsChoiceRule(def state.ChoiceRuleDefinition) Timest
 }
 
 func (s TimestampLessThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s TimestampLessThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.TimestampLessThanEquals == nil {
 		return false, errors.Errorf("TimestampLessThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -628,11 +724,15 @@ func NewTimestampGreaterThanEqualsChoiceRule(def state.ChoiceRuleDefinition) Tim
 }
 
 func (s TimestampGreaterThanEqualsChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s TimestampGreaterThanEqualsChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	if s.def.TimestampGreaterThanEquals == nil {
 		return false, errors.Errorf("TimestampGreaterThanEquals is nil")
 	}
 
-	jsonOperand, err := s.def.VariableExp.Search(input)
+	jsonOperand, err := s.def.VariableJSONPath().SearchDocument(doc)
 	if err != nil {
 		return false, errors.Wrap(err, "error searching json input")
 	}
@@ -662,8 +762,12 @@ func NewAndChoiceRule(def state.ChoiceRuleDefinition, choiceRules ...ChoiceRule)
 }
 
 func (s AndChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s AndChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	for _, choiceRule := range s.choiceRules {
-		result, err := choiceRule.Run(input)
+		result, err := choiceRule.Evaluate(doc)
 		if err != nil {
 			return false, err
 		}
@@ -692,8 +796,12 @@ func NewOrChoiceRule(def state.ChoiceRuleDefinition, choiceRules ...ChoiceRule)
 }
 
 func (s OrChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s OrChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
 	for _, choiceRule := range s.choiceRules {
-		result, err := choiceRule.Run(input)
+		result, err := choiceRule.Evaluate(doc)
 		if err != nil {
 			return false, err
 		}
@@ -722,7 +830,11 @@ func NewNotChoiceRule(def state.ChoiceRuleDefinition, choiceRule ChoiceRule) Not
 }
 
 func (s NotChoiceRule) Run(input []byte) (bool, error) {
-	result, err := s.choiceRule.Run(input)
+	return runChoiceRule(s, input)
+}
+
+func (s NotChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
+	result, err := s.choiceRule.Evaluate(doc)
 	if err != nil {
 		return false, err
 	}
@@ -733,3 +845,32 @@ func (s NotChoiceRule) Run(input []byte) (bool, error) {
 func (s NotChoiceRule) Next() string {
 	return s.def.NextState
 }
+
+// ConditionChoiceRule is the choice rule of JSONata states, a JSONata expression evaluated against
+// $states.input
+type ConditionChoiceRule struct {
+	def state.ChoiceRuleDefinition
+}
+
+func NewConditionChoiceRule(def state.ChoiceRuleDefinition) ConditionChoiceRule {
+	return ConditionChoiceRule{
+		def: def,
+	}
+}
+
+func (s ConditionChoiceRule) Run(input []byte) (bool, error) {
+	return runChoiceRule(s, input)
+}
+
+func (s ConditionChoiceRule) Evaluate(doc *jsonpath.Document) (bool, error) {
+	bindings, err := state.NewJSONataBindings(doc.JSON(), nil, state.Variables(doc.Variables()))
+	if err != nil {
+		return false, errors.Wrap(err, "error creating bindings")
+	}
+
+	return state.EvaluateJSONataCondition(s.def.Condition, bindings)
+}
+
+func (s ConditionChoiceRule) Next() string {
+	return s.def.NextState
+}
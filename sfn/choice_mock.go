@@ -5,6 +5,7 @@
 package sfn
 
 import (
+	jsonpath "github.com/eggsbenjamin/stepFnLocal/jsonpath"
 	state "github.com/eggsbenjamin/stepFnLocal/state"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
@@ -46,6 +47,19 @@ func (mr *MockChoiceRuleMockRecorder) Run(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockChoiceRule)(nil).Run), arg0)
 }
 
+// Evaluate mocks base method
+func (m *MockChoiceRule) Evaluate(arg0 *jsonpath.Document) (bool, error) {
+	ret := m.ctrl.Call(m, "Evaluate", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Evaluate indicates an expected call of Evaluate
+func (mr *MockChoiceRuleMockRecorder) Evaluate(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Evaluate", reflect.TypeOf((*MockChoiceRule)(nil).Evaluate), arg0)
+}
+
 // Next mocks base method
 func (m *MockChoiceRule) Next() string {
 	ret := m.ctrl.Call(m, "Next")
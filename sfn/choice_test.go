@@ -590,7 +590,7 @@ func TestChoiceRules(t *testing.T) {
 				func(ctrl *gomock.Controller) []sfn.ChoiceRule {
 					choiceRules := []sfn.ChoiceRule{}
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(false, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 					return append(choiceRules, mockChoiceRule)
 				},
@@ -601,7 +601,7 @@ func TestChoiceRules(t *testing.T) {
 				func(ctrl *gomock.Controller) []sfn.ChoiceRule {
 					choiceRules := []sfn.ChoiceRule{}
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(true, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(true, nil)
 
 					return append(choiceRules, mockChoiceRule)
 				},
@@ -614,7 +614,7 @@ func TestChoiceRules(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				rule := sfn.NewAndChoiceRule(state.ChoiceRuleDefinition{}, tt.setupChoiceRules(ctrl)...)
 
-				result, err := rule.Run([]byte{})
+				result, err := rule.Run([]byte(`{}`))
 				require.NoError(t, err)
 				require.Equal(t, tt.expectedResult, result)
 				ctrl.Finish()
@@ -633,7 +633,7 @@ func TestChoiceRules(t *testing.T) {
 				func(ctrl *gomock.Controller) []sfn.ChoiceRule {
 					choiceRules := []sfn.ChoiceRule{}
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(false, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 					return append(choiceRules, mockChoiceRule)
 				},
@@ -644,7 +644,7 @@ func TestChoiceRules(t *testing.T) {
 				func(ctrl *gomock.Controller) []sfn.ChoiceRule {
 					choiceRules := []sfn.ChoiceRule{}
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(true, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(true, nil)
 
 					return append(choiceRules, mockChoiceRule)
 				},
@@ -657,7 +657,7 @@ func TestChoiceRules(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				rule := sfn.NewOrChoiceRule(state.ChoiceRuleDefinition{}, tt.setupChoiceRules(ctrl)...)
 
-				result, err := rule.Run([]byte{})
+				result, err := rule.Run([]byte(`{}`))
 				require.NoError(t, err)
 				require.Equal(t, tt.expectedResult, result)
 				ctrl.Finish()
@@ -675,7 +675,7 @@ func TestChoiceRules(t *testing.T) {
 				"false",
 				func(ctrl *gomock.Controller) sfn.ChoiceRule {
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(false, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 					return mockChoiceRule
 				},
@@ -685,7 +685,7 @@ func TestChoiceRules(t *testing.T) {
 				"true",
 				func(ctrl *gomock.Controller) sfn.ChoiceRule {
 					mockChoiceRule := sfn.NewMockChoiceRule(ctrl)
-					mockChoiceRule.EXPECT().Run([]byte{}).Return(true, nil)
+					mockChoiceRule.EXPECT().Evaluate(gomock.Any()).Return(true, nil)
 
 					return mockChoiceRule
 				},
@@ -698,7 +698,7 @@ func TestChoiceRules(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				rule := sfn.NewNotChoiceRule(state.ChoiceRuleDefinition{}, tt.setupChoiceRule(ctrl))
 
-				result, err := rule.Run([]byte{})
+				result, err := rule.Run([]byte(`{}`))
 				require.NoError(t, err)
 				require.Equal(t, tt.expectedResult, result)
 				ctrl.Finish()
@@ -737,7 +737,7 @@ func TestChoiceState(t *testing.T) {
 			func(ctrl *gomock.Controller) sfn.State {
 				choiceRules := []sfn.ChoiceRule{}
 				mockChoiceRule1 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule1.EXPECT().Run(input).Return(true, nil)
+				mockChoiceRule1.EXPECT().Evaluate(gomock.Any()).Return(true, nil)
 				mockChoiceRule1.EXPECT().Next().Return("test")
 
 				mockChoiceRule2 := sfn.NewMockChoiceRule(ctrl)
@@ -753,10 +753,10 @@ func TestChoiceState(t *testing.T) {
 			func(ctrl *gomock.Controller) sfn.State {
 				choiceRules := []sfn.ChoiceRule{}
 				mockChoiceRule1 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule1.EXPECT().Run(input).Return(false, nil)
+				mockChoiceRule1.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 				mockChoiceRule2 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule2.EXPECT().Run(input).Return(true, nil)
+				mockChoiceRule2.EXPECT().Evaluate(gomock.Any()).Return(true, nil)
 				mockChoiceRule2.EXPECT().Next().Return("test")
 
 				choiceRules = append(choiceRules, mockChoiceRule1, mockChoiceRule2)
@@ -770,10 +770,10 @@ func TestChoiceState(t *testing.T) {
 			func(ctrl *gomock.Controller) sfn.State {
 				choiceRules := []sfn.ChoiceRule{}
 				mockChoiceRule1 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule1.EXPECT().Run(input).Return(false, nil)
+				mockChoiceRule1.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 				mockChoiceRule2 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule2.EXPECT().Run(input).Return(false, nil)
+				mockChoiceRule2.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 				def := state.ChoiceDefinition{
 					DefaultState: "test",
@@ -789,10 +789,10 @@ func TestChoiceState(t *testing.T) {
 			func(ctrl *gomock.Controller) sfn.State {
 				choiceRules := []sfn.ChoiceRule{}
 				mockChoiceRule1 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule1.EXPECT().Run(input).Return(false, nil)
+				mockChoiceRule1.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 				mockChoiceRule2 := sfn.NewMockChoiceRule(ctrl)
-				mockChoiceRule2.EXPECT().Run(input).Return(false, nil)
+				mockChoiceRule2.EXPECT().Evaluate(gomock.Any()).Return(false, nil)
 
 				def := state.ChoiceDefinition{}
 				choiceRules = append(choiceRules, mockChoiceRule1, mockChoiceRule2)
@@ -825,3 +825,57 @@ func TestChoiceState(t *testing.T) {
 		})
 	}
 }
+
+func TestChoiceStateRules(t *testing.T) {
+	def := state.ChoiceDefinition{
+		DefaultState: "default",
+	}
+	ruleFactory := sfn.NewChoiceRuleFactory()
+	ruleDefs := []state.ChoiceRuleDefinition{
+		{
+			VariableExp:  state.JSONPathExp("$.name"),
+			StringEquals: aws.String("first"),
+			NextState:    "first",
+		},
+		{
+			And: []state.ChoiceRuleDefinition{
+				{VariableExp: state.JSONPathExp("$.name"), StringEquals: aws.String("second")},
+				{VariableExp: state.JSONPathExp("$.count"), NumericGreaterThan: aws.Float64(1)},
+			},
+			NextState: "second",
+		},
+	}
+
+	choiceRules := []sfn.ChoiceRule{}
+	for _, ruleDef := range ruleDefs {
+		rule, err := ruleFactory.Create(ruleDef)
+		require.NoError(t, err)
+		choiceRules = append(choiceRules, rule)
+	}
+
+	tests := []struct {
+		title        string
+		input        string
+		expectedNext string
+	}{
+		{"first rule", `{"name":"first"}`, "first"},
+		{"logical rule", `{"name":"second","count":2}`, "second"},
+		{"default", `{"name":"second","count":1}`, "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			choiceState := sfn.NewChoiceState(def, choiceRules...)
+
+			result, err := choiceState.Run([]byte(tt.input))
+			require.NoError(t, err)
+			require.Equal(t, tt.input, string(result))
+			require.Equal(t, tt.expectedNext, choiceState.Next())
+		})
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := sfn.NewChoiceState(def, choiceRules...).Run([]byte(`{`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,72 @@
+package sfn
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+// defaultStateMachineARN is the ARN of the machine of a top level execution started without one
+const defaultStateMachineARN = "arn:aws:states:us-east-1:123456789012:stateMachine:stateMachine"
+
+// contextObject is the context object of a running state, which paths reference as $$ e.g.
+// $$.Task.Token
+type contextObject struct {
+	Execution contextExecution `json:"Execution"`
+	State     contextState     `json:"State"`
+	Task      *contextTask     `json:"Task,omitempty"`
+}
+
+type contextExecution struct {
+	ID        string          `json:"Id"`
+	Input     json.RawMessage `json:"Input"`
+	Name      string          `json:"Name"`
+	StartTime string          `json:"StartTime"`
+}
+
+type contextState struct {
+	EnteredTime string `json:"EnteredTime"`
+	Name        string `json:"Name"`
+}
+
+type contextTask struct {
+	Token string `json:"Token"`
+}
+
+// newContextExecution returns the execution of a context object, named by the options or with a new
+// name. Input that isn't JSON is omitted.
+func newContextExecution(input []byte, start time.Time, opts ExecutionOptions) contextExecution {
+	if !json.Valid(input) {
+		input = nil
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = uuid.New()
+	}
+
+	return contextExecution{
+		ID:        executionARN(opts.StateMachineARN, name),
+		Input:     input,
+		Name:      name,
+		StartTime: formatContextTime(start),
+	}
+}
+
+// executionARN returns the ARN of an execution of the state machine e.g.
+// arn:aws:states:us-east-1:123456789012:execution:machine:name
+func executionARN(stateMachineARN, name string) string {
+	return strings.Replace(stateMachineARN, ":stateMachine:", ":execution:", 1) + ":" + name
+}
+
+func formatContextTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// TaskTokenState is implemented by states which wait for the outcome of a task token, which is available
+// to their parameters as $$.Task.Token
+type TaskTokenState interface {
+	TaskToken() string
+}
@@ -0,0 +1,143 @@
+package sfn
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/eggsbenjamin/stepFnLocal/dynamodb"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+const (
+	// DynamoDB integration resources, which call the item operation of the same name with the parameters
+	// of the task
+	DynamoDBGetItemResource    = "arn:aws:states:::dynamodb:getItem"
+	DynamoDBPutItemResource    = "arn:aws:states:::dynamodb:putItem"
+	DynamoDBUpdateItemResource = "arn:aws:states:::dynamodb:updateItem"
+	DynamoDBDeleteItemResource = "arn:aws:states:::dynamodb:deleteItem"
+)
+
+// dynamoDBResult is the result of a DynamoDB integration, the response of its operation
+type dynamoDBResult struct {
+	Item                dynamodb.Item       `json:"Item,omitempty"`
+	Attributes          dynamodb.Item       `json:"Attributes,omitempty"`
+	SdkHttpMetadata     sdkHttpMetadata     `json:"SdkHttpMetadata"`
+	SdkResponseMetadata sdkResponseMetadata `json:"SdkResponseMetadata"`
+}
+
+// dynamoDBInput is implemented by the inputs of the operations
+type dynamoDBInput interface {
+	Validate() error
+}
+
+// RegisterDynamoDB registers the handlers of the DynamoDB integration resources, which call the item
+// operations of the client e.g. a dynamodb.Store
+func RegisterDynamoDB(resources ResourceRegistry, client dynamodb.Client) {
+	for _, resource := range []string{DynamoDBGetItemResource, DynamoDBPutItemResource, DynamoDBUpdateItemResource, DynamoDBDeleteItemResource} {
+		resources.Register(resource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+			return NewDynamoDBTask(def, ResourcePattern(resource), client), nil
+		})
+	}
+}
+
+// DynamoDBTask calls an item operation of DynamoDB with the optimised DynamoDB integration
+type DynamoDBTask struct {
+	definition state.TaskDefinition
+	resource   string
+	client     dynamodb.Client
+}
+
+func NewDynamoDBTask(def state.TaskDefinition, resource string, client dynamodb.Client) State {
+	return DynamoDBTask{
+		definition: def,
+		resource:   resource,
+		client:     client,
+	}
+}
+
+// Run calls the operation with the task's input, its parameters in DynamoDB JSON
+func (d DynamoDBTask) Run(input []byte) ([]byte, error) {
+	var (
+		result dynamoDBResult
+		err    error
+	)
+
+	switch d.resource {
+	case DynamoDBGetItemResource:
+		params := &awsdynamodb.GetItemInput{}
+		if err := decodeDynamoDBInput(input, params); err != nil {
+			return nil, err
+		}
+		var output *awsdynamodb.GetItemOutput
+		if output, err = d.client.GetItem(params); err == nil {
+			result.Item = output.Item
+		}
+	case DynamoDBPutItemResource:
+		params := &awsdynamodb.PutItemInput{}
+		if err := decodeDynamoDBInput(input, params); err != nil {
+			return nil, err
+		}
+		var output *awsdynamodb.PutItemOutput
+		if output, err = d.client.PutItem(params); err == nil {
+			result.Attributes = output.Attributes
+		}
+	case DynamoDBUpdateItemResource:
+		params := &awsdynamodb.UpdateItemInput{}
+		if err := decodeDynamoDBInput(input, params); err != nil {
+			return nil, err
+		}
+		var output *awsdynamodb.UpdateItemOutput
+		if output, err = d.client.UpdateItem(params); err == nil {
+			result.Attributes = output.Attributes
+		}
+	case DynamoDBDeleteItemResource:
+		params := &awsdynamodb.DeleteItemInput{}
+		if err := decodeDynamoDBInput(input, params); err != nil {
+			return nil, err
+		}
+		var output *awsdynamodb.DeleteItemOutput
+		if output, err = d.client.DeleteItem(params); err == nil {
+			result.Attributes = output.Attributes
+		}
+	default:
+		return nil, state.NewError(state.ErrRuntimeCode, "unsupported DynamoDB resource "+d.resource)
+	}
+
+	if err != nil {
+		return nil, newServiceClientError("DynamoDB", err)
+	}
+
+	result.SdkHttpMetadata = sdkHttpMetadata{
+		HttpHeaders:    map[string]string{"Content-Type": "application/x-amz-json-1.0"},
+		HttpStatusCode: http.StatusOK,
+	}
+	result.SdkResponseMetadata = sdkResponseMetadata{
+		RequestId: uuid.New(),
+	}
+
+	return json.Marshal(result)
+}
+
+func (d DynamoDBTask) Next() string {
+	return d.definition.Next()
+}
+
+func (d DynamoDBTask) IsEnd() bool {
+	return d.definition.End()
+}
+
+// decodeDynamoDBInput decodes the parameters of an operation, failing the task if they're invalid
+func decodeDynamoDBInput(input []byte, params dynamoDBInput) error {
+	if err := json.Unmarshal(input, params); err != nil {
+		return state.NewError(state.ErrRuntimeCode, "invalid DynamoDB parameters: "+err.Error())
+	}
+
+	if err := params.Validate(); err != nil {
+		return state.NewError(state.ErrRuntimeCode, "invalid DynamoDB parameters: "+err.Error())
+	}
+
+	return nil
+}
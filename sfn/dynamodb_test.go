@@ -0,0 +1,146 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/dynamodb"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBTask(t *testing.T) {
+	newStore := func(t *testing.T) *dynamodb.Store {
+		store := dynamodb.NewStore()
+		require.NoError(t, store.CreateTable(dynamodb.Table{Name: "orders", PartitionKey: "id"}))
+		require.NoError(t, store.SeedJSON("orders", []byte(`[{"id":{"S":"1"},"status":{"S":"NEW"}}]`)))
+		return store
+	}
+
+	t.Run("workflow", func(t *testing.T) {
+		store := newStore(t)
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "get",
+			"States": {
+				"get": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:getItem",
+					"Parameters": {"TableName": "orders", "Key": {"id": {"S.$": "$.id"}}},
+					"ResultSelector": {"status.$": "$.Item.status.S"},
+					"ResultPath": "$.order",
+					"Next": "update"
+				},
+				"update": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:updateItem",
+					"Parameters": {
+						"TableName": "orders",
+						"Key": {"id": {"S.$": "$.id"}},
+						"UpdateExpression": "SET #s = :s",
+						"ConditionExpression": "#s = :new",
+						"ExpressionAttributeNames": {"#s": "status"},
+						"ExpressionAttributeValues": {":s": {"S": "DONE"}, ":new": {"S.$": "$.order.status"}},
+						"ReturnValues": "ALL_NEW"
+					},
+					"ResultPath": "$.updated",
+					"Next": "put"
+				},
+				"put": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:putItem",
+					"Parameters": {"TableName": "orders", "Item": {"id": {"S": "2"}, "status": {"S": "NEW"}}},
+					"ResultPath": null,
+					"Next": "delete"
+				},
+				"delete": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:deleteItem",
+					"Parameters": {"TableName": "orders", "Key": {"id": {"S": "2"}}, "ReturnValues": "ALL_OLD"},
+					"ResultSelector": {"deleted.$": "$.Attributes.id.S"},
+					"ResultPath": "$.deleted",
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterDynamoDB(resources, store)
+		})
+
+		result, err := fn.StartExecution([]byte(`{"id":"1"}`))
+		require.NoError(t, err)
+
+		var output struct {
+			Order   json.RawMessage `json:"order"`
+			Updated struct {
+				Attributes          json.RawMessage
+				SdkHttpMetadata     struct{ HttpStatusCode int }
+				SdkResponseMetadata struct{ RequestId string }
+			} `json:"updated"`
+			Deleted json.RawMessage `json:"deleted"`
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.JSONEq(t, `{"status":"NEW"}`, string(output.Order))
+		require.JSONEq(t, `{"id":{"S":"1"},"status":{"S":"DONE"}}`, string(output.Updated.Attributes))
+		require.Equal(t, 200, output.Updated.SdkHttpMetadata.HttpStatusCode)
+		require.NotEmpty(t, output.Updated.SdkResponseMetadata.RequestId)
+		require.JSONEq(t, `{"deleted":"2"}`, string(output.Deleted))
+
+		items, err := store.Items("orders")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		data, err := json.Marshal(items[0])
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":{"S":"1"},"status":{"S":"DONE"}}`, string(data))
+	})
+
+	t.Run("conditional check failed", func(t *testing.T) {
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "put",
+			"States": {
+				"put": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:putItem",
+					"Parameters": {"TableName": "orders", "Item": {"id": {"S": "1"}}, "ConditionExpression": "attribute_not_exists(id)"},
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterDynamoDB(resources, newStore(t))
+		})
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "DynamoDB.ConditionalCheckFailedException", result.Error)
+		require.Equal(t, "The conditional request failed", result.Cause)
+	})
+
+	t.Run("resource not found", func(t *testing.T) {
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "get",
+			"States": {
+				"get": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::dynamodb:getItem",
+					"Parameters": {"TableName": "missing", "Key": {"id": {"S": "1"}}},
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterDynamoDB(resources, newStore(t))
+		})
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "DynamoDB.ResourceNotFoundException", result.Error)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		task := sfn.NewDynamoDBTask(state.TaskDefinition{}, sfn.DynamoDBGetItemResource, newStore(t))
+
+		_, err := task.Run([]byte(`{"Key":{"id":{"S":"1"}}}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrRuntimeCode, err.(state.Error).Name)
+	})
+}
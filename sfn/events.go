@@ -0,0 +1,91 @@
+package sfn
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/eggsbenjamin/stepFnLocal/events"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+)
+
+// EventsPutEventsResource is the resource of the EventBridge integration, which puts the entries of the
+// task's parameters on their event buses
+const EventsPutEventsResource = "arn:aws:states:::events:putEvents"
+
+// ErrEventBridgeFailedEntryCode is the error of an EventBridge integration task whose entries weren't all
+// put
+const ErrEventBridgeFailedEntryCode = "EventBridge.FailedEntry"
+
+// putEventsParameters are the parameters of the EventBridge integration, whose entries are decoded once
+// their details are JSON text
+type putEventsParameters struct {
+	Entries []json.RawMessage `json:"Entries"`
+}
+
+// RegisterEvents registers the handler of the EventBridge integration resource, putting events with the client
+func RegisterEvents(resources ResourceRegistry, client events.Client) {
+	resources.Register(EventsPutEventsResource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewEventsPutEventsTask(def, client), nil
+	})
+}
+
+// EventsPutEventsTask puts events on event buses with the optimised EventBridge integration
+type EventsPutEventsTask struct {
+	definition state.TaskDefinition
+	client     events.Client
+}
+
+func NewEventsPutEventsTask(def state.TaskDefinition, client events.Client) State {
+	return EventsPutEventsTask{
+		definition: def,
+		client:     client,
+	}
+}
+
+// Run puts the entries of the task's input, failing with EventBridge.FailedEntry if any weren't put
+func (e EventsPutEventsTask) Run(input []byte) ([]byte, error) {
+	params := putEventsParameters{}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid events:putEvents parameters: "+err.Error())
+	}
+
+	request := &events.PutEventsInput{
+		Entries: make([]*events.PutEventsRequestEntry, 0, len(params.Entries)),
+	}
+	for _, data := range params.Entries {
+		data, err := stringifyParameter(data, "Detail")
+		if err != nil {
+			return nil, state.NewError(state.ErrRuntimeCode, "invalid events:putEvents parameters: "+err.Error())
+		}
+
+		entry := &events.PutEventsRequestEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return nil, state.NewError(state.ErrRuntimeCode, "invalid events:putEvents parameters: "+err.Error())
+		}
+		request.Entries = append(request.Entries, entry)
+	}
+
+	output, err := e.client.PutEvents(request)
+	if err != nil {
+		return nil, newServiceClientError("EventBridge", err)
+	}
+
+	result, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.FailedEntryCount > 0 {
+		return nil, state.NewError(ErrEventBridgeFailedEntryCode, string(result))
+	}
+
+	return result, nil
+}
+
+func (e EventsPutEventsTask) Next() string {
+	return e.definition.Next()
+}
+
+func (e EventsPutEventsTask) IsEnd() bool {
+	return e.definition.End()
+}
@@ -0,0 +1,86 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/eggsbenjamin/stepFnLocal/events"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/stretchr/testify/require"
+)
+
+// putEventsMachine returns the definition of a machine which puts the entries, a JSON array, on buses
+func putEventsMachine(entries string) string {
+	return `{
+		"StartAt": "publish",
+		"States": {
+			"publish": {
+				"Type": "Task",
+				"Resource": "arn:aws:states:::events:putEvents",
+				"Parameters": {"Entries": ` + entries + `},
+				"End": true
+			}
+		}
+	}`
+}
+
+// registerEvents returns the registration of the EventBridge integration, putting events on the bus
+func registerEvents(bus *events.Bus) func(sfn.ResourceRegistry) {
+	return func(resources sfn.ResourceRegistry) {
+		sfn.RegisterEvents(resources, bus)
+	}
+}
+
+func TestEventsPutEventsTask(t *testing.T) {
+	t.Run("put events", func(t *testing.T) {
+		bus := events.NewBus()
+		fn := newStepFunctionWithResources(t, putEventsMachine(`[
+			{"Source": "orders", "DetailType": "created", "Detail": {"id.$": "$.id"}},
+			{"Source": "orders", "DetailType": "audited", "Detail": "{\"id\":\"1\"}", "EventBusName": "audit"}
+		]`), registerEvents(bus))
+
+		result, err := fn.StartExecution([]byte(`{"id":"1"}`))
+		require.NoError(t, err)
+
+		var output struct {
+			Entries          []struct{ EventId string }
+			FailedEntryCount int
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.Equal(t, 0, output.FailedEntryCount)
+		require.Len(t, output.Entries, 2)
+
+		published := bus.EventsOnBus(events.DefaultEventBusName)
+		require.Len(t, published, 1)
+		require.Equal(t, output.Entries[0].EventId, published[0].EventID)
+		require.Equal(t, "created", published[0].DetailType)
+		require.JSONEq(t, `{"id":"1"}`, published[0].Detail)
+		require.Len(t, bus.EventsOnBus("audit"), 1)
+	})
+
+	t.Run("failed entries", func(t *testing.T) {
+		bus := events.NewBus()
+		bus.FailEntries(func(entry events.PutEventsRequestEntry) (string, string) {
+			return "InternalFailure", "injected failure for " + aws.StringValue(entry.DetailType)
+		})
+		fn := newStepFunctionWithResources(t, putEventsMachine(`[{"Source": "orders", "DetailType": "created", "Detail": {}}]`), registerEvents(bus))
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, sfn.ErrEventBridgeFailedEntryCode, result.Error)
+		require.JSONEq(t, `{
+			"Entries": [{"ErrorCode": "InternalFailure", "ErrorMessage": "injected failure for created"}],
+			"FailedEntryCount": 1
+		}`, result.Cause)
+		require.Empty(t, bus.Events())
+	})
+
+	t.Run("validation exception", func(t *testing.T) {
+		result, err := newStepFunctionWithResources(t, putEventsMachine(`[]`), registerEvents(events.NewBus())).StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "EventBridge.ValidationException", result.Error)
+	})
+}
@@ -0,0 +1,295 @@
+package sfn
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// StartExecutionResource is the resource of the integration which starts executions of other state
+	// machines, without waiting for them or with the .sync and .sync:2 patterns
+	StartExecutionResource = "arn:aws:states:::states:startExecution"
+
+	ErrStateMachineDoesNotExistCode = "StepFunctions.StateMachineDoesNotExistException"
+)
+
+// startExecutionParameters are the parameters of the startExecution integration. The input of the
+// execution may be JSON or a string of JSON.
+type startExecutionParameters struct {
+	StateMachineArn string          `json:"StateMachineArn"`
+	Input           json.RawMessage `json:"Input"`
+	Name            string          `json:"Name"`
+}
+
+// startExecutionResult is the result of starting an execution without waiting for it
+type startExecutionResult struct {
+	ExecutionArn string `json:"ExecutionArn"`
+	StartDate    int64  `json:"StartDate"`
+}
+
+// describeExecutionResult is the result of an execution waited for with the .sync patterns, as described
+// by the DescribeExecution API. The input and output are strings of JSON with .sync and JSON with .sync:2.
+type describeExecutionResult struct {
+	ExecutionArn    string          `json:"ExecutionArn"`
+	StateMachineArn string          `json:"StateMachineArn"`
+	Name            string          `json:"Name"`
+	Status          string          `json:"Status"`
+	StartDate       int64           `json:"StartDate"`
+	StopDate        int64           `json:"StopDate"`
+	Input           json.RawMessage `json:"Input"`
+	InputDetails    payloadDetails  `json:"InputDetails"`
+	Output          json.RawMessage `json:"Output,omitempty"`
+	OutputDetails   *payloadDetails `json:"OutputDetails,omitempty"`
+	Error           string          `json:"Error,omitempty"`
+	Cause           string          `json:"Cause,omitempty"`
+}
+
+type payloadDetails struct {
+	Included bool `json:"Included"`
+}
+
+// StateMachines is a registry of state machines by ARN, which tasks start executions of with the
+// startExecution integration. The results of the executions it starts are recorded in its history.
+type StateMachines struct {
+	mu         sync.Mutex
+	machines   map[string]StepFunction
+	executions []ExecutionResult
+	running    sync.WaitGroup
+}
+
+func NewStateMachines() *StateMachines {
+	return &StateMachines{
+		machines: map[string]StepFunction{},
+	}
+}
+
+// Register registers a state machine by ARN e.g. arn:aws:states:us-east-1:123456789012:stateMachine:name
+func (m *StateMachines) Register(stateMachineARN string, fn StepFunction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.machines[stateMachineARN] = fn
+}
+
+// Handler returns the resource handler of the startExecution integration e.g. to register as the
+// StartExecutionResource
+func (m *StateMachines) Handler() ResourceHandler {
+	return func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewStartExecutionTask(def, m), nil
+	}
+}
+
+// StartExecution runs an execution of a registered state machine and records its result
+func (m *StateMachines) StartExecution(stateMachineARN string, input []byte, opts ExecutionOptions) (ExecutionResult, error) {
+	fn, err := m.get(stateMachineARN)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+
+	opts.StateMachineARN = stateMachineARN
+	result, err := fn.StartExecutionWithOptions(input, opts)
+	m.record(result)
+
+	return result, err
+}
+
+// Executions returns the results of the executions that have completed, in the order they completed
+func (m *StateMachines) Executions() []ExecutionResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]ExecutionResult{}, m.executions...)
+}
+
+// ChildExecutions returns the results of the completed executions started by the execution
+func (m *StateMachines) ChildExecutions(executionARN string) []ExecutionResult {
+	children := []ExecutionResult{}
+	for _, execution := range m.Executions() {
+		if execution.ParentExecutionARN == executionARN {
+			children = append(children, execution)
+		}
+	}
+
+	return children
+}
+
+// Wait waits for the executions started without waiting for them to complete
+func (m *StateMachines) Wait() {
+	m.running.Wait()
+}
+
+func (m *StateMachines) get(stateMachineARN string) (StepFunction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn, ok := m.machines[stateMachineARN]
+	if !ok {
+		return nil, state.NewError(ErrStateMachineDoesNotExistCode, "State Machine Does Not Exist: '"+stateMachineARN+"'")
+	}
+
+	return fn, nil
+}
+
+func (m *StateMachines) record(result ExecutionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.executions = append(m.executions, result)
+}
+
+// StartExecutionTask starts an execution of a registered state machine with the startExecution
+// integration, arn:aws:states:::states:startExecution
+type StartExecutionTask struct {
+	definition state.TaskDefinition
+	machines   *StateMachines
+}
+
+func NewStartExecutionTask(def state.TaskDefinition, machines *StateMachines) State {
+	return StartExecutionTask{
+		definition: def,
+		machines:   machines,
+	}
+}
+
+func (s StartExecutionTask) Run(input []byte) ([]byte, error) {
+	return s.RunWithVariables(input, state.Variables{})
+}
+
+// RunWithVariables starts an execution of the state machine named by the task's input, linked to the
+// execution of the context object of the task. The task completes once the execution has started, or
+// with the .sync patterns once it has succeeded, failing with States.TaskFailed if it doesn't succeed.
+func (s StartExecutionTask) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
+	params := startExecutionParameters{}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid states:startExecution parameters: "+err.Error())
+	}
+
+	if params.StateMachineArn == "" {
+		return nil, state.NewError(state.ErrRuntimeCode, "The field 'StateMachineArn' is required but was missing")
+	}
+
+	executionInput, err := params.executionInput()
+	if err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid states:startExecution Input: "+err.Error())
+	}
+
+	if _, err := s.machines.get(params.StateMachineArn); err != nil {
+		return nil, err
+	}
+
+	opts := ExecutionOptions{
+		Name:               params.Name,
+		ParentExecutionARN: parentExecutionARN(variables),
+	}
+	if opts.Name == "" {
+		opts.Name = uuid.New()
+	}
+
+	if s.definition.IntegrationPattern() != state.SyncIntegrationPattern {
+		s.machines.running.Add(1)
+		go func() {
+			defer s.machines.running.Done()
+			s.machines.StartExecution(params.StateMachineArn, executionInput, opts)
+		}()
+
+		return json.Marshal(startExecutionResult{
+			ExecutionArn: executionARN(params.StateMachineArn, opts.Name),
+			StartDate:    epochMillis(time.Now()),
+		})
+	}
+
+	result, _ := s.machines.StartExecution(params.StateMachineArn, executionInput, opts)
+	described, err := describeExecution(result, strings.HasSuffix(s.definition.Resource, state.SyncIntegrationPattern+":2"))
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Status != ExecutionStatusSucceeded {
+		return nil, state.NewError(state.ErrTaskFailedCode, string(described))
+	}
+
+	return described, nil
+}
+
+func (s StartExecutionTask) Next() string {
+	return s.definition.Next()
+}
+
+func (s StartExecutionTask) IsEnd() bool {
+	return s.definition.End()
+}
+
+// executionInput returns the input of the execution, which is {} if omitted
+func (p startExecutionParameters) executionInput() ([]byte, error) {
+	if len(p.Input) == 0 || string(p.Input) == "null" {
+		return []byte(`{}`), nil
+	}
+
+	var input string
+	if err := json.Unmarshal(p.Input, &input); err != nil {
+		return p.Input, nil
+	}
+
+	if !json.Valid([]byte(input)) {
+		return nil, errors.New("input is not a string of JSON")
+	}
+
+	return []byte(input), nil
+}
+
+// parentExecutionARN returns the ARN of the execution of the context object of the variables
+func parentExecutionARN(variables state.Variables) string {
+	context := contextObject{}
+	json.Unmarshal(variables.Context(), &context)
+
+	return context.Execution.ID
+}
+
+// describeExecution describes the result of an execution, with its input and output as strings of JSON
+// unless parsed
+func describeExecution(result ExecutionResult, parsed bool) ([]byte, error) {
+	payload := func(data []byte) (json.RawMessage, error) {
+		if parsed {
+			return data, nil
+		}
+		return json.Marshal(string(data))
+	}
+
+	described := describeExecutionResult{
+		ExecutionArn:    result.ExecutionARN,
+		StateMachineArn: result.StateMachineARN,
+		Name:            result.ExecutionARN[strings.LastIndex(result.ExecutionARN, ":")+1:],
+		Status:          result.Status,
+		StartDate:       epochMillis(result.Start),
+		StopDate:        epochMillis(result.End),
+		InputDetails:    payloadDetails{Included: true},
+		Error:           result.Error,
+		Cause:           result.Cause,
+	}
+
+	var err error
+	if described.Input, err = payload(result.Input); err != nil {
+		return nil, err
+	}
+
+	if result.Status == ExecutionStatusSucceeded {
+		if described.Output, err = payload(result.Output); err != nil {
+			return nil, err
+		}
+		described.OutputDetails = &payloadDetails{Included: true}
+	}
+
+	return json.Marshal(described)
+}
+
+// epochMillis returns the time in milliseconds since the epoch, as integrations return timestamps
+func epochMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
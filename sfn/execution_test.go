@@ -0,0 +1,136 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	parentARN = "arn:aws:states:us-east-1:123456789012:stateMachine:parent"
+	childARN  = "arn:aws:states:us-east-1:123456789012:stateMachine:child"
+)
+
+// newNestedStateMachines registers a parent machine whose task starts the child machine with the
+// resource, and a child which fails if its input has a "fail" field of true
+func newNestedStateMachines(t *testing.T, resource, parameters string) *sfn.StateMachines {
+	machines := sfn.NewStateMachines()
+	resources := sfn.NewResourceRegistry()
+	resources.Register(sfn.StartExecutionResource, machines.Handler())
+
+	parent, err := sfn.NewWithResources(state.MachineDefinition{
+		StartAt: "start",
+		States: state.MachineStates{
+			"start": []byte(`{"Type":"Task","Resource":"` + resource + `","Parameters":` + parameters + `,"End":true}`),
+		},
+	}, nil, resources)
+	require.NoError(t, err)
+	machines.Register(parentARN, parent)
+
+	child, err := sfn.New(state.MachineDefinition{
+		StartAt: "choice",
+		States: state.MachineStates{
+			"choice": []byte(`{"Type":"Choice","Choices":[{"Variable":"$.fail","BooleanEquals":true,"Next":"fail"}],"Default":"pass"}`),
+			"fail":   []byte(`{"Type":"Fail","Error":"ChildError","Cause":"child failed"}`),
+			"pass":   []byte(`{"Type":"Pass","Parameters":{"b.$":"$.a"},"End":true}`),
+		},
+	}, nil)
+	require.NoError(t, err)
+	machines.Register(childARN, child)
+
+	return machines
+}
+
+func TestStartExecution(t *testing.T) {
+	t.Run("sync", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource+".sync", `{"StateMachineArn":"`+childARN+`","Input":{"a":1,"fail":false},"Name":"nested"}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.NoError(t, err)
+
+		var output map[string]interface{}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.Equal(t, "arn:aws:states:us-east-1:123456789012:execution:child:nested", output["ExecutionArn"])
+		require.Equal(t, childARN, output["StateMachineArn"])
+		require.Equal(t, "nested", output["Name"])
+		require.Equal(t, sfn.ExecutionStatusSucceeded, output["Status"])
+		require.JSONEq(t, `{"b":1}`, output["Output"].(string))
+		require.JSONEq(t, `{"a":1,"fail":false}`, output["Input"].(string))
+
+		children := machines.ChildExecutions(result.ExecutionARN)
+		require.Len(t, children, 1)
+		require.Equal(t, output["ExecutionArn"], children[0].ExecutionARN)
+		require.Equal(t, result.ExecutionARN, children[0].ParentExecutionARN)
+		require.Len(t, machines.Executions(), 2)
+	})
+
+	t.Run("sync:2", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource+".sync:2", `{"StateMachineArn":"`+childARN+`","Input":"{\"a\":1,\"fail\":false}"}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.NoError(t, err)
+
+		var output struct {
+			Input  json.RawMessage
+			Output json.RawMessage
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.JSONEq(t, `{"a":1,"fail":false}`, string(output.Input))
+		require.JSONEq(t, `{"b":1}`, string(output.Output))
+	})
+
+	t.Run("sync failure", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource+".sync", `{"StateMachineArn":"`+childARN+`","Input":{"fail":true}}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.Error(t, err)
+		require.Equal(t, state.ErrTaskFailedCode, result.Error)
+
+		var cause map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result.Cause), &cause))
+		require.Equal(t, sfn.ExecutionStatusFailed, cause["Status"])
+		require.Equal(t, "ChildError", cause["Error"])
+		require.Equal(t, "child failed", cause["Cause"])
+	})
+
+	t.Run("fire and forget", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource, `{"StateMachineArn":"`+childARN+`","Input":{"a":1,"fail":false}}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.NoError(t, err)
+		machines.Wait()
+
+		var output struct {
+			ExecutionArn string
+			StartDate    int64
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.NotZero(t, output.StartDate)
+
+		children := machines.ChildExecutions(result.ExecutionARN)
+		require.Len(t, children, 1)
+		require.Equal(t, output.ExecutionArn, children[0].ExecutionARN)
+		require.JSONEq(t, `{"b":1}`, string(children[0].Output))
+	})
+
+	t.Run("state machine does not exist", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource+".sync", `{"StateMachineArn":"arn:aws:states:us-east-1:123456789012:stateMachine:missing"}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.Error(t, err)
+		require.Equal(t, sfn.ErrStateMachineDoesNotExistCode, result.Error)
+	})
+
+	t.Run("missing state machine ARN", func(t *testing.T) {
+		machines := newNestedStateMachines(t, sfn.StartExecutionResource+".sync", `{}`)
+
+		result, err := machines.StartExecution(parentARN, []byte(`{}`), sfn.ExecutionOptions{})
+		require.Error(t, err)
+		require.Equal(t, state.ErrRuntimeCode, result.Error)
+	})
+}
@@ -0,0 +1,34 @@
+// +build unit
+
+package sfn
+
+import "time"
+
+const MaxTimedOutTokens = maxTimedOutTokens
+
+// TimeOutTask registers the token of a new task and times it out
+func TimeOutTask(tokens *TaskTokens) string {
+	task := tokens.create()
+	tokens.timeout(task)
+	return task.token
+}
+
+// PendingTasks returns the number of registered tasks waiting for their outcome
+func PendingTasks(tokens *TaskTokens) int {
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+
+	return len(tokens.tasks)
+}
+
+// TimedOutWait registers the token of a new task and returns a wait for its outcome whose timeout has
+// already elapsed
+func TimedOutWait(tokens *TaskTokens) (string, func() ([]byte, error)) {
+	task := tokens.create()
+	timeout := make(chan time.Time, 1)
+	timeout <- time.Time{}
+
+	return task.token, func() ([]byte, error) {
+		return tokens.wait(task, timeout, 0, systemClock{})
+	}
+}
@@ -3,7 +3,6 @@
 package sfn
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/eggsbenjamin/stepFnLocal/lambda"
 	state "github.com/eggsbenjamin/stepFnLocal/state"
@@ -15,14 +14,19 @@ type StateFactory interface {
 }
 
 type stateFactory struct {
-	overrides    map[string]OverrideFn
-	lambdaClient lambda.Client
+	overrides map[string]OverrideFn
+	resources ResourceRegistry
 }
 
 func NewStateFactory(overrides map[string]OverrideFn, lambdaClient lambda.Client) StateFactory {
+	return NewStateFactoryWithResources(overrides, NewDefaultResourceRegistry(lambdaClient))
+}
+
+// NewStateFactoryWithResources returns a factory which creates tasks with the handlers of the registry
+func NewStateFactoryWithResources(overrides map[string]OverrideFn, resources ResourceRegistry) StateFactory {
 	return stateFactory{
-		overrides:    overrides,
-		lambdaClient: lambdaClient,
+		overrides: overrides,
+		resources: resources,
 	}
 }
 
@@ -69,19 +73,22 @@ func (s stateFactory) Create(def state.Definition) (State, error) {
 	return nil, state.ErrUnknownState
 }
 
-// TODO: these shouldn't be methods exposed by the factory, instad they should be dependencies (see abstract factory pattern)
 func (s stateFactory) createTaskState(def state.TaskDefinition) (State, error) {
 	if overrideFn, ok := s.overrides[def.Resource]; ok {
 		return NewOverrideTask(def, overrideFn), nil
 	}
 
-	arn, err := arn.Parse(def.Resource)
+	resource, err := arn.Parse(def.Resource)
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid arn")
+		return nil, errors.Wrapf(ErrUnsupportedResource, "%s is neither an override nor a valid arn: %s", def.Resource, err)
 	}
 
-	// TODO: add support for aws resources other than lambda
-	return NewLambdaTask(def, arn, s.lambdaClient), nil
+	handler, err := s.resources.Handler(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(def, resource)
 }
 
 func (s stateFactory) createChoiceState(def state.ChoiceDefinition) (State, error) {
@@ -102,12 +109,10 @@ func (s stateFactory) createChoiceState(def state.ChoiceDefinition) (State, erro
 func (s stateFactory) createParallelState(def state.ParallelDefinition) (State, error) {
 	stateMachines := []StepFunction{}
 
+	// the branches are validated with the parallel state and create their states with this factory. Their
+	// states were parsed with the parallel state, so building their machines is cheap.
 	for _, branchDef := range def.Branches {
-		stateMachine, err := NewWithAWSConfig(branchDef, s.overrides, &aws.Config{}) // TODO: use same config
-		if err != nil {
-			return nil, errors.Wrap(err, "error creating parallel state branch")
-		}
-		stateMachines = append(stateMachines, stateMachine)
+		stateMachines = append(stateMachines, newStepFunction(branchDef, s))
 	}
 
 	return NewParallelState(def, stateMachines...), nil
@@ -1,6 +1,11 @@
 package sfn
 
-import "github.com/eggsbenjamin/stepFnLocal/state"
+import (
+	"encoding/json"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+)
 
 type FailState struct {
 	def state.FailDefinition
@@ -13,7 +18,22 @@ func NewFailState(def state.FailDefinition) FailState {
 }
 
 func (p FailState) Run(input []byte) ([]byte, error) {
-	return input, state.NewError(p.def.Error, p.def.Cause)
+	return p.RunWithVariables(input, nil)
+}
+
+// RunWithVariables fails with the error and cause, which may reference variables
+func (p FailState) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
+	name, err := resolveFailField("ErrorPath", p.def.Error, p.def.CompiledErrorPath(), input, variables)
+	if err != nil {
+		return input, err
+	}
+
+	cause, err := resolveFailField("CausePath", p.def.Cause, p.def.CompiledCausePath(), input, variables)
+	if err != nil {
+		return input, err
+	}
+
+	return input, state.NewError(name, cause)
 }
 
 func (p FailState) Next() string {
@@ -23,3 +43,37 @@ func (p FailState) Next() string {
 func (p FailState) IsEnd() bool {
 	return true
 }
+
+// resolveFailField returns the static value of a Fail state field, the string a JSONata value evaluates
+// to or, if set, the string its path resolves to.
+func resolveFailField(field string, value string, path *state.CompiledValueExp, input []byte, variables state.Variables) (string, error) {
+	if state.IsJSONataExpression(value) {
+		bindings, err := state.NewJSONataBindings(input, nil, variables)
+		if err != nil {
+			return "", state.AsError(errors.Wrapf(err, "error resolving %s", field), state.ErrQueryEvaluationErrorCode)
+		}
+
+		return state.EvaluateJSONataString(value, bindings)
+	}
+
+	if path.IsEmpty() {
+		return value, nil
+	}
+
+	doc, err := variables.Document(input)
+	if err != nil {
+		return "", state.AsError(errors.Wrapf(err, "error resolving %s", field), state.ErrRuntimeCode)
+	}
+
+	result, err := path.EvaluateDocument(doc)
+	if err != nil {
+		return "", state.AsError(errors.Wrapf(err, "error resolving %s", field), state.ErrRuntimeCode)
+	}
+
+	var str string
+	if err := json.Unmarshal(result, &str); err != nil {
+		return "", state.NewError(state.ErrRuntimeCode, field+" must resolve to a string: "+string(result))
+	}
+
+	return str, nil
+}
@@ -9,20 +9,76 @@ import (
 )
 
 func TestFailState(t *testing.T) {
-	input := []byte("input")
-	def := state.FailDefinition{
-		Error: "test error",
-		Cause: "test cause",
-	}
-	failState := sfn.NewFailState(def)
-
-	expectedErr := state.NewError(
-		def.Error,
-		def.Cause,
-	)
-
-	result, err := failState.Run(input)
-	require.Equal(t, input, result) // should never modify it's input
-	require.Equal(t, "", failState.Next())
-	require.Equal(t, expectedErr, err)
+	t.Run("static error and cause", func(t *testing.T) {
+		input := []byte("input")
+		def := state.FailDefinition{
+			Error: "test error",
+			Cause: "test cause",
+		}
+		failState := sfn.NewFailState(def)
+
+		expectedErr := state.NewError(
+			def.Error,
+			def.Cause,
+		)
+
+		result, err := failState.Run(input)
+		require.Equal(t, input, result) // should never modify it's input
+		require.Equal(t, "", failState.Next())
+		require.Equal(t, expectedErr, err)
+	})
+
+	t.Run("ErrorPath and CausePath", func(t *testing.T) {
+		input := []byte(`{"error":"Custom.Error","detail":"upstream failure"}`)
+		def := state.FailDefinition{
+			ErrorPath: "$.error",
+			CausePath: "States.Format('failed: {}', $.detail)",
+		}
+		failState := sfn.NewFailState(def)
+
+		result, err := failState.Run(input)
+		require.Equal(t, input, result)
+		require.Equal(t, state.NewError("Custom.Error", "failed: upstream failure"), err)
+	})
+
+	t.Run("path errors", func(t *testing.T) {
+		tests := []struct {
+			title        string
+			def          state.FailDefinition
+			expectedName string
+		}{
+			{
+				"path not found",
+				state.FailDefinition{
+					ErrorPath: "$.missing",
+				},
+				state.ErrRuntimeCode,
+			},
+			{
+				"path to non string",
+				state.FailDefinition{
+					CausePath: "$.count",
+				},
+				state.ErrRuntimeCode,
+			},
+			{
+				"intrinsic function failure",
+				state.FailDefinition{
+					CausePath: "States.ArrayGetItem($.items, 3)",
+				},
+				state.ErrIntrinsicFailureCode,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				failState := sfn.NewFailState(tt.def)
+
+				_, err := failState.Run([]byte(`{"count":1,"items":[]}`))
+				stateErr, ok := err.(state.Error)
+				require.True(t, ok)
+				require.Equal(t, tt.expectedName, stateErr.Name)
+			})
+		}
+	})
 }
@@ -0,0 +1,163 @@
+package sfn
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// LambdaInvokeResource is the resource of the optimised lambda integration
+	LambdaInvokeResource = "arn:aws:states:::lambda:invoke"
+
+	ErrLambdaUnknownCode            = "Lambda.Unknown"
+	ErrLambdaSdkClientExceptionCode = "Lambda.SdkClientException"
+)
+
+// errLambdaNoOutput is the error of a lambda client that returned neither an output nor an error
+var errLambdaNoOutput = errors.New("lambda client returned no output")
+
+// lambdaInvokeParameters are the parameters of the optimised lambda integration
+type lambdaInvokeParameters struct {
+	FunctionName   string          `json:"FunctionName"`
+	Payload        json.RawMessage `json:"Payload"`
+	Qualifier      string          `json:"Qualifier"`
+	InvocationType string          `json:"InvocationType"`
+	ClientContext  string          `json:"ClientContext"`
+	LogType        string          `json:"LogType"`
+}
+
+// lambdaInvokeResult is the result of the optimised lambda integration, which envelopes the function's
+// payload with the response of the invocation
+type lambdaInvokeResult struct {
+	ExecutedVersion     string              `json:"ExecutedVersion,omitempty"`
+	FunctionError       string              `json:"FunctionError,omitempty"`
+	LogResult           string              `json:"LogResult,omitempty"`
+	Payload             json.RawMessage     `json:"Payload,omitempty"`
+	SdkHttpMetadata     sdkHttpMetadata     `json:"SdkHttpMetadata"`
+	SdkResponseMetadata sdkResponseMetadata `json:"SdkResponseMetadata"`
+	StatusCode          int64               `json:"StatusCode"`
+}
+
+// lambdaFunctionError is the payload of a function that returned an error
+type lambdaFunctionError struct {
+	ErrorType    string `json:"errorType"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// LambdaInvokeTask invokes a function with the optimised lambda integration, arn:aws:states:::lambda:invoke
+type LambdaInvokeTask struct {
+	definition   state.TaskDefinition
+	lambdaClient lambda.Client
+}
+
+func NewLambdaInvokeTask(def state.TaskDefinition, lambdaClient lambda.Client) State {
+	return LambdaInvokeTask{
+		definition:   def,
+		lambdaClient: lambdaClient,
+	}
+}
+
+// Run invokes the function named by the task's input. A function error fails the task with the error
+// type of the function and a client error with the name of the lambda service exception.
+func (l LambdaInvokeTask) Run(input []byte) ([]byte, error) {
+	params := lambdaInvokeParameters{}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid lambda:invoke parameters: "+err.Error())
+	}
+
+	if params.FunctionName == "" {
+		return nil, state.NewError(state.ErrRuntimeCode, "The field 'FunctionName' is required but was missing")
+	}
+
+	invokeInput := &awslambda.InvokeInput{
+		FunctionName: aws.String(params.FunctionName),
+		Payload:      params.Payload,
+	}
+	if params.Qualifier != "" {
+		invokeInput.Qualifier = aws.String(params.Qualifier)
+	}
+	if params.InvocationType != "" {
+		invokeInput.InvocationType = aws.String(params.InvocationType)
+	}
+	if params.ClientContext != "" {
+		invokeInput.ClientContext = aws.String(params.ClientContext)
+	}
+	if params.LogType != "" {
+		invokeInput.LogType = aws.String(params.LogType)
+	}
+
+	invokeOutput, err := l.lambdaClient.Invoke(invokeInput)
+	if err != nil {
+		return nil, newServiceClientError("Lambda", err)
+	}
+	if invokeOutput == nil {
+		return nil, newServiceClientError("Lambda", errLambdaNoOutput)
+	}
+
+	if invokeOutput.FunctionError != nil {
+		return nil, newLambdaFunctionError(invokeOutput.Payload)
+	}
+
+	return json.Marshal(newLambdaInvokeResult(invokeOutput))
+}
+
+func (l LambdaInvokeTask) Next() string {
+	return l.definition.Next()
+}
+
+func (l LambdaInvokeTask) IsEnd() bool {
+	return l.definition.End()
+}
+
+func newLambdaInvokeResult(invokeOutput *awslambda.InvokeOutput) lambdaInvokeResult {
+	statusCode := aws.Int64Value(invokeOutput.StatusCode)
+	if invokeOutput.StatusCode == nil {
+		statusCode = http.StatusOK
+	}
+
+	result := lambdaInvokeResult{
+		ExecutedVersion: aws.StringValue(invokeOutput.ExecutedVersion),
+		LogResult:       aws.StringValue(invokeOutput.LogResult),
+		SdkHttpMetadata: sdkHttpMetadata{
+			HttpHeaders:    map[string]string{"Content-Type": "application/json"},
+			HttpStatusCode: statusCode,
+		},
+		SdkResponseMetadata: sdkResponseMetadata{
+			RequestId: uuid.New(),
+		},
+		StatusCode: statusCode,
+	}
+
+	if result.ExecutedVersion != "" {
+		result.SdkHttpMetadata.HttpHeaders["X-Amz-Executed-Version"] = result.ExecutedVersion
+	}
+
+	// a payload that isn't JSON is returned as a string
+	if len(invokeOutput.Payload) > 0 {
+		result.Payload = invokeOutput.Payload
+		if !json.Valid(invokeOutput.Payload) {
+			result.Payload, _ = json.Marshal(string(invokeOutput.Payload))
+		}
+	}
+
+	return result
+}
+
+// newLambdaFunctionError returns the error of a function, which is named after the error type of its
+// payload and caused by its error message. The payload of a function that failed without an error type
+// e.g. one that timed out, is the cause of a Lambda.Unknown error.
+func newLambdaFunctionError(payload []byte) state.Error {
+	functionErr := lambdaFunctionError{}
+	if err := json.Unmarshal(payload, &functionErr); err != nil || functionErr.ErrorType == "" {
+		return state.NewError(ErrLambdaUnknownCode, string(payload))
+	}
+
+	return state.NewError(functionErr.ErrorType, functionErr.ErrorMessage)
+}
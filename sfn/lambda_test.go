@@ -0,0 +1,144 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLambdaInvokeTask(t *testing.T) {
+	def := state.TaskDefinition{Resource: sfn.LambdaInvokeResource}
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockClient := lambda.NewMockClient(ctrl)
+		mockClient.EXPECT().Invoke(&awslambda.InvokeInput{
+			FunctionName: aws.String("test"),
+			Qualifier:    aws.String("live"),
+			Payload:      []byte(`{"a":1}`),
+		}).Return(&awslambda.InvokeOutput{
+			ExecutedVersion: aws.String("2"),
+			Payload:         []byte(`{"b":2}`),
+			StatusCode:      aws.Int64(200),
+		}, nil)
+
+		task := sfn.NewLambdaInvokeTask(def, mockClient)
+		output, err := task.Run([]byte(`{"FunctionName":"test","Qualifier":"live","Payload":{"a":1}}`))
+		require.NoError(t, err)
+
+		result := map[string]json.RawMessage{}
+		require.NoError(t, json.Unmarshal(output, &result))
+		require.JSONEq(t, `"2"`, string(result["ExecutedVersion"]))
+		require.JSONEq(t, `{"b":2}`, string(result["Payload"]))
+		require.JSONEq(t, `200`, string(result["StatusCode"]))
+		require.Contains(t, result, "SdkHttpMetadata")
+		require.Contains(t, result, "SdkResponseMetadata")
+	})
+
+	t.Run("non JSON payload", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockClient := lambda.NewMockClient(ctrl)
+		mockClient.EXPECT().Invoke(gomock.Any()).Return(&awslambda.InvokeOutput{
+			Payload: []byte(`ok`),
+		}, nil)
+
+		task := sfn.NewLambdaInvokeTask(def, mockClient)
+		output, err := task.Run([]byte(`{"FunctionName":"test"}`))
+		require.NoError(t, err)
+
+		result := map[string]json.RawMessage{}
+		require.NoError(t, json.Unmarshal(output, &result))
+		require.JSONEq(t, `"ok"`, string(result["Payload"]))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tests := []struct {
+			title         string
+			output        *awslambda.InvokeOutput
+			err           error
+			expectedError state.Error
+		}{
+			{
+				"service exception",
+				nil,
+				awserr.New("ServiceException", "service error", nil),
+				state.NewError("Lambda.ServiceException", "service error"),
+			},
+			{
+				"client",
+				nil,
+				errors.New("connection refused"),
+				state.NewError(sfn.ErrLambdaSdkClientExceptionCode, "connection refused"),
+			},
+			{
+				"function error",
+				&awslambda.InvokeOutput{
+					FunctionError: aws.String("Unhandled"),
+					Payload:       []byte(`{"errorType":"MyCustomError","errorMessage":"custom error"}`),
+				},
+				nil,
+				state.NewError("MyCustomError", "custom error"),
+			},
+			{
+				"function error without type",
+				&awslambda.InvokeOutput{
+					FunctionError: aws.String("Unhandled"),
+					Payload:       []byte(`{"errorMessage":"Task timed out after 3.00 seconds"}`),
+				},
+				nil,
+				state.NewError(sfn.ErrLambdaUnknownCode, `{"errorMessage":"Task timed out after 3.00 seconds"}`),
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				mockClient := lambda.NewMockClient(ctrl)
+				mockClient.EXPECT().Invoke(gomock.Any()).Return(tt.output, tt.err)
+
+				task := sfn.NewLambdaInvokeTask(def, mockClient)
+				_, err := task.Run([]byte(`{"FunctionName":"test"}`))
+				require.Equal(t, tt.expectedError, err)
+			})
+		}
+
+		t.Run("missing function name", func(t *testing.T) {
+			task := sfn.NewLambdaInvokeTask(def, nil)
+			_, err := task.Run([]byte(`{"Payload":{}}`))
+			require.Error(t, err)
+			require.Equal(t, state.ErrRuntimeCode, err.(state.Error).Name)
+		})
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		factory := sfn.NewStateFactory(nil, nil)
+
+		_state, err := factory.Create(state.TaskDefinition{Resource: sfn.LambdaInvokeResource})
+		require.NoError(t, err)
+		require.IsType(t, sfn.LambdaInvokeTask{}, _state)
+
+		_state, err = factory.Create(state.TaskDefinition{Resource: sfn.LambdaInvokeResource + ".waitForTaskToken"})
+		require.NoError(t, err)
+		require.IsType(t, sfn.LambdaInvokeTask{}, _state)
+
+		_, err = factory.Create(state.TaskDefinition{Resource: sfn.LambdaInvokeResource + ".sync"})
+		require.Equal(t, sfn.ErrUnsupportedResource, errors.Cause(err))
+	})
+}
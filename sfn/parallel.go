@@ -10,24 +10,36 @@ import (
 type ParallelState struct {
 	def           state.ParallelDefinition
 	stateMachines []StepFunction
+	branchResults []ExecutionResult
 }
 
 func NewParallelState(def state.ParallelDefinition, stateMachines ...StepFunction) ParallelState {
 	return ParallelState{
 		def:           def,
 		stateMachines: stateMachines,
+		branchResults: make([]ExecutionResult, len(stateMachines)),
 	}
 }
 
 func (p ParallelState) Run(input []byte) ([]byte, error) {
+	return p.RunWithVariables(input, nil)
+}
+
+// RunWithVariables runs the branches, each of which can read a copy of the variables
+func (p ParallelState) RunWithVariables(input []byte, variables state.Variables) ([]byte, error) {
 	type stateMachineResult struct {
 		Index  int // order of output is important
-		Output json.RawMessage
+		Result ExecutionResult
+		Err    error
+	}
+
+	// results of a previous run of the state, e.g. in a loop, have already been accounted for
+	for i := range p.branchResults {
+		p.branchResults[i] = ExecutionResult{}
 	}
 
 	var wg sync.WaitGroup
-	stateMachineResults := make(chan stateMachineResult)
-	errs := make(chan error)
+	stateMachineResults := make(chan stateMachineResult, len(p.stateMachines))
 
 	wg.Add(len(p.stateMachines))
 	go func() {
@@ -39,39 +51,44 @@ func (p ParallelState) Run(input []byte) ([]byte, error) {
 		go func(index int, stateMachine StepFunction) {
 			defer wg.Done()
 
-			result, err := stateMachine.StartExecution(input)
-			if err != nil {
-				errs <- err
-				return
-			}
+			result, err := stateMachine.StartExecutionWithVariables(input, variables)
 			stateMachineResults <- stateMachineResult{
 				Index:  index,
-				Output: result.Output,
+				Result: result,
+				Err:    err,
 			}
 		}(i, stateMachine)
 	}
 
+	// every branch is waited for, even once one has failed, so none is left running after the state. The
+	// state fails with the error of the first branch to fail.
+	var branchErr error
 	results := make([]json.RawMessage, len(p.stateMachines))
-	running := true
-	for running {
-		select {
-		case result, ok := <-stateMachineResults:
-			if !ok {
-				running = false
-				break
-			}
-			results[result.Index] = result.Output
-		case err, ok := <-errs:
-			if ok {
-				return []byte{}, err
-			}
-			errs = nil
+	for result := range stateMachineResults {
+		p.branchResults[result.Index] = result.Result
+		if result.Err != nil && branchErr == nil {
+			branchErr = result.Err
 		}
+		results[result.Index] = result.Result.Output
+	}
+
+	if branchErr != nil {
+		return []byte{}, branchErr
 	}
 
 	return json.Marshal(results)
 }
 
+// Branches returns the state machines of the branches
+func (p ParallelState) Branches() []StepFunction {
+	return p.stateMachines
+}
+
+// BranchResults returns the results of the branch executions that have completed
+func (p ParallelState) BranchResults() []ExecutionResult {
+	return p.branchResults
+}
+
 func (p ParallelState) Next() string {
 	return p.def.NextState
 }
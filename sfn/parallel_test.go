@@ -2,6 +2,7 @@ package sfn_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/eggsbenjamin/stepFnLocal/sfn"
 	"github.com/eggsbenjamin/stepFnLocal/state"
@@ -21,7 +22,7 @@ func TestParallelState(t *testing.T) {
 			"single branch error",
 			func(ctrl *gomock.Controller) (sfn.State, []byte) {
 				branch := sfn.NewMockStepFunction(ctrl)
-				branch.EXPECT().StartExecution(gomock.Any()).Return(
+				branch.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{},
 					dummyErr,
 				)
@@ -38,14 +39,14 @@ func TestParallelState(t *testing.T) {
 			"multiple branches one error",
 			func(ctrl *gomock.Controller) (sfn.State, []byte) {
 				branch1 := sfn.NewMockStepFunction(ctrl)
-				branch1.EXPECT().StartExecution(gomock.Any()).Return(
+				branch1.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{
 						Status: sfn.ExecutionStatusSucceeded,
 					},
 					nil,
 				)
 				branch2 := sfn.NewMockStepFunction(ctrl)
-				branch2.EXPECT().StartExecution(gomock.Any()).Return(
+				branch2.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{},
 					dummyErr,
 				)
@@ -63,7 +64,7 @@ func TestParallelState(t *testing.T) {
 			"single branch success",
 			func(ctrl *gomock.Controller) (sfn.State, []byte) {
 				branch := sfn.NewMockStepFunction(ctrl)
-				branch.EXPECT().StartExecution(gomock.Any()).Return(
+				branch.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{
 						Status: sfn.ExecutionStatusSucceeded,
 						Output: []byte(`{"result":"test"}`),
@@ -83,7 +84,7 @@ func TestParallelState(t *testing.T) {
 			"multiple branch success",
 			func(ctrl *gomock.Controller) (sfn.State, []byte) {
 				branch1 := sfn.NewMockStepFunction(ctrl)
-				branch1.EXPECT().StartExecution(gomock.Any()).Return(
+				branch1.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{
 						Status: sfn.ExecutionStatusSucceeded,
 						Output: []byte(`{"result":"test"}`),
@@ -91,7 +92,7 @@ func TestParallelState(t *testing.T) {
 					nil,
 				)
 				branch2 := sfn.NewMockStepFunction(ctrl)
-				branch2.EXPECT().StartExecution(gomock.Any()).Return(
+				branch2.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{
 						Status: sfn.ExecutionStatusSucceeded,
 						Output: []byte(`"test"`),
@@ -99,7 +100,7 @@ func TestParallelState(t *testing.T) {
 					nil,
 				)
 				branch3 := sfn.NewMockStepFunction(ctrl)
-				branch3.EXPECT().StartExecution(gomock.Any()).Return(
+				branch3.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
 					sfn.ExecutionResult{
 						Status: sfn.ExecutionStatusSucceeded,
 						Output: []byte(`1351`),
@@ -135,4 +136,44 @@ func TestParallelState(t *testing.T) {
 			ctrl.Finish()
 		})
 	}
+
+	t.Run("branch error waits for the other branches", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		release := make(chan struct{})
+		failed := sfn.NewMockStepFunction(ctrl)
+		failed.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).Return(
+			sfn.ExecutionResult{},
+			dummyErr,
+		)
+		running := sfn.NewMockStepFunction(ctrl)
+		running.EXPECT().StartExecutionWithVariables(gomock.Any(), gomock.Any()).DoAndReturn(
+			func([]byte, state.Variables) (sfn.ExecutionResult, error) {
+				<-release
+				return sfn.ExecutionResult{Status: sfn.ExecutionStatusSucceeded, HistoryEvents: 2}, nil
+			},
+		)
+		parallelState := sfn.NewParallelState(
+			state.ParallelDefinition{},
+			failed,
+			running,
+		)
+
+		done := make(chan error)
+		go func() {
+			_, err := parallelState.Run([]byte{})
+			done <- err
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("state returned while a branch was still running")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		require.Equal(t, dummyErr, errors.Cause(<-done))
+		require.Equal(t, 2, parallelState.BranchResults()[1].HistoryEvents)
+	})
 }
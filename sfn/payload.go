@@ -0,0 +1,70 @@
+package sfn
+
+import (
+	"fmt"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+)
+
+// DefaultPayloadLimit is the AWS quota, in bytes, for the input and output of a state and the payload of
+// a task
+const DefaultPayloadLimit = 256 * 1024
+
+// PayloadLimits are the maximum sizes, in bytes, of the payloads of an execution. A state exceeding a
+// limit fails with States.DataLimitExceeded. A limit of 0 isn't enforced.
+type PayloadLimits struct {
+	StateInputOutput int // the raw input and output of every state
+	Task             int // the effective input sent to, and result received from, a task
+}
+
+// DefaultPayloadLimits returns the limits AWS enforces
+func DefaultPayloadLimits() PayloadLimits {
+	return PayloadLimits{
+		StateInputOutput: DefaultPayloadLimit,
+		Task:             DefaultPayloadLimit,
+	}
+}
+
+// PayloadReport records the size, in bytes, of the largest payload seen by each state of an execution,
+// including the states of Parallel branches
+type PayloadReport map[string]int
+
+// Largest returns the state with the largest payload of the execution
+func (p PayloadReport) Largest() (string, int) {
+	largestState, largestSize := "", 0
+	for stateTitle, size := range p {
+		if size > largestSize || (size == largestSize && stateTitle < largestState) {
+			largestState, largestSize = stateTitle, size
+		}
+	}
+
+	return largestState, largestSize
+}
+
+func (p PayloadReport) record(stateTitle string, payload []byte) {
+	if len(payload) > p[stateTitle] {
+		p[stateTitle] = len(payload)
+	}
+}
+
+func (p PayloadReport) merge(report PayloadReport) {
+	for stateTitle, size := range report {
+		if size > p[stateTitle] {
+			p[stateTitle] = size
+		}
+	}
+}
+
+// checkPayload records the size of a payload of a state and enforces its limit
+func checkPayload(report PayloadReport, stateTitle, payloadType string, payload []byte, limit int) error {
+	report.record(stateTitle, payload)
+
+	if limit > 0 && len(payload) > limit {
+		return state.NewError(
+			state.ErrDataLimitExceededCode,
+			fmt.Sprintf("The %s of state '%s' has a size of %d bytes, exceeding the limit of %d bytes.", payloadType, stateTitle, len(payload), limit),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package sfn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+)
+
+const (
+	// DefaultMaxHistoryEvents is the AWS quota for the history events of a standard workflow execution
+	DefaultMaxHistoryEvents = 25000
+	// DefaultMaxExpressDuration is the AWS quota for the duration of an express workflow execution
+	DefaultMaxExpressDuration = 5 * time.Minute
+)
+
+// ExecutionQuotas are the quotas of an execution, which fails as it would in AWS when one is exceeded. A
+// quota of 0 isn't enforced.
+type ExecutionQuotas struct {
+	MaxHistoryEvents int
+	MaxDuration      time.Duration
+}
+
+// StandardQuotas returns the quotas AWS enforces for standard workflows
+func StandardQuotas() ExecutionQuotas {
+	return ExecutionQuotas{
+		MaxHistoryEvents: DefaultMaxHistoryEvents,
+	}
+}
+
+// ExpressQuotas returns the quotas AWS enforces for express workflows
+func ExpressQuotas() ExecutionQuotas {
+	return ExecutionQuotas{
+		MaxDuration: DefaultMaxExpressDuration,
+	}
+}
+
+// Clock tells the time of an execution. The duration of an execution is measured with its clock, which
+// can be a virtual one e.g. to emulate long running tasks in tests.
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the channel once the duration has elapsed, as time.After does e.g. to time
+	// out a task waiting for the outcome of its token
+	After(time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// stateHistoryEvents returns the number of history events AWS records for running a state, excluding the
+// events of the branches of a Parallel state
+func stateHistoryEvents(def state.Definition) int {
+	switch def.Type() {
+	case state.TaskStateType:
+		return 5 // entered, scheduled, started, succeeded and exited
+	case state.ParallelStateType:
+		return 4 // entered, started, succeeded and exited
+	case state.FailStateType:
+		return 1 // entered
+	}
+
+	return 2 // entered and exited
+}
+
+// remaining returns the quotas that remain of an execution e.g. for the branches of a Parallel state
+func (q ExecutionQuotas) remaining(exec *execution, now time.Time) ExecutionQuotas {
+	remaining := ExecutionQuotas{}
+
+	if q.MaxHistoryEvents > 0 {
+		remaining.MaxHistoryEvents = q.MaxHistoryEvents - exec.historyEvents
+		if remaining.MaxHistoryEvents < 1 {
+			remaining.MaxHistoryEvents = 1
+		}
+	}
+
+	if q.MaxDuration > 0 {
+		remaining.MaxDuration = q.MaxDuration - now.Sub(exec.start)
+		if remaining.MaxDuration < time.Nanosecond {
+			remaining.MaxDuration = time.Nanosecond
+		}
+	}
+
+	return remaining
+}
+
+// check fails an execution that has exceeded a quota. AWS fails an execution that exceeds its history
+// quota and times out one that exceeds its duration quota.
+func (q ExecutionQuotas) check(exec *execution, stateTitle string, now time.Time) error {
+	if q.MaxHistoryEvents > 0 && exec.historyEvents > q.MaxHistoryEvents {
+		return newStateFailure(
+			stateTitle,
+			state.NewError(state.ErrRuntimeCode, fmt.Sprintf("The execution has exceeded the maximum number of %d history events.", q.MaxHistoryEvents)),
+			state.ErrRuntimeCode,
+		)
+	}
+
+	if q.MaxDuration > 0 && now.Sub(exec.start) > q.MaxDuration {
+		failure := newStateFailure(
+			stateTitle,
+			state.NewError(state.ErrTimeoutCode, fmt.Sprintf("The execution has exceeded its maximum duration of %s.", q.MaxDuration)),
+			state.ErrTimeoutCode,
+		)
+		failure.status = ExecutionStatusTimedOut
+		return failure
+	}
+
+	return nil
+}
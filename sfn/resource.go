@@ -0,0 +1,134 @@
+package sfn
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/eggsbenjamin/stepFnLocal/lambda"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+)
+
+// LambdaFunctionResource is the pattern of lambda function ARNs
+const LambdaFunctionResource = "arn:aws:lambda:::function"
+
+// ErrUnsupportedResource is returned when creating a task whose resource has no handler
+var ErrUnsupportedResource = errors.New("unsupported task resource")
+
+// ResourceHandler creates the state of a task whose resource it handles
+type ResourceHandler func(def state.TaskDefinition, resource arn.ARN) (State, error)
+
+// ResourceRegistry holds the handlers of task resources, keyed by ARN pattern
+type ResourceRegistry interface {
+	Register(pattern string, handler ResourceHandler)
+	Handler(resource arn.ARN) (ResourceHandler, error)
+}
+
+type resourceRegistry struct {
+	handlers map[string]ResourceHandler
+}
+
+func NewResourceRegistry() ResourceRegistry {
+	return resourceRegistry{
+		handlers: map[string]ResourceHandler{},
+	}
+}
+
+// NewDefaultResourceRegistry returns a registry which invokes lambda functions, by ARN or with the
+// optimised lambda integration, with the lambda client
+func NewDefaultResourceRegistry(lambdaClient lambda.Client) ResourceRegistry {
+	registry := NewResourceRegistry()
+	registry.Register(LambdaFunctionResource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewLambdaTask(def, resource, lambdaClient), nil
+	})
+	registry.Register(LambdaInvokeResource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		if def.IntegrationPattern() == state.SyncIntegrationPattern {
+			return nil, errors.Wrapf(ErrUnsupportedResource, "unsupported integration pattern %s", def.Resource)
+		}
+		return NewLambdaInvokeTask(def, lambdaClient), nil
+	})
+
+	return registry
+}
+
+// Register registers the handler of the resources matching the pattern e.g.
+// arn:aws:states:::dynamodb:putItem. A handler handles every integration pattern of its resource.
+func (r resourceRegistry) Register(pattern string, handler ResourceHandler) {
+	if resource, err := arn.Parse(pattern); err == nil {
+		pattern = ResourcePattern(resource)
+	}
+
+	r.handlers[pattern] = handler
+}
+
+func (r resourceRegistry) Handler(resource arn.ARN) (ResourceHandler, error) {
+	handler, ok := r.handlers[ResourcePattern(resource)]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedResource, "no handler registered for %s", resource.String())
+	}
+
+	return handler, nil
+}
+
+// ResourcePattern returns the pattern of a resource ARN, which is its service and the type of resource
+// e.g. arn:aws:lambda:::function for a lambda function, or, for a service integration, its service and
+// action without the integration pattern e.g. arn:aws:states:::sqs:sendMessage.
+func ResourcePattern(resource arn.ARN) string {
+	resourceType := resource.Resource
+	if resource.AccountID != "" {
+		if i := strings.IndexAny(resourceType, ":/"); i >= 0 {
+			resourceType = resourceType[:i]
+		}
+	} else {
+		for _, suffix := range []string{state.SyncIntegrationPattern + ":2", state.SyncIntegrationPattern, state.WaitForTaskTokenIntegrationPattern} {
+			resourceType = strings.TrimSuffix(resourceType, suffix)
+		}
+	}
+
+	return "arn:aws:" + resource.Service + ":::" + resourceType
+}
+
+// sdkHttpMetadata and sdkResponseMetadata are the metadata of the response of a service integration
+type sdkHttpMetadata struct {
+	HttpHeaders    map[string]string `json:"HttpHeaders"`
+	HttpStatusCode int64             `json:"HttpStatusCode"`
+}
+
+type sdkResponseMetadata struct {
+	RequestId string `json:"RequestId"`
+}
+
+// newServiceClientError returns the error of a failed request to the service of an integration, which is
+// named after the exception of the service e.g. DynamoDB.ResourceNotFoundException, or
+// <service>.SdkClientException if the client failed without a response from the service
+func newServiceClientError(service string, err error) state.Error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return state.NewError(service+"."+awsErr.Code(), awsErr.Message())
+	}
+
+	return state.NewError(service+".SdkClientException", err.Error())
+}
+
+// stringifyParameter replaces the parameter of the input with its JSON text if it isn't a string, as
+// integrations do for messages and event details built from JSON e.g. {"MessageBody":{"id":1}}
+func stringifyParameter(input []byte, name string) ([]byte, error) {
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, err
+	}
+
+	value, ok := params[name]
+	if !ok || len(value) == 0 || value[0] == '"' || string(value) == "null" {
+		return input, nil
+	}
+
+	text, err := json.Marshal(string(value))
+	if err != nil {
+		return nil, err
+	}
+	params[name] = text
+
+	return json.Marshal(params)
+}
@@ -0,0 +1,109 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// newStepFunctionWithResources returns a step function of the definition, a machine's JSON, whose task
+// resources are handled by the integrations register registers
+func newStepFunctionWithResources(t *testing.T, def string, register func(sfn.ResourceRegistry)) sfn.StepFunction {
+	resources := sfn.NewResourceRegistry()
+	register(resources)
+
+	machine := state.MachineDefinition{}
+	require.NoError(t, json.Unmarshal([]byte(def), &machine))
+
+	fn, err := sfn.NewWithResources(machine, nil, resources)
+	require.NoError(t, err)
+
+	return fn
+}
+
+func TestResourcePattern(t *testing.T) {
+	tests := []struct {
+		title           string
+		resource        string
+		expectedPattern string
+	}{
+		{
+			"lambda function",
+			"arn:aws:lambda:us-east-1:123456789012:function:test",
+			"arn:aws:lambda:::function",
+		},
+		{
+			"lambda function version",
+			"arn:aws:lambda:us-east-1:123456789012:function:test:1",
+			"arn:aws:lambda:::function",
+		},
+		{
+			"activity",
+			"arn:aws:states:us-east-1:123456789012:activity:test",
+			"arn:aws:states:::activity",
+		},
+		{
+			"request response",
+			"arn:aws:states:::dynamodb:putItem",
+			"arn:aws:states:::dynamodb:putItem",
+		},
+		{
+			"sync",
+			"arn:aws:states:::states:startExecution.sync:2",
+			"arn:aws:states:::states:startExecution",
+		},
+		{
+			"wait for task token",
+			"arn:aws:states:::sqs:sendMessage.waitForTaskToken",
+			"arn:aws:states:::sqs:sendMessage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			resource, err := arn.Parse(tt.resource)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedPattern, sfn.ResourcePattern(resource))
+		})
+	}
+}
+
+func TestResourceRegistry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockState := sfn.NewMockState(ctrl)
+	registry := sfn.NewResourceRegistry()
+	registry.Register("arn:aws:states:::dynamodb:putItem", func(state.TaskDefinition, arn.ARN) (sfn.State, error) {
+		return mockState, nil
+	})
+	factory := sfn.NewStateFactoryWithResources(nil, registry)
+
+	t.Run("registered", func(t *testing.T) {
+		_state, err := factory.Create(state.TaskDefinition{Resource: "arn:aws:states:::dynamodb:putItem"})
+		require.NoError(t, err)
+		require.Equal(t, mockState, _state)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := factory.Create(state.TaskDefinition{Resource: "arn:aws:states:::dynamodb:getItem"})
+		require.Error(t, err)
+		require.Equal(t, sfn.ErrUnsupportedResource, errors.Cause(err))
+		require.Contains(t, err.Error(), "arn:aws:states:::dynamodb:getItem")
+	})
+
+	t.Run("not an arn", func(t *testing.T) {
+		_, err := factory.Create(state.TaskDefinition{Resource: "missing-override"})
+		require.Error(t, err)
+		require.Equal(t, sfn.ErrUnsupportedResource, errors.Cause(err))
+		require.Contains(t, err.Error(), "missing-override")
+	})
+}
@@ -4,13 +4,13 @@ package sfn
 
 import (
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -21,13 +21,29 @@ const (
 	ExecutionStatusAborted   = "ABORTED"
 )
 
+// ErrStateMachineTypeNotSupported is returned when starting a synchronous execution of a standard machine
+var ErrStateMachineTypeNotSupported = errors.New("StateMachineTypeNotSupported: synchronous executions are only supported by express state machines")
+
 // ExecutionResult represents the result of a state machine execution
 type ExecutionResult struct {
-	Input  []byte
-	Output []byte
-	Status string
-	Start  time.Time
-	End    time.Time
+	Input       []byte
+	Output      []byte
+	Status      string
+	Error       string        // states language error name of a failed execution
+	Cause       string        // cause of a failed execution
+	FailedState string        // name of the state a failed execution failed in
+	Payloads    PayloadReport // size of the largest payload seen by each state
+	// HistoryEvents and Transitions count the history events and state transitions of the execution,
+	// including those of Parallel branches
+	HistoryEvents int
+	Transitions   int
+	Start         time.Time
+	End           time.Time
+	// ExecutionARN names the execution. A nested execution is linked to the execution that started it by
+	// its ParentExecutionARN.
+	ExecutionARN       string
+	StateMachineARN    string
+	ParentExecutionARN string
 }
 
 // State defines the standard state API for state machine implementations
@@ -37,14 +53,67 @@ type State interface {
 	IsEnd() bool
 }
 
+// VariableState is implemented by states that read the variables of their scope while running e.g. a
+// Choice state whose rules reference $name
+type VariableState interface {
+	RunWithVariables([]byte, state.Variables) ([]byte, error)
+}
+
 type StepFunction interface {
 	StartExecution([]byte) (ExecutionResult, error)
+	// StartExecutionWithOptions starts a named execution e.g. a nested execution started by another
+	StartExecutionWithOptions([]byte, ExecutionOptions) (ExecutionResult, error)
+	// StartSyncExecution runs an express machine's execution once and returns its result, as the
+	// StartSyncExecution API does
+	StartSyncExecution([]byte) (ExecutionResult, error)
+	// StartExecutionWithVariables starts an execution with the variables of an outer scope e.g. as a
+	// Parallel branch
+	StartExecutionWithVariables([]byte, state.Variables) (ExecutionResult, error)
 	SetStateFactory(StateFactory)
+	SetPayloadLimits(PayloadLimits)
+	SetQuotas(ExecutionQuotas)
+	SetClock(Clock)
+	SetRedeliveries(int)
+	// SetTaskTokens sets the registry of the tokens of tasks with the .waitForTaskToken pattern e.g. to
+	// share it with activities
+	SetTaskTokens(*TaskTokens)
+	TaskTokens() *TaskTokens
+}
+
+// ExecutionOptions are the options of starting an execution. An execution is given a random name by
+// default.
+type ExecutionOptions struct {
+	Name               string
+	StateMachineARN    string
+	ParentExecutionARN string
+}
+
+// brancher is implemented by states which run state machines of their own e.g. the branches of a
+// Parallel state, which are subject to the limits and quotas of the execution
+type brancher interface {
+	Branches() []StepFunction
+	BranchResults() []ExecutionResult
 }
 
 type stepFunction struct {
 	stateMachineDef state.MachineDefinition
+	definitions     map[string]state.Definition
 	stateFactory    StateFactory
+	payloadLimits   PayloadLimits
+	quotas          ExecutionQuotas
+	clock           Clock
+	redeliveries    int
+	tokens          *TaskTokens
+}
+
+// execution is the runtime state of an execution of a state machine or of a Parallel branch
+type execution struct {
+	variables     state.Variables
+	payloads      PayloadReport
+	historyEvents int
+	transitions   int
+	start         time.Time
+	context       contextObject
 }
 
 func New(def state.MachineDefinition, overrides map[string]OverrideFn) (StepFunction, error) {
@@ -55,10 +124,7 @@ func New(def state.MachineDefinition, overrides map[string]OverrideFn) (StepFunc
 	lambdaClient := lambda.New(session.Must(session.NewSession(&aws.Config{})))
 	stateFactory := NewStateFactory(overrides, lambdaClient)
 
-	return &stepFunction{
-		stateMachineDef: def,
-		stateFactory:    stateFactory,
-	}, nil
+	return newStepFunction(def, stateFactory), nil
 }
 
 func NewWithAWSConfig(def state.MachineDefinition, overrides map[string]OverrideFn, awsCfg *aws.Config) (StepFunction, error) {
@@ -69,80 +135,347 @@ func NewWithAWSConfig(def state.MachineDefinition, overrides map[string]Override
 	lambdaClient := lambda.New(session.Must(session.NewSession(awsCfg)))
 	stateFactory := NewStateFactory(overrides, lambdaClient)
 
-	return &stepFunction{
+	return newStepFunction(def, stateFactory), nil
+}
+
+// NewWithResources returns a step function whose tasks are created with the handlers of the registry
+func NewWithResources(def state.MachineDefinition, overrides map[string]OverrideFn, resources ResourceRegistry) (StepFunction, error) {
+	if err := def.Validate(); err != nil {
+		return &stepFunction{}, err
+	}
+
+	return newStepFunction(def, NewStateFactoryWithResources(overrides, resources)), nil
+}
+
+// newStepFunction returns a step function of the machine's workflow type. Express machines enforce the
+// express quotas by default.
+func newStepFunction(def state.MachineDefinition, stateFactory StateFactory) *stepFunction {
+	s := &stepFunction{
 		stateMachineDef: def,
+		definitions:     map[string]state.Definition{},
 		stateFactory:    stateFactory,
-	}, nil
+		clock:           systemClock{},
+		tokens:          NewTaskTokens(),
+	}
+
+	// the states are parsed, and their paths and expressions compiled, once rather than each time they're
+	// run. A state that can't be parsed fails when it's run.
+	for name := range def.States {
+		if stateDef, err := def.GetDefinition(name); err == nil {
+			s.definitions[name] = stateDef
+		}
+	}
+
+	if def.IsExpress() {
+		s.quotas = ExpressQuotas()
+		// the machine's TimeoutSeconds, which can't exceed the quota, shortens the maximum duration
+		if def.TimeoutSeconds > 0 {
+			s.quotas.MaxDuration = time.Duration(def.TimeoutSeconds) * time.Second
+		}
+	}
+
+	return s
 }
 
+// StartExecution runs an execution of the machine. An express machine's execution is run again for each
+// redelivery, as an asynchronous express execution is run at least once, and the result of the last run
+// returned.
 func (s *stepFunction) StartExecution(input []byte) (ExecutionResult, error) {
-	start := time.Now()
+	return s.StartExecutionWithOptions(input, ExecutionOptions{})
+}
+
+func (s *stepFunction) StartExecutionWithOptions(input []byte, opts ExecutionOptions) (ExecutionResult, error) {
+	if s.stateMachineDef.IsExpress() {
+		for i := 0; i < s.redeliveries; i++ {
+			s.execute(input, state.Variables{}, 2, opts)
+		}
+	}
+
+	// the ExecutionStarted event and the event the execution ends with
+	return s.execute(input, state.Variables{}, 2, opts)
+}
+
+// StartSyncExecution runs an execution of an express machine at most once
+func (s *stepFunction) StartSyncExecution(input []byte) (ExecutionResult, error) {
+	if !s.stateMachineDef.IsExpress() {
+		return ExecutionResult{}, ErrStateMachineTypeNotSupported
+	}
+
+	return s.execute(input, state.Variables{}, 2, ExecutionOptions{})
+}
+
+func (s *stepFunction) StartExecutionWithVariables(input []byte, variables state.Variables) (ExecutionResult, error) {
+	return s.execute(input, variables, 0, ExecutionOptions{})
+}
+
+func (s *stepFunction) execute(input []byte, variables state.Variables, executionEvents int, opts ExecutionOptions) (ExecutionResult, error) {
+	exec := &execution{
+		variables:     variables.Copy(),
+		payloads:      PayloadReport{},
+		historyEvents: executionEvents,
+		start:         s.clock.Now(),
+	}
+
+	// a Parallel branch runs in the context of the execution of its state
+	if err := json.Unmarshal(variables.Context(), &exec.context); err != nil {
+		if opts.StateMachineARN == "" {
+			opts.StateMachineARN = defaultStateMachineARN
+		}
+		exec.context.Execution = newContextExecution(input, exec.start, opts)
+	}
+
+	result := ExecutionResult{
+		ExecutionARN:       exec.context.Execution.ID,
+		StateMachineARN:    opts.StateMachineARN,
+		ParentExecutionARN: opts.ParentExecutionARN,
+		Input:              input,
+		Status:             ExecutionStatusSucceeded,
+		Start:              exec.start,
+		Payloads:           exec.payloads,
+	}
+
+	output, err := s.run(s.stateMachineDef.StartAt, input, exec)
+	result.HistoryEvents = exec.historyEvents
+	result.Transitions = exec.transitions
+	result.End = s.clock.Now()
 
-	status := ExecutionStatusSucceeded
-	output, err := s.run(s.stateMachineDef.StartAt, input)
 	if err != nil {
-		status = ExecutionStatusFailed
+		failure, ok := err.(stateFailure)
+		if !ok {
+			failure = newStateFailure(s.stateMachineDef.StartAt, err, state.ErrRuntimeCode)
+		}
+
+		result.Status = failure.status
+		result.Error = failure.err.Name
+		result.Cause = string(failure.err.Cause)
+		result.FailedState = failure.stateTitle
+		return result, failure.err
 	}
 
-	return ExecutionResult{
-		Input:  input,
-		Output: output,
-		Status: status,
-		Start:  start,
-		End:    time.Now(),
-	}, err
+	result.Output = output
+	return result, nil
 }
 
 func (s *stepFunction) SetStateFactory(stateFactory StateFactory) {
 	s.stateFactory = stateFactory
 }
 
-func (r stepFunction) run(stateTitle string, input json.RawMessage) ([]byte, error) {
-	fmt.Printf("running state: %s\n", stateTitle)
-	def, err := r.stateMachineDef.States.GetDefinition(stateTitle)
+// SetPayloadLimits enables the enforcement of payload limits, which are disabled by default
+func (s *stepFunction) SetPayloadLimits(payloadLimits PayloadLimits) {
+	s.payloadLimits = payloadLimits
+}
+
+// SetQuotas enables the enforcement of execution quotas, which are disabled by default
+func (s *stepFunction) SetQuotas(quotas ExecutionQuotas) {
+	s.quotas = quotas
+}
+
+// SetClock sets the clock executions are timed with
+func (s *stepFunction) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// SetTaskTokens sets the registry the tokens of tasks with the .waitForTaskToken pattern are sent to
+func (s *stepFunction) SetTaskTokens(tokens *TaskTokens) {
+	s.tokens = tokens
+}
+
+// TaskTokens returns the registry of the tokens of tasks with the .waitForTaskToken pattern, which pause
+// the execution until their outcome is sent
+func (s *stepFunction) TaskTokens() *TaskTokens {
+	return s.tokens
+}
+
+// SetRedeliveries sets the number of times an express machine's asynchronous executions are run again,
+// to test that its tasks are idempotent
+func (s *stepFunction) SetRedeliveries(redeliveries int) {
+	s.redeliveries = redeliveries
+}
+
+// definition returns the definition of a state, which was parsed when the step function was created
+func (r stepFunction) definition(stateTitle string) (state.Definition, error) {
+	if def, ok := r.definitions[stateTitle]; ok {
+		return def, nil
+	}
+
+	return r.stateMachineDef.GetDefinition(stateTitle)
+}
+
+func (r stepFunction) run(stateTitle string, input json.RawMessage, exec *execution) ([]byte, error) {
+	def, err := r.definition(stateTitle)
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, newStateFailure(stateTitle, errors.Wrapf(err, "error getting state definition for %s", stateTitle), state.ErrRuntimeCode)
 	}
 
 	_state, err := r.stateFactory.Create(def)
 	if err != nil {
+		return []byte{}, newStateFailure(stateTitle, errors.Wrapf(err, "error creating state %s", stateTitle), state.ErrRuntimeCode)
+	}
+
+	if taskDef, ok := def.(state.TaskDefinition); ok && taskDef.IntegrationPattern() == state.WaitForTaskTokenIntegrationPattern {
+		_state = NewWaitForTaskTokenTask(taskDef, _state, r.tokens, r.clock)
+	}
+
+	exec.historyEvents += stateHistoryEvents(def)
+	exec.transitions++
+	if err := r.quotas.check(exec, stateTitle, r.clock.Now()); err != nil {
 		return []byte{}, err
 	}
 
-	if v, ok := def.(state.InputPather); ok {
-		input, err = v.InputPath().Search(input)
-		if err != nil {
-			return []byte{}, err
+	branches, hasBranches := _state.(brancher)
+	if hasBranches {
+		quotas := r.quotas.remaining(exec, r.clock.Now())
+		for _, branch := range branches.Branches() {
+			branch.SetPayloadLimits(r.payloadLimits)
+			branch.SetQuotas(quotas)
+			branch.SetClock(r.clock)
+			branch.SetTaskTokens(r.tokens)
 		}
 	}
 
-	output, err := _state.Run(input)
+	payloads, variables := exec.payloads, exec.variables
+
+	if err := checkPayload(payloads, stateTitle, "input", input, r.payloadLimits.StateInputOutput); err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, state.ErrDataLimitExceededCode)
+	}
+
+	if err := setContext(exec, stateTitle, _state, r.clock.Now()); err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, state.ErrRuntimeCode)
+	}
+
+	processor := state.NewIOProcessor(def, r.stateMachineDef.StateQueryLanguage(def), variables)
+
+	effectiveInput, err := processor.ProcessInput(input)
 	if err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, state.ErrRuntimeCode)
+	}
+
+	isTask := def.Type() == state.TaskStateType
+	if isTask {
+		if err := checkPayload(payloads, stateTitle, "task input", effectiveInput, r.payloadLimits.Task); err != nil {
+			return []byte{}, newStateFailure(stateTitle, err, state.ErrDataLimitExceededCode)
+		}
+	}
+
+	result, err := runState(_state, effectiveInput, variables)
+	if hasBranches {
+		for _, branchResult := range branches.BranchResults() {
+			payloads.merge(branchResult.Payloads)
+			exec.historyEvents += branchResult.HistoryEvents
+			exec.transitions += branchResult.Transitions
+		}
+	}
+
+	// a quota exceeded while the state ran, e.g. by a long running task, takes precedence over the
+	// state's own error
+	if err := r.quotas.check(exec, stateTitle, r.clock.Now()); err != nil {
 		return []byte{}, err
 	}
 
-	/*
-		TODO: add support for ResultPath
+	if err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, runErrCode(def))
+	}
 
-		ResultPath modifies the output of a state using jsonpath.
+	if isTask {
+		if err := checkPayload(payloads, stateTitle, "task result", result, r.payloadLimits.Task); err != nil {
+			return []byte{}, newStateFailure(stateTitle, err, state.ErrDataLimitExceededCode)
+		}
+	}
 
-		e.g.
+	output, selected, err := processor.ProcessOutput(input, result)
+	if err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, state.ErrRuntimeCode)
+	}
 
-		output = {"hello":"world"}
-		ResultPath = "$.test"
-		modified = {"test":{"hello":"world"}}
-	*/
+	if err := checkPayload(payloads, stateTitle, "output", output, r.payloadLimits.StateInputOutput); err != nil {
+		return []byte{}, newStateFailure(stateTitle, err, state.ErrDataLimitExceededCode)
+	}
 
-	if v, ok := def.(state.OutputPather); ok {
-		output, err = v.OutputPath().Search(output)
+	// variables are assigned once the state has completed, so every value is evaluated against the
+	// variables as they were while it ran
+	if assign := assignTemplate(_state, def); !assign.IsEmpty() {
+		assigned, err := processor.ProcessAssign(assign, input, selected)
 		if err != nil {
-			return []byte{}, err
+			return []byte{}, newStateFailure(stateTitle, err, state.ErrRuntimeCode)
 		}
+		variables.Set(assigned)
 	}
 
 	if _state.IsEnd() {
 		return output, nil
 	}
 
-	return r.run(_state.Next(), output)
+	return r.run(_state.Next(), output, exec)
+}
+
+// setContext sets the context object of the state entered, which its paths reference as $$
+func setContext(exec *execution, stateTitle string, _state State, entered time.Time) error {
+	exec.context.State = contextState{
+		EnteredTime: formatContextTime(entered),
+		Name:        stateTitle,
+	}
+
+	exec.context.Task = nil
+	if v, ok := _state.(TaskTokenState); ok {
+		exec.context.Task = &contextTask{Token: v.TaskToken()}
+	}
+
+	context, err := json.Marshal(exec.context)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling context object")
+	}
+
+	exec.variables.SetContext(context)
+	return nil
+}
+
+func runState(_state State, input []byte, variables state.Variables) ([]byte, error) {
+	if v, ok := _state.(VariableState); ok {
+		return v.RunWithVariables(input, variables)
+	}
+
+	return _state.Run(input)
+}
+
+// assignTemplate returns the Assign of a state. A state that decides what to assign while running, e.g. a
+// Choice state assigning the variables of the rule that matched, takes precedence over its definition.
+func assignTemplate(_state State, def state.Definition) state.AssignTemplate {
+	if v, ok := _state.(state.Assigner); ok {
+		return v.Assign()
+	}
+
+	if v, ok := def.(state.Assigner); ok {
+		return v.Assign()
+	}
+
+	return state.AssignTemplate{}
+}
+
+// runErrCode returns the states language error code reported when running a state of the given
+// definition fails with an error that is not already a states language error.
+func runErrCode(def state.Definition) string {
+	if def.Type() == state.TaskStateType {
+		return state.ErrTaskFailedCode
+	}
+
+	return state.ErrRuntimeCode
+}
+
+// stateFailure records the state an execution failed in alongside the states language error it failed with.
+type stateFailure struct {
+	stateTitle string
+	err        state.Error
+	status     string // status of the execution, failed unless it timed out
+}
+
+func newStateFailure(stateTitle string, err error, code string) stateFailure {
+	return stateFailure{
+		stateTitle: stateTitle,
+		err:        state.AsError(err, code),
+		status:     ExecutionStatusFailed,
+	}
+}
+
+func (s stateFailure) Error() string {
+	return s.err.Error()
 }
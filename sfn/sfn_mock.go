@@ -5,6 +5,7 @@
 package sfn
 
 import (
+	state "github.com/eggsbenjamin/stepFnLocal/state"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -69,6 +70,42 @@ func (mr *MockStateMockRecorder) IsEnd() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEnd", reflect.TypeOf((*MockState)(nil).IsEnd))
 }
 
+// MockVariableState is a mock of VariableState interface
+type MockVariableState struct {
+	ctrl     *gomock.Controller
+	recorder *MockVariableStateMockRecorder
+}
+
+// MockVariableStateMockRecorder is the mock recorder for MockVariableState
+type MockVariableStateMockRecorder struct {
+	mock *MockVariableState
+}
+
+// NewMockVariableState creates a new mock instance
+func NewMockVariableState(ctrl *gomock.Controller) *MockVariableState {
+	mock := &MockVariableState{ctrl: ctrl}
+	mock.recorder = &MockVariableStateMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVariableState) EXPECT() *MockVariableStateMockRecorder {
+	return m.recorder
+}
+
+// RunWithVariables mocks base method
+func (m *MockVariableState) RunWithVariables(arg0 []byte, arg1 state.Variables) ([]byte, error) {
+	ret := m.ctrl.Call(m, "RunWithVariables", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunWithVariables indicates an expected call of RunWithVariables
+func (mr *MockVariableStateMockRecorder) RunWithVariables(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunWithVariables", reflect.TypeOf((*MockVariableState)(nil).RunWithVariables), arg0, arg1)
+}
+
 // MockStepFunction is a mock of StepFunction interface
 type MockStepFunction struct {
 	ctrl     *gomock.Controller
@@ -105,6 +142,45 @@ func (mr *MockStepFunctionMockRecorder) StartExecution(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartExecution", reflect.TypeOf((*MockStepFunction)(nil).StartExecution), arg0)
 }
 
+// StartExecutionWithOptions mocks base method
+func (m *MockStepFunction) StartExecutionWithOptions(arg0 []byte, arg1 ExecutionOptions) (ExecutionResult, error) {
+	ret := m.ctrl.Call(m, "StartExecutionWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(ExecutionResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartExecutionWithOptions indicates an expected call of StartExecutionWithOptions
+func (mr *MockStepFunctionMockRecorder) StartExecutionWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartExecutionWithOptions", reflect.TypeOf((*MockStepFunction)(nil).StartExecutionWithOptions), arg0, arg1)
+}
+
+// StartSyncExecution mocks base method
+func (m *MockStepFunction) StartSyncExecution(arg0 []byte) (ExecutionResult, error) {
+	ret := m.ctrl.Call(m, "StartSyncExecution", arg0)
+	ret0, _ := ret[0].(ExecutionResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartSyncExecution indicates an expected call of StartSyncExecution
+func (mr *MockStepFunctionMockRecorder) StartSyncExecution(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSyncExecution", reflect.TypeOf((*MockStepFunction)(nil).StartSyncExecution), arg0)
+}
+
+// StartExecutionWithVariables mocks base method
+func (m *MockStepFunction) StartExecutionWithVariables(arg0 []byte, arg1 state.Variables) (ExecutionResult, error) {
+	ret := m.ctrl.Call(m, "StartExecutionWithVariables", arg0, arg1)
+	ret0, _ := ret[0].(ExecutionResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartExecutionWithVariables indicates an expected call of StartExecutionWithVariables
+func (mr *MockStepFunctionMockRecorder) StartExecutionWithVariables(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartExecutionWithVariables", reflect.TypeOf((*MockStepFunction)(nil).StartExecutionWithVariables), arg0, arg1)
+}
+
 // SetStateFactory mocks base method
 func (m *MockStepFunction) SetStateFactory(arg0 StateFactory) {
 	m.ctrl.Call(m, "SetStateFactory", arg0)
@@ -114,3 +190,112 @@ func (m *MockStepFunction) SetStateFactory(arg0 StateFactory) {
 func (mr *MockStepFunctionMockRecorder) SetStateFactory(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStateFactory", reflect.TypeOf((*MockStepFunction)(nil).SetStateFactory), arg0)
 }
+
+// SetPayloadLimits mocks base method
+func (m *MockStepFunction) SetPayloadLimits(arg0 PayloadLimits) {
+	m.ctrl.Call(m, "SetPayloadLimits", arg0)
+}
+
+// SetPayloadLimits indicates an expected call of SetPayloadLimits
+func (mr *MockStepFunctionMockRecorder) SetPayloadLimits(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPayloadLimits", reflect.TypeOf((*MockStepFunction)(nil).SetPayloadLimits), arg0)
+}
+
+// SetQuotas mocks base method
+func (m *MockStepFunction) SetQuotas(arg0 ExecutionQuotas) {
+	m.ctrl.Call(m, "SetQuotas", arg0)
+}
+
+// SetQuotas indicates an expected call of SetQuotas
+func (mr *MockStepFunctionMockRecorder) SetQuotas(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuotas", reflect.TypeOf((*MockStepFunction)(nil).SetQuotas), arg0)
+}
+
+// SetClock mocks base method
+func (m *MockStepFunction) SetClock(arg0 Clock) {
+	m.ctrl.Call(m, "SetClock", arg0)
+}
+
+// SetClock indicates an expected call of SetClock
+func (mr *MockStepFunctionMockRecorder) SetClock(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetClock", reflect.TypeOf((*MockStepFunction)(nil).SetClock), arg0)
+}
+
+// SetRedeliveries mocks base method
+func (m *MockStepFunction) SetRedeliveries(arg0 int) {
+	m.ctrl.Call(m, "SetRedeliveries", arg0)
+}
+
+// SetRedeliveries indicates an expected call of SetRedeliveries
+func (mr *MockStepFunctionMockRecorder) SetRedeliveries(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRedeliveries", reflect.TypeOf((*MockStepFunction)(nil).SetRedeliveries), arg0)
+}
+
+// SetTaskTokens mocks base method
+func (m *MockStepFunction) SetTaskTokens(arg0 *TaskTokens) {
+	m.ctrl.Call(m, "SetTaskTokens", arg0)
+}
+
+// SetTaskTokens indicates an expected call of SetTaskTokens
+func (mr *MockStepFunctionMockRecorder) SetTaskTokens(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTaskTokens", reflect.TypeOf((*MockStepFunction)(nil).SetTaskTokens), arg0)
+}
+
+// TaskTokens mocks base method
+func (m *MockStepFunction) TaskTokens() *TaskTokens {
+	ret := m.ctrl.Call(m, "TaskTokens")
+	ret0, _ := ret[0].(*TaskTokens)
+	return ret0
+}
+
+// TaskTokens indicates an expected call of TaskTokens
+func (mr *MockStepFunctionMockRecorder) TaskTokens() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TaskTokens", reflect.TypeOf((*MockStepFunction)(nil).TaskTokens))
+}
+
+// Mockbrancher is a mock of brancher interface
+type Mockbrancher struct {
+	ctrl     *gomock.Controller
+	recorder *MockbrancherMockRecorder
+}
+
+// MockbrancherMockRecorder is the mock recorder for Mockbrancher
+type MockbrancherMockRecorder struct {
+	mock *Mockbrancher
+}
+
+// NewMockbrancher creates a new mock instance
+func NewMockbrancher(ctrl *gomock.Controller) *Mockbrancher {
+	mock := &Mockbrancher{ctrl: ctrl}
+	mock.recorder = &MockbrancherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *Mockbrancher) EXPECT() *MockbrancherMockRecorder {
+	return m.recorder
+}
+
+// Branches mocks base method
+func (m *Mockbrancher) Branches() []StepFunction {
+	ret := m.ctrl.Call(m, "Branches")
+	ret0, _ := ret[0].([]StepFunction)
+	return ret0
+}
+
+// Branches indicates an expected call of Branches
+func (mr *MockbrancherMockRecorder) Branches() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Branches", reflect.TypeOf((*Mockbrancher)(nil).Branches))
+}
+
+// BranchResults mocks base method
+func (m *Mockbrancher) BranchResults() []ExecutionResult {
+	ret := m.ctrl.Call(m, "BranchResults")
+	ret0, _ := ret[0].([]ExecutionResult)
+	return ret0
+}
+
+// BranchResults indicates an expected call of BranchResults
+func (mr *MockbrancherMockRecorder) BranchResults() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BranchResults", reflect.TypeOf((*Mockbrancher)(nil).BranchResults))
+}
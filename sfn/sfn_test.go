@@ -3,11 +3,15 @@
 package sfn_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eggsbenjamin/stepFnLocal/sfn"
 	"github.com/eggsbenjamin/stepFnLocal/state"
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,5 +66,663 @@ func TestStepFunction(t *testing.T) {
 			require.Equal(t, expectedResult.Status, result.Status)
 			ctrl.Finish()
 		})
+
+		t.Run("errors", func(t *testing.T) {
+			dummyErr := errors.New("error")
+
+			tests := []struct {
+				title               string
+				def                 state.MachineDefinition
+				setup               func(*sfn.MockStateFactory, *sfn.MockState)
+				expectedErr         state.Error
+				expectedFailedState string
+			}{
+				{
+					"state not found",
+					state.MachineDefinition{
+						StartAt: "test1",
+						States: state.MachineStates{
+							"test1": []byte(`{"Type":"Pass","Next":"test2"}`),
+							"test2": []byte(`{"Type":"Succeed"}`),
+						},
+					},
+					func(mockStateFactory *sfn.MockStateFactory, mockState *sfn.MockState) {
+						gomock.InOrder(
+							mockStateFactory.EXPECT().Create(gomock.Any()).Return(mockState, nil),
+							mockState.EXPECT().Run(gomock.Any()).Return([]byte(`{}`), nil),
+							mockState.EXPECT().IsEnd().Return(false),
+							mockState.EXPECT().Next().Return("unknown"),
+						)
+					},
+					state.NewError(state.ErrRuntimeCode, "error getting state definition for unknown: state not found"),
+					"unknown",
+				},
+				{
+					"task error",
+					state.MachineDefinition{
+						StartAt: "test1",
+						States: state.MachineStates{
+							"test1": []byte(`{"Type":"Task","End":true,"Resource":"arn:aws:lambda:eu-west-1:12345678:function:dummy"}`),
+						},
+					},
+					func(mockStateFactory *sfn.MockStateFactory, mockState *sfn.MockState) {
+						gomock.InOrder(
+							mockStateFactory.EXPECT().Create(gomock.Any()).Return(mockState, nil),
+							mockState.EXPECT().Run(gomock.Any()).Return(nil, dummyErr),
+						)
+					},
+					state.NewError(state.ErrTaskFailedCode, dummyErr.Error()),
+					"test1",
+				},
+				{
+					"non task error",
+					state.MachineDefinition{
+						StartAt: "test1",
+						States: state.MachineStates{
+							"test1": []byte(`{"Type":"Pass","End":true}`),
+						},
+					},
+					func(mockStateFactory *sfn.MockStateFactory, mockState *sfn.MockState) {
+						gomock.InOrder(
+							mockStateFactory.EXPECT().Create(gomock.Any()).Return(mockState, nil),
+							mockState.EXPECT().Run(gomock.Any()).Return(nil, dummyErr),
+						)
+					},
+					state.NewError(state.ErrRuntimeCode, dummyErr.Error()),
+					"test1",
+				},
+				{
+					"states language error",
+					state.MachineDefinition{
+						StartAt: "test1",
+						States: state.MachineStates{
+							"test1": []byte(`{"Type":"Task","End":true,"Resource":"arn:aws:lambda:eu-west-1:12345678:function:dummy"}`),
+						},
+					},
+					func(mockStateFactory *sfn.MockStateFactory, mockState *sfn.MockState) {
+						gomock.InOrder(
+							mockStateFactory.EXPECT().Create(gomock.Any()).Return(mockState, nil),
+							mockState.EXPECT().Run(gomock.Any()).Return(nil, state.NewError(state.ErrTimeoutCode, "timeout")),
+						)
+					},
+					state.NewError(state.ErrTimeoutCode, "timeout"),
+					"test1",
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					ctrl := gomock.NewController(t)
+					mockState := sfn.NewMockState(ctrl)
+					mockStateFactory := sfn.NewMockStateFactory(ctrl)
+					tt.setup(mockStateFactory, mockState)
+
+					fn, err := sfn.New(tt.def, nil)
+					require.NoError(t, err)
+
+					fn.SetStateFactory(mockStateFactory)
+
+					result, err := fn.StartExecution([]byte(`{}`))
+					require.Equal(t, tt.expectedErr, err)
+					require.Equal(t, sfn.ExecutionStatusFailed, result.Status)
+					require.Equal(t, tt.expectedErr.Name, result.Error)
+					require.Equal(t, string(tt.expectedErr.Cause), result.Cause)
+					require.Equal(t, tt.expectedFailedState, result.FailedState)
+					require.Empty(t, result.Output)
+					ctrl.Finish()
+				})
+			}
+		})
+
+		t.Run("parallel branch fail state", func(t *testing.T) {
+			def := state.MachineDefinition{
+				StartAt: "parallel",
+				States: state.MachineStates{
+					"parallel": []byte(`{
+						"Type": "Parallel",
+						"End": true,
+						"Branches": [
+							{
+								"StartAt": "succeed",
+								"States": {"succeed": {"Type": "Succeed"}}
+							},
+							{
+								"StartAt": "fail",
+								"States": {"fail": {"Type": "Fail", "Error": "Custom.Error", "Cause": "branch failed"}}
+							}
+						]
+					}`),
+				},
+			}
+
+			fn, err := sfn.New(def, nil)
+			require.NoError(t, err)
+
+			result, err := fn.StartExecution([]byte(`{}`))
+			require.Equal(t, state.NewError("Custom.Error", "branch failed"), err)
+			require.Equal(t, sfn.ExecutionStatusFailed, result.Status)
+			require.Equal(t, "Custom.Error", result.Error)
+			require.Equal(t, "branch failed", result.Cause)
+			require.Equal(t, "parallel", result.FailedState)
+		})
+
+		t.Run("pass state input and output processing", func(t *testing.T) {
+			tests := []struct {
+				title          string
+				passState      string
+				input          string
+				expectedOutput string
+			}{
+				{
+					"Result replaces input",
+					`{"Type":"Pass","End":true,"Result":{"result":1}}`,
+					`{"input":1}`,
+					`{"result":1}`,
+				},
+				{
+					"Result at ResultPath",
+					`{"Type":"Pass","End":true,"Result":{"result":1},"ResultPath":"$.nested.result"}`,
+					`{"input":1}`,
+					`{"input":1,"nested":{"result":{"result":1}}}`,
+				},
+				{
+					"null ResultPath discards result",
+					`{"Type":"Pass","End":true,"Result":{"result":1},"ResultPath":null}`,
+					`{"input":1}`,
+					`{"input":1}`,
+				},
+				{
+					"ResultPath is applied to the raw input",
+					`{"Type":"Pass","End":true,"InputPath":"$.input","ResultPath":"$.result"}`,
+					`{"input":{"value":1}}`,
+					`{"input":{"value":1},"result":{"value":1}}`,
+				},
+				{
+					"Parameters",
+					`{"Type":"Pass","End":true,"Parameters":{"static":"value","name.$":"$.name","greeting.$":"States.Format('hello {}', $.name)","nested":{"id.$":"$.id"}}}`,
+					`{"name":"world","id":1}`,
+					`{"static":"value","name":"world","greeting":"hello world","nested":{"id":1}}`,
+				},
+				{
+					"Parameters after InputPath",
+					`{"Type":"Pass","End":true,"InputPath":"$.user","Parameters":{"name.$":"$.name"},"ResultPath":"$.user"}`,
+					`{"user":{"name":"world","age":30}}`,
+					`{"user":{"name":"world"}}`,
+				},
+				{
+					"null InputPath",
+					`{"Type":"Pass","End":true,"InputPath":null}`,
+					`{"input":1}`,
+					`{}`,
+				},
+				{
+					"OutputPath",
+					`{"Type":"Pass","End":true,"Result":"value","ResultPath":"$.result","OutputPath":"$.result"}`,
+					`{"input":1}`,
+					`"value"`,
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					def := state.MachineDefinition{
+						StartAt: "pass",
+						States: state.MachineStates{
+							"pass": []byte(tt.passState),
+						},
+					}
+
+					fn, err := sfn.New(def, nil)
+					require.NoError(t, err)
+
+					result, err := fn.StartExecution([]byte(tt.input))
+					require.NoError(t, err)
+					require.JSONEq(t, tt.expectedOutput, string(result.Output))
+				})
+			}
+		})
+
+		t.Run("pass state processing errors", func(t *testing.T) {
+			tests := []struct {
+				title        string
+				passState    string
+				expectedName string
+			}{
+				{
+					"Parameters path not found",
+					`{"Type":"Pass","End":true,"Parameters":{"name.$":"$.missing"}}`,
+					state.ErrParameterPathFailureCode,
+				},
+				{
+					"Parameters intrinsic function failure",
+					`{"Type":"Pass","End":true,"Parameters":{"item.$":"States.ArrayGetItem($.items, 5)"}}`,
+					state.ErrIntrinsicFailureCode,
+				},
+				{
+					"ResultPath through non object",
+					`{"Type":"Pass","End":true,"ResultPath":"$.name.result"}`,
+					state.ErrResultPathMatchFailureCode,
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					def := state.MachineDefinition{
+						StartAt: "pass",
+						States: state.MachineStates{
+							"pass": []byte(tt.passState),
+						},
+					}
+
+					fn, err := sfn.New(def, nil)
+					require.NoError(t, err)
+
+					result, err := fn.StartExecution([]byte(`{"name":"world","items":[]}`))
+					require.Error(t, err)
+					require.Equal(t, tt.expectedName, result.Error)
+				})
+			}
+		})
+
+		t.Run("JSONata", func(t *testing.T) {
+			def := state.MachineDefinition{
+				StartAt:       "pass",
+				QueryLanguage: state.JSONataQueryLanguage,
+				States: state.MachineStates{
+					"pass":   []byte(`{"Type":"Pass","Next":"choice","Output":{"total":"{% $sum($states.input.items.price) %}","code":"{% $states.input.code %}"}}`),
+					"choice": []byte(`{"Type":"Choice","Choices":[{"Condition":"{% $states.input.total > 10 %}","Next":"large"}],"Default":"fail"}`),
+					"large":  []byte(`{"Type":"Pass","End":true,"Output":{"size":"large","total":"{% $states.input.total %}"}}`),
+					"fail":   []byte(`{"Type":"Fail","Error":"{% 'Custom.' & $states.input.code %}","Cause":"{% 'total ' & $states.input.total %}"}`),
+				},
+			}
+
+			fn, err := sfn.New(def, nil)
+			require.NoError(t, err)
+
+			result, err := fn.StartExecution([]byte(`{"code":"Small","items":[{"price":5},{"price":6}]}`))
+			require.NoError(t, err)
+			require.JSONEq(t, `{"size":"large","total":11}`, string(result.Output))
+
+			result, err = fn.StartExecution([]byte(`{"code":"Small","items":[{"price":5}]}`))
+			require.Error(t, err)
+			require.Equal(t, "Custom.Small", result.Error)
+			require.Equal(t, "total 5", result.Cause)
+			require.Equal(t, "fail", result.FailedState)
+
+			result, err = fn.StartExecution([]byte(`{"code":"Small","items":[{"price":"5"}]}`))
+			require.Error(t, err)
+			require.Equal(t, state.ErrQueryEvaluationErrorCode, result.Error)
+			require.Equal(t, "pass", result.FailedState)
+		})
+
+		t.Run("variables", func(t *testing.T) {
+			def := state.MachineDefinition{
+				StartAt: "init",
+				States: state.MachineStates{
+					"init":  []byte(`{"Type":"Pass","Next":"check","Result":{"limit":10},"Assign":{"limit.$":"$.limit","size":"unknown"}}`),
+					"check": []byte(`{"Type":"Choice","Choices":[{"Variable":"$limit","NumericGreaterThan":5,"Next":"branches","Assign":{"size":"large"}}],"Default":"fail","Assign":{"size":"small"}}`),
+					"branches": []byte(`{"Type":"Parallel","Next":"final","Branches":[
+						{"StartAt":"read","States":{"read":{"Type":"Pass","End":true,"Parameters":{"limit.$":"$limit"},"Assign":{"size":"branch","inner":1}}}},
+						{"StartAt":"read","States":{"read":{"Type":"Pass","End":true,"Parameters":{"size.$":"$size"}}}}
+					]}`),
+					"final": []byte(`{"Type":"Pass","End":true,"Parameters":{"size.$":"$size","branches.$":"$"},"Assign":{"size":"final"}}`),
+					"fail":  []byte(`{"Type":"Fail","ErrorPath":"States.Format('Too.{}', $size)","Cause":"limit too low"}`),
+				},
+			}
+
+			fn, err := sfn.New(def, nil)
+			require.NoError(t, err)
+
+			// variables assigned by a branch aren't visible to the outer scope or the other branches, and
+			// a state's own assignments only apply once it has completed
+			result, err := fn.StartExecution([]byte(`{}`))
+			require.NoError(t, err)
+			require.JSONEq(t, `{"size":"large","branches":[{"limit":10},{"size":"large"}]}`, string(result.Output))
+
+			def.States["init"] = []byte(`{"Type":"Pass","Next":"check","Result":{"limit":1},"Assign":{"limit.$":"$.limit"}}`)
+			fn, err = sfn.New(def, nil)
+			require.NoError(t, err)
+
+			result, err = fn.StartExecution([]byte(`{}`))
+			require.Error(t, err)
+			require.Equal(t, "Too.small", result.Error)
+
+			t.Run("selected result", func(t *testing.T) {
+				def := state.MachineDefinition{
+					StartAt: "task",
+					States: state.MachineStates{
+						"task":  []byte(`{"Type":"Task","Resource":"test","Next":"final","ResultSelector":{"id.$":"States.UUID()"},"Assign":{"id.$":"$.id"}}`),
+						"final": []byte(`{"Type":"Pass","End":true,"Parameters":{"assigned.$":"$id","selected.$":"$.id"}}`),
+					},
+				}
+				overrides := map[string]sfn.OverrideFn{
+					"test": func([]byte) ([]byte, error) {
+						return []byte(`{}`), nil
+					},
+				}
+
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+
+				// Assign is evaluated against the result selected for the output rather than selecting it again
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+
+				var output struct {
+					Assigned string `json:"assigned"`
+					Selected string `json:"selected"`
+				}
+				require.NoError(t, json.Unmarshal(result.Output, &output))
+				require.NotEmpty(t, output.Selected)
+				require.Equal(t, output.Selected, output.Assigned)
+			})
+
+			t.Run("undefined variable", func(t *testing.T) {
+				def := state.MachineDefinition{
+					StartAt: "pass",
+					States: state.MachineStates{
+						"pass": []byte(`{"Type":"Pass","End":true,"Parameters":{"a.$":"$missing"}}`),
+					},
+				}
+
+				fn, err := sfn.New(def, nil)
+				require.NoError(t, err)
+
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, state.ErrParameterPathFailureCode, result.Error)
+			})
+
+			t.Run("JSONata", func(t *testing.T) {
+				def := state.MachineDefinition{
+					StartAt:       "init",
+					QueryLanguage: state.JSONataQueryLanguage,
+					States: state.MachineStates{
+						"init":  []byte(`{"Type":"Pass","Next":"check","Assign":{"items":"{% $states.input.items %}","index":0}}`),
+						"check": []byte(`{"Type":"Choice","Choices":[{"Condition":"{% $index < $count($items) %}","Next":"next","Assign":{"index":"{% $index + 1 %}"}}],"Default":"done"}`),
+						"next":  []byte(`{"Type":"Pass","Next":"check","Output":{"item":"{% $items[$index - 1] %}"}}`),
+						"done":  []byte(`{"Type":"Succeed","Output":{"count":"{% $index %}","last":"{% $states.input.item %}"}}`),
+					},
+				}
+
+				fn, err := sfn.New(def, nil)
+				require.NoError(t, err)
+
+				result, err := fn.StartExecution([]byte(`{"items":["a","b","c"]}`))
+				require.NoError(t, err)
+				require.JSONEq(t, `{"count":3,"last":"c"}`, string(result.Output))
+			})
+		})
+
+		t.Run("payload limits", func(t *testing.T) {
+			def := state.MachineDefinition{
+				StartAt: "task",
+				States: state.MachineStates{
+					"task": []byte(`{"Type":"Task","Resource":"large","Next":"parallel","ResultPath":"$.result"}`),
+					"parallel": []byte(`{"Type":"Parallel","End":true,"Branches":[
+						{"StartAt":"branch","States":{"branch":{"Type":"Pass","End":true,"Parameters":{"result.$":"$.result","extra":"xxxxxxxxxx"}}}}
+					]}`),
+				},
+			}
+			result := strings.Repeat("a", 100)
+			overrides := map[string]sfn.OverrideFn{
+				"large": func([]byte) ([]byte, error) {
+					return []byte(`"` + result + `"`), nil
+				},
+			}
+
+			t.Run("disabled", func(t *testing.T) {
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+				require.Equal(t, sfn.PayloadReport{"task": 113, "parallel": 136, "branch": 134}, result.Payloads)
+
+				stateTitle, size := result.Payloads.Largest()
+				require.Equal(t, "parallel", stateTitle)
+				require.Equal(t, 136, size)
+			})
+
+			tests := []struct {
+				title         string
+				limits        sfn.PayloadLimits
+				expectedState string
+			}{
+				{
+					"task result",
+					sfn.PayloadLimits{Task: 100},
+					"task",
+				},
+				{
+					"state output",
+					sfn.PayloadLimits{StateInputOutput: 110},
+					"task",
+				},
+				{
+					"branch state",
+					sfn.PayloadLimits{StateInputOutput: 120},
+					"parallel",
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					fn, err := sfn.New(def, overrides)
+					require.NoError(t, err)
+					fn.SetPayloadLimits(tt.limits)
+
+					result, err := fn.StartExecution([]byte(`{}`))
+					require.Error(t, err)
+					require.Equal(t, state.ErrDataLimitExceededCode, result.Error)
+					require.Equal(t, tt.expectedState, result.FailedState)
+				})
+			}
+
+			t.Run("default", func(t *testing.T) {
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetPayloadLimits(sfn.DefaultPayloadLimits())
+
+				_, err = fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+
+				result = strings.Repeat("a", sfn.DefaultPayloadLimit)
+				_, err = fn.StartExecution([]byte(`{}`))
+				require.Error(t, err)
+			})
+		})
+
+		t.Run("express", func(t *testing.T) {
+			def := state.MachineDefinition{
+				StartAt: "task",
+				States: state.MachineStates{
+					"task": []byte(`{"Type":"Task","Resource":"count","End":true}`),
+				},
+			}
+			runs := 0
+			overrides := map[string]sfn.OverrideFn{
+				"count": func(input []byte) ([]byte, error) {
+					runs++
+					return input, nil
+				},
+			}
+
+			t.Run("standard", func(t *testing.T) {
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetRedeliveries(2)
+
+				_, err = fn.StartSyncExecution([]byte(`{}`))
+				require.Equal(t, sfn.ErrStateMachineTypeNotSupported, err)
+
+				runs = 0
+				_, err = fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+				require.Equal(t, 1, runs)
+			})
+
+			def.Type = state.ExpressMachineType
+
+			t.Run("sync", func(t *testing.T) {
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetRedeliveries(2)
+
+				runs = 0
+				result, err := fn.StartSyncExecution([]byte(`{"a":1}`))
+				require.NoError(t, err)
+				require.Equal(t, sfn.ExecutionStatusSucceeded, result.Status)
+				require.JSONEq(t, `{"a":1}`, string(result.Output))
+				require.Equal(t, 1, runs)
+			})
+
+			t.Run("at least once", func(t *testing.T) {
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetRedeliveries(2)
+
+				runs = 0
+				_, err = fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+				require.Equal(t, 3, runs)
+			})
+
+			t.Run("duration", func(t *testing.T) {
+				clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+				overrides := map[string]sfn.OverrideFn{
+					"count": func(input []byte) ([]byte, error) {
+						clock.now = clock.now.Add(sfn.DefaultMaxExpressDuration + time.Second)
+						return input, nil
+					},
+				}
+
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetClock(clock)
+
+				result, err := fn.StartSyncExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, sfn.ExecutionStatusTimedOut, result.Status)
+			})
+
+			t.Run("TimeoutSeconds", func(t *testing.T) {
+				clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+				overrides := map[string]sfn.OverrideFn{
+					"count": func(input []byte) ([]byte, error) {
+						clock.now = clock.now.Add(61 * time.Second)
+						return input, nil
+					},
+				}
+
+				def := def
+				def.TimeoutSeconds = 60
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetClock(clock)
+
+				result, err := fn.StartSyncExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, sfn.ExecutionStatusTimedOut, result.Status)
+				require.Equal(t, state.ErrTimeoutCode, result.Error)
+			})
+		})
+
+		t.Run("quotas", func(t *testing.T) {
+			loop := state.MachineDefinition{
+				StartAt: "a",
+				States: state.MachineStates{
+					"a": []byte(`{"Type":"Pass","Next":"b"}`),
+					"b": []byte(`{"Type":"Pass","Next":"a"}`),
+				},
+			}
+
+			t.Run("history events", func(t *testing.T) {
+				fn, err := sfn.New(loop, nil)
+				require.NoError(t, err)
+				fn.SetQuotas(sfn.ExecutionQuotas{MaxHistoryEvents: 11})
+
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, sfn.ExecutionStatusFailed, result.Status)
+				require.Equal(t, state.ErrRuntimeCode, result.Error)
+				require.Equal(t, "a", result.FailedState)
+				require.Equal(t, 12, result.HistoryEvents)
+				require.Equal(t, 5, result.Transitions)
+			})
+
+			t.Run("branch history events", func(t *testing.T) {
+				def := state.MachineDefinition{
+					StartAt: "parallel",
+					States: state.MachineStates{
+						"parallel": []byte(`{"Type":"Parallel","End":true,"Branches":[
+							{"StartAt":"a","States":{"a":{"Type":"Pass","Next":"b"},"b":{"Type":"Pass","Next":"a"}}}
+						]}`),
+					},
+				}
+
+				fn, err := sfn.New(def, nil)
+				require.NoError(t, err)
+				fn.SetQuotas(sfn.ExecutionQuotas{MaxHistoryEvents: 20})
+
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, sfn.ExecutionStatusFailed, result.Status)
+				require.Equal(t, state.ErrRuntimeCode, result.Error)
+				require.Equal(t, "parallel", result.FailedState)
+				require.True(t, result.HistoryEvents > 20)
+			})
+
+			t.Run("express duration", func(t *testing.T) {
+				def := state.MachineDefinition{
+					StartAt: "task",
+					States: state.MachineStates{
+						"task": []byte(`{"Type":"Task","Resource":"slow","End":true}`),
+					},
+				}
+				clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+				overrides := map[string]sfn.OverrideFn{
+					"slow": func(input []byte) ([]byte, error) {
+						clock.now = clock.now.Add(sfn.DefaultMaxExpressDuration + time.Second)
+						return input, nil
+					},
+				}
+
+				fn, err := sfn.New(def, overrides)
+				require.NoError(t, err)
+				fn.SetClock(clock)
+
+				result, err := fn.StartExecution([]byte(`{}`))
+				require.NoError(t, err)
+				require.Equal(t, sfn.ExecutionStatusSucceeded, result.Status)
+				require.Equal(t, 7, result.HistoryEvents)
+				require.Equal(t, 1, result.Transitions)
+				require.Equal(t, sfn.DefaultMaxExpressDuration+time.Second, result.End.Sub(result.Start))
+
+				fn.SetQuotas(sfn.ExpressQuotas())
+
+				result, err = fn.StartExecution([]byte(`{}`))
+				require.Error(t, err)
+				require.Equal(t, sfn.ExecutionStatusTimedOut, result.Status)
+				require.Equal(t, state.ErrTimeoutCode, result.Error)
+				require.Equal(t, "task", result.FailedState)
+			})
+		})
 	})
 }
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// After elapses at once, advancing the clock by the duration
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	elapsed := make(chan time.Time, 1)
+	elapsed <- c.now
+	return elapsed
+}
@@ -0,0 +1,85 @@
+package sfn
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+	"github.com/eggsbenjamin/stepFnLocal/sns"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+// SNSPublishResource is the resource of the SNS integration, which publishes the parameters of the task as
+// a message
+const SNSPublishResource = "arn:aws:states:::sns:publish"
+
+// snsPublishResult is the result of the SNS integration, the response of Publish
+type snsPublishResult struct {
+	MessageId           string              `json:"MessageId"`
+	SdkHttpMetadata     sdkHttpMetadata     `json:"SdkHttpMetadata"`
+	SdkResponseMetadata sdkResponseMetadata `json:"SdkResponseMetadata"`
+}
+
+// RegisterSNS registers the handler of the SNS integration resource, which publishes with the client
+func RegisterSNS(resources ResourceRegistry, client sns.Client) {
+	resources.Register(SNSPublishResource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewSNSPublishTask(def, client), nil
+	})
+}
+
+// SNSPublishTask publishes a message with the optimised SNS integration
+type SNSPublishTask struct {
+	definition state.TaskDefinition
+	client     sns.Client
+}
+
+func NewSNSPublishTask(def state.TaskDefinition, client sns.Client) State {
+	return SNSPublishTask{
+		definition: def,
+		client:     client,
+	}
+}
+
+// Run publishes the message of the task's input e.g. {"TopicArn":"...","Message":{"id":1}}
+func (s SNSPublishTask) Run(input []byte) ([]byte, error) {
+	input, err := stringifyParameter(input, "Message")
+	if err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sns:publish parameters: "+err.Error())
+	}
+
+	params := &awssns.PublishInput{}
+	if err := json.Unmarshal(input, params); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sns:publish parameters: "+err.Error())
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sns:publish parameters: "+err.Error())
+	}
+
+	output, err := s.client.Publish(params)
+	if err != nil {
+		return nil, newServiceClientError("SNS", err)
+	}
+
+	return json.Marshal(snsPublishResult{
+		MessageId: aws.StringValue(output.MessageId),
+		SdkHttpMetadata: sdkHttpMetadata{
+			HttpHeaders:    map[string]string{"Content-Type": "text/xml"},
+			HttpStatusCode: http.StatusOK,
+		},
+		SdkResponseMetadata: sdkResponseMetadata{
+			RequestId: uuid.New(),
+		},
+	})
+}
+
+func (s SNSPublishTask) Next() string {
+	return s.definition.Next()
+}
+
+func (s SNSPublishTask) IsEnd() bool {
+	return s.definition.End()
+}
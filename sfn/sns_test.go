@@ -0,0 +1,65 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/sns"
+	"github.com/stretchr/testify/require"
+)
+
+const snsTopicARN = "arn:aws:sns:us-east-1:123456789012:orders"
+
+// snsPublishMachine is the definition of a machine which publishes its input to the topic
+const snsPublishMachine = `{
+	"StartAt": "publish",
+	"States": {
+		"publish": {
+			"Type": "Task",
+			"Resource": "arn:aws:states:::sns:publish",
+			"Parameters": {"TopicArn": "` + snsTopicARN + `", "Subject": "order", "Message.$": "$"},
+			"End": true
+		}
+	}
+}`
+
+// registerSNS returns the registration of the SNS integration, publishing to the topics
+func registerSNS(topics *sns.Topics) func(sfn.ResourceRegistry) {
+	return func(resources sfn.ResourceRegistry) {
+		sfn.RegisterSNS(resources, topics)
+	}
+}
+
+func TestSNSPublishTask(t *testing.T) {
+	t.Run("publish", func(t *testing.T) {
+		topics := sns.NewTopics()
+		topics.CreateTopic(snsTopicARN)
+		fn := newStepFunctionWithResources(t, snsPublishMachine, registerSNS(topics))
+
+		result, err := fn.StartExecution([]byte(`{"id":"1"}`))
+		require.NoError(t, err)
+
+		var output struct {
+			MessageId       string
+			SdkHttpMetadata struct{ HttpStatusCode int }
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.Equal(t, 200, output.SdkHttpMetadata.HttpStatusCode)
+
+		messages, err := topics.Messages(snsTopicARN)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, output.MessageId, messages[0].MessageID)
+		require.Equal(t, "order", messages[0].Subject)
+		require.JSONEq(t, `{"id":"1"}`, messages[0].Message)
+	})
+
+	t.Run("topic not found", func(t *testing.T) {
+		result, err := newStepFunctionWithResources(t, snsPublishMachine, registerSNS(sns.NewTopics())).StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "SNS.NotFound", result.Error)
+	})
+}
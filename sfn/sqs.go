@@ -0,0 +1,91 @@
+package sfn
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/eggsbenjamin/stepFnLocal/sqs"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+// SQSSendMessageResource is the resource of the SQS integration, which sends the parameters of the task
+// as a message
+const SQSSendMessageResource = "arn:aws:states:::sqs:sendMessage"
+
+// sqsSendMessageResult is the result of the SQS integration, the response of SendMessage
+type sqsSendMessageResult struct {
+	MD5OfMessageAttributes string              `json:"MD5OfMessageAttributes,omitempty"`
+	MD5OfMessageBody       string              `json:"MD5OfMessageBody"`
+	MessageId              string              `json:"MessageId"`
+	SequenceNumber         string              `json:"SequenceNumber,omitempty"`
+	SdkHttpMetadata        sdkHttpMetadata     `json:"SdkHttpMetadata"`
+	SdkResponseMetadata    sdkResponseMetadata `json:"SdkResponseMetadata"`
+}
+
+// RegisterSQS registers the handler of the SQS integration resource, which sends messages with the client
+func RegisterSQS(resources ResourceRegistry, client sqs.Client) {
+	resources.Register(SQSSendMessageResource, func(def state.TaskDefinition, resource arn.ARN) (State, error) {
+		return NewSQSSendMessageTask(def, client), nil
+	})
+}
+
+// SQSSendMessageTask sends a message to a queue with the optimised SQS integration
+type SQSSendMessageTask struct {
+	definition state.TaskDefinition
+	client     sqs.Client
+}
+
+func NewSQSSendMessageTask(def state.TaskDefinition, client sqs.Client) State {
+	return SQSSendMessageTask{
+		definition: def,
+		client:     client,
+	}
+}
+
+// Run sends the message of the task's input e.g. {"QueueUrl":"...","MessageBody":{"id":1}}
+func (s SQSSendMessageTask) Run(input []byte) ([]byte, error) {
+	input, err := stringifyParameter(input, "MessageBody")
+	if err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sqs:sendMessage parameters: "+err.Error())
+	}
+
+	params := &awssqs.SendMessageInput{}
+	if err := json.Unmarshal(input, params); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sqs:sendMessage parameters: "+err.Error())
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, state.NewError(state.ErrRuntimeCode, "invalid sqs:sendMessage parameters: "+err.Error())
+	}
+
+	output, err := s.client.SendMessage(params)
+	if err != nil {
+		return nil, newServiceClientError("SQS", err)
+	}
+
+	return json.Marshal(sqsSendMessageResult{
+		MD5OfMessageAttributes: aws.StringValue(output.MD5OfMessageAttributes),
+		MD5OfMessageBody:       aws.StringValue(output.MD5OfMessageBody),
+		MessageId:              aws.StringValue(output.MessageId),
+		SequenceNumber:         aws.StringValue(output.SequenceNumber),
+		SdkHttpMetadata: sdkHttpMetadata{
+			HttpHeaders:    map[string]string{"Content-Type": "text/xml"},
+			HttpStatusCode: http.StatusOK,
+		},
+		SdkResponseMetadata: sdkResponseMetadata{
+			RequestId: uuid.New(),
+		},
+	})
+}
+
+func (s SQSSendMessageTask) Next() string {
+	return s.definition.Next()
+}
+
+func (s SQSSendMessageTask) IsEnd() bool {
+	return s.definition.End()
+}
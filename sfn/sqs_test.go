@@ -0,0 +1,139 @@
+// +build unit
+
+package sfn_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eggsbenjamin/stepFnLocal/sfn"
+	"github.com/eggsbenjamin/stepFnLocal/sqs"
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sqsQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/orders"
+
+func TestSQSSendMessageTask(t *testing.T) {
+	t.Run("send message", func(t *testing.T) {
+		queues := sqs.NewQueues()
+		queues.CreateQueue(sqsQueueURL)
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "send",
+			"States": {
+				"send": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::sqs:sendMessage",
+					"Parameters": {"QueueUrl": "`+sqsQueueURL+`", "MessageBody": {"id.$": "$.id"}},
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterSQS(resources, queues)
+		})
+
+		result, err := fn.StartExecution([]byte(`{"id":"1"}`))
+		require.NoError(t, err)
+
+		var output struct {
+			MD5OfMessageBody    string
+			MessageId           string
+			SdkHttpMetadata     struct{ HttpStatusCode int }
+			SdkResponseMetadata struct{ RequestId string }
+		}
+		require.NoError(t, json.Unmarshal(result.Output, &output))
+		require.Equal(t, 200, output.SdkHttpMetadata.HttpStatusCode)
+		require.NotEmpty(t, output.SdkResponseMetadata.RequestId)
+
+		messages, err := queues.Messages(sqsQueueURL)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.JSONEq(t, `{"id":"1"}`, messages[0].Body)
+		require.Equal(t, messages[0].MessageID, output.MessageId)
+		require.Equal(t, messages[0].MD5OfBody, output.MD5OfMessageBody)
+	})
+
+	t.Run("wait for task token", func(t *testing.T) {
+		queues := sqs.NewQueues()
+		queues.CreateQueue(sqsQueueURL)
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "send",
+			"States": {
+				"send": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::sqs:sendMessage.waitForTaskToken",
+					"Parameters": {"QueueUrl": "`+sqsQueueURL+`", "MessageBody": {"token.$": "$$.Task.Token"}},
+					"TimeoutSeconds": 5,
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterSQS(resources, queues)
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				messages, _ := queues.Messages(sqsQueueURL)
+				if len(messages) > 0 {
+					var body struct {
+						Token string `json:"token"`
+					}
+					if assert.NoError(t, json.Unmarshal([]byte(messages[0].Body), &body)) {
+						assert.NoError(t, fn.TaskTokens().SendTaskSuccess(body.Token, []byte(`{"processed":true}`)))
+					}
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"processed":true}`, string(result.Output))
+		<-done
+	})
+
+	t.Run("queue does not exist", func(t *testing.T) {
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "send",
+			"States": {
+				"send": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::sqs:sendMessage",
+					"Parameters": {"QueueUrl": "`+sqsQueueURL+`", "MessageBody": "hello"},
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterSQS(resources, sqs.NewQueues())
+		})
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, "SQS.AWS.SimpleQueueService.NonExistentQueue", result.Error)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		fn := newStepFunctionWithResources(t, `{
+			"StartAt": "send",
+			"States": {
+				"send": {
+					"Type": "Task",
+					"Resource": "arn:aws:states:::sqs:sendMessage",
+					"Parameters": {"MessageBody": "hello"},
+					"End": true
+				}
+			}
+		}`, func(resources sfn.ResourceRegistry) {
+			sfn.RegisterSQS(resources, sqs.NewQueues())
+		})
+
+		result, err := fn.StartExecution([]byte(`{}`))
+		require.Error(t, err)
+		require.Equal(t, state.ErrRuntimeCode, result.Error)
+	})
+}
@@ -9,7 +9,6 @@ import (
 	awslambda "github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/eggsbenjamin/stepFnLocal/lambda"
 	"github.com/eggsbenjamin/stepFnLocal/state"
-	"github.com/pkg/errors"
 )
 
 type LambdaTask struct {
@@ -33,6 +32,12 @@ func (l LambdaTask) Run(input []byte) ([]byte, error) {
 		LogType:      aws.String("Tail"),
 		Payload:      input,
 	})
+	if err != nil {
+		return nil, newServiceClientError("Lambda", err)
+	}
+	if invokeOutput == nil {
+		return nil, newServiceClientError("Lambda", errLambdaNoOutput)
+	}
 	if invokeOutput.LogResult != nil {
 		logResult, err := base64.StdEncoding.DecodeString(*invokeOutput.LogResult)
 		if err != nil {
@@ -40,14 +45,8 @@ func (l LambdaTask) Run(input []byte) ([]byte, error) {
 		}
 		log.Printf("%s\n%s", l.arn.Resource, logResult)
 	}
-	if err != nil {
-		return nil, errors.Wrap(err, "lambda client error")
-	}
 	if invokeOutput.FunctionError != nil {
-		return nil, state.NewError(
-			state.ErrTaskFailedCode,
-			string(invokeOutput.Payload),
-		)
+		return nil, newLambdaFunctionError(invokeOutput.Payload)
 	}
 
 	return invokeOutput.Payload, nil
@@ -8,6 +8,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	awslambda "github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/eggsbenjamin/stepFnLocal/lambda"
 	"github.com/eggsbenjamin/stepFnLocal/sfn"
@@ -31,23 +32,30 @@ func TestLambdaTask(t *testing.T) {
 			)
 
 			_, err := task.Run([]byte{})
-			require.Equal(t, dummyErr, errors.Cause(err))
+			require.Equal(t, state.NewError(sfn.ErrLambdaSdkClientExceptionCode, dummyErr.Error()), err)
 			ctrl.Finish()
 		})
 
-		t.Run("invocation", func(t *testing.T) {
-			errorPayload := []byte(`{"errorMessage" : "error"}`)
-			expectedError := state.NewError(
-				state.ErrTaskFailedCode,
-				string(errorPayload),
+		t.Run("service exception", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockClient := lambda.NewMockClient(ctrl)
+			mockClient.EXPECT().Invoke(gomock.Any()).Return(nil, awserr.New("ServiceException", "service error", nil))
+
+			task := sfn.NewLambdaTask(
+				state.TaskDefinition{},
+				arn.ARN{},
+				mockClient,
 			)
 
+			_, err := task.Run([]byte{})
+			require.Equal(t, state.NewError("Lambda.ServiceException", "service error"), err)
+			ctrl.Finish()
+		})
+
+		t.Run("no output", func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			mockClient := lambda.NewMockClient(ctrl)
-			mockClient.EXPECT().Invoke(gomock.Any()).Return(&awslambda.InvokeOutput{
-				FunctionError: aws.String("Handled"),
-				Payload:       errorPayload,
-			}, nil)
+			mockClient.EXPECT().Invoke(gomock.Any()).Return(nil, nil)
 
 			task := sfn.NewLambdaTask(
 				state.TaskDefinition{},
@@ -56,9 +64,106 @@ func TestLambdaTask(t *testing.T) {
 			)
 
 			_, err := task.Run([]byte{})
-			require.Equal(t, expectedError, err)
+			require.Equal(t, state.NewError(sfn.ErrLambdaSdkClientExceptionCode, "lambda client returned no output"), err)
 			ctrl.Finish()
 		})
+
+		t.Run("unknown function", func(t *testing.T) {
+			resource, _ := arn.Parse("arn:aws:lambda:us-east-1:123456789012:function:missing")
+
+			task := sfn.NewLambdaTask(
+				state.TaskDefinition{},
+				resource,
+				lambda.NewHandlerClient(),
+			)
+
+			_, err := task.Run([]byte(`{}`))
+			require.Equal(t, state.NewError("Lambda."+awslambda.ErrCodeResourceNotFoundException, "Function not found: missing"), err)
+		})
+
+		t.Run("process", func(t *testing.T) {
+			client := lambda.NewProcessClient()
+			client.Register("unstartable", lambda.ProcessFunction{Command: "/does/not/exist"})
+
+			tests := []struct {
+				title        string
+				function     string
+				expectedName string
+			}{
+				{
+					"unknown function",
+					"missing",
+					"Lambda." + awslambda.ErrCodeResourceNotFoundException,
+				},
+				{
+					"unstartable function",
+					"unstartable",
+					sfn.ErrLambdaSdkClientExceptionCode,
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					resource, _ := arn.Parse("arn:aws:lambda:us-east-1:123456789012:function:" + tt.function)
+
+					task := sfn.NewLambdaTask(
+						state.TaskDefinition{},
+						resource,
+						client,
+					)
+
+					_, err := task.Run([]byte(`{}`))
+					stateErr, ok := err.(state.Error)
+					require.True(t, ok)
+					require.Equal(t, tt.expectedName, stateErr.Name)
+				})
+			}
+		})
+
+		t.Run("invocation", func(t *testing.T) {
+			tests := []struct {
+				title         string
+				errorPayload  []byte
+				expectedError state.Error
+			}{
+				{
+					"error type",
+					[]byte(`{"errorType" : "MyCustomError", "errorMessage" : "error", "stackTrace" : []}`),
+					state.NewError("MyCustomError", "error"),
+				},
+				{
+					"no error type",
+					[]byte(`{"errorMessage" : "error"}`),
+					state.NewError(sfn.ErrLambdaUnknownCode, `{"errorMessage" : "error"}`),
+				},
+				{
+					"invalid payload",
+					[]byte(`error`),
+					state.NewError(sfn.ErrLambdaUnknownCode, `error`),
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.title, func(t *testing.T) {
+					ctrl := gomock.NewController(t)
+					mockClient := lambda.NewMockClient(ctrl)
+					mockClient.EXPECT().Invoke(gomock.Any()).Return(&awslambda.InvokeOutput{
+						FunctionError: aws.String("Handled"),
+						Payload:       tt.errorPayload,
+					}, nil)
+
+					task := sfn.NewLambdaTask(
+						state.TaskDefinition{},
+						arn.ARN{},
+						mockClient,
+					)
+
+					_, err := task.Run([]byte{})
+					require.Equal(t, tt.expectedError, err)
+					ctrl.Finish()
+				})
+			}
+		})
 	})
 
 	t.Run("success", func(t *testing.T) {
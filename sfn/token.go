@@ -0,0 +1,290 @@
+package sfn
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	apiTargetPrefix = "AWSStepFunctions."
+	// maxTimedOutTokens is the number of tokens of timed out tasks remembered, so sending their outcome is
+	// rejected with TaskTimedOut rather than TaskDoesNotExist. The oldest are forgotten first.
+	maxTimedOutTokens = 1000
+)
+
+var (
+	ErrTaskDoesNotExist = errors.New("TaskDoesNotExist")
+	ErrTaskTimedOut     = errors.New("TaskTimedOut")
+	ErrInvalidToken     = errors.New("InvalidToken")
+	ErrInvalidOutput    = errors.New("InvalidOutput")
+	// ErrUnknownOperation is the error of a request for an action the API doesn't serve
+	ErrUnknownOperation = errors.New("UnknownOperationException")
+)
+
+// taskOutcome is the output or error a task completes with
+type taskOutcome struct {
+	output []byte
+	err    error
+}
+
+// tokenTask is a task waiting for the outcome sent with its token
+type tokenTask struct {
+	token     string
+	outcome   chan taskOutcome
+	heartbeat chan struct{}
+}
+
+// TaskTokens is a registry of the tokens of tasks which wait for their outcome to be sent e.g. by an
+// activity worker, with SendTaskSuccess or SendTaskFailure
+type TaskTokens struct {
+	mu            sync.Mutex
+	tasks         map[string]*tokenTask
+	timedOut      map[string]struct{}
+	timedOutOrder []string
+}
+
+func NewTaskTokens() *TaskTokens {
+	return &TaskTokens{
+		tasks:    map[string]*tokenTask{},
+		timedOut: map[string]struct{}{},
+	}
+}
+
+// SendTaskSuccess completes the task of the token with the output
+func (t *TaskTokens) SendTaskSuccess(token string, output []byte) error {
+	if !json.Valid(output) {
+		return errors.Wrap(ErrInvalidOutput, "invalid task output")
+	}
+
+	return t.complete(token, taskOutcome{output: output})
+}
+
+// SendTaskFailure fails the task of the token with the error and cause
+func (t *TaskTokens) SendTaskFailure(token, name, cause string) error {
+	if name == "" {
+		name = state.ErrTaskFailedCode
+	}
+
+	return t.complete(token, taskOutcome{err: state.NewError(name, cause)})
+}
+
+// SendTaskHeartbeat reports that the task of the token is still in progress
+func (t *TaskTokens) SendTaskHeartbeat(token string) error {
+	t.mu.Lock()
+	task, err := t.get(token)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case task.heartbeat <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// create registers the token of a new task
+func (t *TaskTokens) create() *tokenTask {
+	task := newTokenTask()
+	t.register(task)
+	return task
+}
+
+// newTokenTask returns a task with a new token, which isn't registered until the task runs
+func newTokenTask() *tokenTask {
+	return &tokenTask{
+		token:     uuid.New(),
+		outcome:   make(chan taskOutcome, 1),
+		heartbeat: make(chan struct{}, 1),
+	}
+}
+
+// register registers the token of a task so its outcome can be sent
+func (t *TaskTokens) register(task *tokenTask) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tasks[task.token] = task
+}
+
+// timeout removes the token of a task that timed out, whose outcome can no longer be sent. It reports
+// false if the outcome of the task was sent first, in which case the task hasn't timed out.
+func (t *TaskTokens) timeout(task *tokenTask) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.tasks[task.token]; !ok {
+		return false
+	}
+
+	delete(t.tasks, task.token)
+	t.timedOut[task.token] = struct{}{}
+	t.timedOutOrder = append(t.timedOutOrder, task.token)
+
+	if len(t.timedOutOrder) > maxTimedOutTokens {
+		delete(t.timedOut, t.timedOutOrder[0])
+		t.timedOutOrder = t.timedOutOrder[1:]
+	}
+
+	return true
+}
+
+// remove removes the token of a task that failed before its outcome could be sent
+func (t *TaskTokens) remove(task *tokenTask) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.tasks, task.token)
+}
+
+// get returns the task of a token. The caller must hold the lock.
+func (t *TaskTokens) get(token string) (*tokenTask, error) {
+	if token == "" {
+		return nil, errors.Wrap(ErrInvalidToken, "task token is empty")
+	}
+
+	if _, ok := t.timedOut[token]; ok {
+		return nil, errors.Wrapf(ErrTaskTimedOut, "task %s timed out", token)
+	}
+
+	task, ok := t.tasks[token]
+	if !ok {
+		return nil, errors.Wrapf(ErrTaskDoesNotExist, "task %s does not exist", token)
+	}
+
+	return task, nil
+}
+
+// complete sends the outcome of the task of a token. The task is settled under the lock, so it either
+// completes with its outcome or times out, but not both.
+func (t *TaskTokens) complete(token string, outcome taskOutcome) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, err := t.get(token)
+	if err != nil {
+		return err
+	}
+
+	delete(t.tasks, token)
+	task.outcome <- outcome
+	return nil
+}
+
+// wait waits for the outcome of a task. A task whose outcome isn't sent before the timeout fails with
+// States.Timeout, and one whose heartbeat isn't sent within heartbeatSeconds, as told by the clock, with
+// States.HeartbeatTimeout. The timeout and heartbeat are disabled if nil or 0.
+func (t *TaskTokens) wait(task *tokenTask, timeout <-chan time.Time, heartbeatSeconds int, clock Clock) ([]byte, error) {
+	var heartbeat <-chan time.Time
+	resetHeartbeat := func() {
+		if heartbeatSeconds > 0 {
+			heartbeat = clock.After(time.Duration(heartbeatSeconds) * time.Second)
+		}
+	}
+	resetHeartbeat()
+
+	for {
+		var err error
+		select {
+		case outcome := <-task.outcome:
+			return outcome.output, outcome.err
+		case <-task.heartbeat:
+			resetHeartbeat()
+			continue
+		case <-heartbeat:
+			err = state.NewError(state.ErrHeartbeatTimeoutCode, "task heartbeat timed out")
+		case <-timeout:
+			err = state.NewError(state.ErrTimeoutCode, "task timed out")
+		}
+
+		// an outcome sent as the task timed out has already settled it
+		if !t.timeout(task) {
+			outcome := <-task.outcome
+			return outcome.output, outcome.err
+		}
+
+		return nil, err
+	}
+}
+
+// ServeHTTP serves the SendTaskSuccess, SendTaskFailure and SendTaskHeartbeat actions of the Step
+// Functions JSON API e.g. to the SDK clients of workers
+func (t *TaskTokens) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		TaskToken string `json:"taskToken"`
+		Output    string `json:"output"`
+		Error     string `json:"error"`
+		Cause     string `json:"cause"`
+	}
+	if err := decodeAPIRequest(req, &body); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	var err error
+	switch apiAction(req) {
+	case "SendTaskSuccess":
+		err = t.SendTaskSuccess(body.TaskToken, []byte(body.Output))
+	case "SendTaskFailure":
+		err = t.SendTaskFailure(body.TaskToken, body.Error, body.Cause)
+	case "SendTaskHeartbeat":
+		err = t.SendTaskHeartbeat(body.TaskToken)
+	default:
+		err = errors.Wrapf(ErrUnknownOperation, "unknown action %s", req.Header.Get("X-Amz-Target"))
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeAPIResponse(w, struct{}{})
+}
+
+// apiAction returns the action of a Step Functions JSON API request
+func apiAction(req *http.Request) string {
+	return strings.TrimPrefix(req.Header.Get("X-Amz-Target"), apiTargetPrefix)
+}
+
+func decodeAPIRequest(req *http.Request, body interface{}) error {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, body)
+}
+
+func writeAPIResponse(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeAPIError writes an error as the JSON API does, typed by the name of the API error it wraps
+func writeAPIError(w http.ResponseWriter, err error) {
+	errType := "InvalidParameterValue"
+	switch cause := errors.Cause(err); cause {
+	case ErrTaskDoesNotExist, ErrTaskTimedOut, ErrInvalidToken, ErrUnknownOperation, ErrInvalidOutput:
+		errType = cause.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{
+		"__type":  errType,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,12 @@
+//go:generate mockgen -package sns -source=sns.go -destination sns_mock.go
+
+package sns
+
+import (
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// Client defines the sns client interface of the service integration
+type Client interface {
+	Publish(*sns.PublishInput) (*sns.PublishOutput, error)
+}
@@ -0,0 +1,116 @@
+package sns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+// Message is a message published to a topic, or directly to a target or phone number
+type Message struct {
+	MessageID        string
+	TopicARN         string
+	TargetARN        string
+	PhoneNumber      string
+	Subject          string
+	Message          string
+	MessageStructure string
+	Attributes       map[string]*sns.MessageAttributeValue
+	Timestamp        time.Time
+}
+
+// Topics captures the messages published to topics in memory, to be inspected after an execution e.g.
+// by the SNS service integration. Messages published to targets or phone numbers are captured too.
+type Topics struct {
+	mu       sync.Mutex
+	topics   map[string][]Message
+	messages []Message
+}
+
+func NewTopics() *Topics {
+	return &Topics{
+		topics: map[string][]Message{},
+	}
+}
+
+// CreateTopic creates a topic of the ARN e.g. arn:aws:sns:us-east-1:123456789012:orders, replacing any
+// topic of the same ARN
+func (t *Topics) CreateTopic(topicARN string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.topics[topicARN] = []Message{}
+}
+
+// Messages returns the messages published to the topic, in the order they were published
+func (t *Topics) Messages(topicARN string) ([]Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	messages, ok := t.topics[topicARN]
+	if !ok {
+		return nil, newTopicNotFoundError()
+	}
+
+	return append([]Message{}, messages...), nil
+}
+
+// Published returns every message published, in the order they were published
+func (t *Topics) Published() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Message{}, t.messages...)
+}
+
+// Publish captures the message. A message must be published to exactly one of a topic, a target or a
+// phone number, and a topic must have been created.
+func (t *Topics) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	destinations := 0
+	for _, destination := range []*string{input.TopicArn, input.TargetArn, input.PhoneNumber} {
+		if aws.StringValue(destination) != "" {
+			destinations++
+		}
+	}
+	if destinations != 1 {
+		return nil, awserr.New(sns.ErrCodeInvalidParameterException, "Invalid parameter: a message must be published to one of TopicArn, TargetArn or PhoneNumber", nil)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	message := Message{
+		MessageID:        uuid.New(),
+		TopicARN:         aws.StringValue(input.TopicArn),
+		TargetARN:        aws.StringValue(input.TargetArn),
+		PhoneNumber:      aws.StringValue(input.PhoneNumber),
+		Subject:          aws.StringValue(input.Subject),
+		Message:          aws.StringValue(input.Message),
+		MessageStructure: aws.StringValue(input.MessageStructure),
+		Attributes:       input.MessageAttributes,
+		Timestamp:        time.Now(),
+	}
+
+	if message.TopicARN != "" {
+		messages, ok := t.topics[message.TopicARN]
+		if !ok {
+			return nil, newTopicNotFoundError()
+		}
+		t.topics[message.TopicARN] = append(messages, message)
+	}
+
+	t.messages = append(t.messages, message)
+	return &sns.PublishOutput{MessageId: aws.String(message.MessageID)}, nil
+}
+
+func newTopicNotFoundError() error {
+	return awserr.New(sns.ErrCodeNotFoundException, "Topic does not exist", nil)
+}
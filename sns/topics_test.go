@@ -0,0 +1,72 @@
+// +build unit
+
+package sns_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+	"github.com/eggsbenjamin/stepFnLocal/sns"
+	"github.com/stretchr/testify/require"
+)
+
+const topicARN = "arn:aws:sns:us-east-1:123456789012:orders"
+
+func requireAWSError(t *testing.T, code string, err error) {
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "expected an awserr.Error, got %v", err)
+	require.Equal(t, code, awsErr.Code())
+}
+
+func TestTopics(t *testing.T) {
+	t.Run("publish", func(t *testing.T) {
+		topics := sns.NewTopics()
+		topics.CreateTopic(topicARN)
+
+		output, err := topics.Publish(&awssns.PublishInput{
+			TopicArn: aws.String(topicARN),
+			Subject:  aws.String("order"),
+			Message:  aws.String("hello"),
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, aws.StringValue(output.MessageId))
+
+		_, err = topics.Publish(&awssns.PublishInput{
+			PhoneNumber: aws.String("+15555550100"),
+			Message:     aws.String("sms"),
+		})
+		require.NoError(t, err)
+
+		messages, err := topics.Messages(topicARN)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, aws.StringValue(output.MessageId), messages[0].MessageID)
+		require.Equal(t, "order", messages[0].Subject)
+		require.Equal(t, "hello", messages[0].Message)
+
+		published := topics.Published()
+		require.Len(t, published, 2)
+		require.Equal(t, "+15555550100", published[1].PhoneNumber)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		topics := sns.NewTopics()
+
+		_, err := topics.Publish(&awssns.PublishInput{
+			TopicArn: aws.String(topicARN),
+			Message:  aws.String("hello"),
+		})
+		requireAWSError(t, awssns.ErrCodeNotFoundException, err)
+
+		_, err = topics.Messages(topicARN)
+		requireAWSError(t, awssns.ErrCodeNotFoundException, err)
+
+		_, err = topics.Publish(&awssns.PublishInput{Message: aws.String("hello")})
+		requireAWSError(t, awssns.ErrCodeInvalidParameterException, err)
+
+		_, err = topics.Publish(&awssns.PublishInput{TopicArn: aws.String(topicARN)})
+		require.Error(t, err)
+	})
+}
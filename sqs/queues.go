@@ -0,0 +1,164 @@
+package sqs
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+)
+
+// fifoSuffix is the suffix of the names, and so URLs, of FIFO queues
+const fifoSuffix = ".fifo"
+
+// Message is a message sent to a queue
+type Message struct {
+	MessageID              string
+	Body                   string
+	MD5OfBody              string
+	MD5OfMessageAttributes string
+	Attributes             map[string]*sqs.MessageAttributeValue
+	DelaySeconds           int64
+	MessageGroupID         string
+	MessageDeduplicationID string
+	SequenceNumber         string
+	SentTimestamp          time.Time
+}
+
+// Queues captures the messages sent to queues in memory, to be inspected after an execution e.g. by the
+// SQS service integration
+type Queues struct {
+	mu       sync.Mutex
+	queues   map[string][]Message
+	sequence int64
+}
+
+func NewQueues() *Queues {
+	return &Queues{
+		queues: map[string][]Message{},
+	}
+}
+
+// CreateQueue creates an empty queue of the URL e.g.
+// https://sqs.us-east-1.amazonaws.com/123456789012/orders, replacing any queue of the same URL. Queues
+// whose URLs end in .fifo are FIFO queues.
+func (q *Queues) CreateQueue(queueURL string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queues[queueURL] = []Message{}
+}
+
+// Messages returns the messages sent to the queue, in the order they were sent
+func (q *Queues) Messages(queueURL string) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages, ok := q.queues[queueURL]
+	if !ok {
+		return nil, newQueueDoesNotExistError(queueURL)
+	}
+
+	return append([]Message{}, messages...), nil
+}
+
+// SendMessage captures the message in its queue. A message to a FIFO queue must have a group ID.
+func (q *Queues) SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	queueURL := aws.StringValue(input.QueueUrl)
+	fifo := strings.HasSuffix(queueURL, fifoSuffix)
+	if fifo && input.MessageGroupId == nil {
+		return nil, awserr.New("MissingParameter", "The request must contain the parameter MessageGroupId.", nil)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages, ok := q.queues[queueURL]
+	if !ok {
+		return nil, newQueueDoesNotExistError(queueURL)
+	}
+
+	message := Message{
+		MessageID:              uuid.New(),
+		Body:                   aws.StringValue(input.MessageBody),
+		MD5OfBody:              md5Hex([]byte(aws.StringValue(input.MessageBody))),
+		Attributes:             input.MessageAttributes,
+		DelaySeconds:           aws.Int64Value(input.DelaySeconds),
+		MessageGroupID:         aws.StringValue(input.MessageGroupId),
+		MessageDeduplicationID: aws.StringValue(input.MessageDeduplicationId),
+		SentTimestamp:          time.Now(),
+	}
+
+	output := &sqs.SendMessageOutput{
+		MessageId:        aws.String(message.MessageID),
+		MD5OfMessageBody: aws.String(message.MD5OfBody),
+	}
+
+	if len(input.MessageAttributes) > 0 {
+		message.MD5OfMessageAttributes = md5OfMessageAttributes(input.MessageAttributes)
+		output.MD5OfMessageAttributes = aws.String(message.MD5OfMessageAttributes)
+	}
+
+	if fifo {
+		q.sequence++
+		message.SequenceNumber = fmt.Sprintf("%020d", q.sequence)
+		output.SequenceNumber = aws.String(message.SequenceNumber)
+	}
+
+	q.queues[queueURL] = append(messages, message)
+	return output, nil
+}
+
+func newQueueDoesNotExistError(queueURL string) error {
+	return awserr.New(sqs.ErrCodeQueueDoesNotExist, "The specified queue does not exist for this wsdl version: "+queueURL, nil)
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// md5OfMessageAttributes returns the digest of message attributes as SQS calculates it, over the name,
+// data type, transport type and value of each attribute in order of name
+func md5OfMessageAttributes(attributes map[string]*sqs.MessageAttributeValue) string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := []byte{}
+	appendField := func(value []byte) {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(value)))
+		data = append(append(data, length...), value...)
+	}
+
+	for _, name := range names {
+		attribute := attributes[name]
+		appendField([]byte(name))
+		appendField([]byte(aws.StringValue(attribute.DataType)))
+
+		if attribute.BinaryValue != nil {
+			data = append(data, 2)
+			appendField(attribute.BinaryValue)
+		} else {
+			data = append(data, 1)
+			appendField([]byte(aws.StringValue(attribute.StringValue)))
+		}
+	}
+
+	return md5Hex(data)
+}
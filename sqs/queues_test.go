@@ -0,0 +1,98 @@
+// +build unit
+
+package sqs_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/eggsbenjamin/stepFnLocal/sqs"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	queueURL     = "https://sqs.us-east-1.amazonaws.com/123456789012/orders"
+	fifoQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/orders.fifo"
+)
+
+func requireAWSError(t *testing.T, code string, err error) {
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "expected an awserr.Error, got %v", err)
+	require.Equal(t, code, awsErr.Code())
+}
+
+func TestQueues(t *testing.T) {
+	t.Run("send message", func(t *testing.T) {
+		queues := sqs.NewQueues()
+		queues.CreateQueue(queueURL)
+
+		output, err := queues.SendMessage(&awssqs.SendMessageInput{
+			QueueUrl:    aws.String(queueURL),
+			MessageBody: aws.String("hello"),
+			MessageAttributes: map[string]*awssqs.MessageAttributeValue{
+				"type": {DataType: aws.String("String"), StringValue: aws.String("order")},
+			},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, aws.StringValue(output.MessageId))
+		require.Equal(t, "5d41402abc4b2a76b9719d911017c592", aws.StringValue(output.MD5OfMessageBody))
+		require.NotEmpty(t, aws.StringValue(output.MD5OfMessageAttributes))
+		require.Nil(t, output.SequenceNumber)
+
+		messages, err := queues.Messages(queueURL)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, aws.StringValue(output.MessageId), messages[0].MessageID)
+		require.Equal(t, "hello", messages[0].Body)
+		require.Equal(t, "order", aws.StringValue(messages[0].Attributes["type"].StringValue))
+	})
+
+	t.Run("fifo queue", func(t *testing.T) {
+		queues := sqs.NewQueues()
+		queues.CreateQueue(fifoQueueURL)
+
+		_, err := queues.SendMessage(&awssqs.SendMessageInput{
+			QueueUrl:    aws.String(fifoQueueURL),
+			MessageBody: aws.String("hello"),
+		})
+		requireAWSError(t, "MissingParameter", err)
+
+		first, err := queues.SendMessage(&awssqs.SendMessageInput{
+			QueueUrl:       aws.String(fifoQueueURL),
+			MessageBody:    aws.String("first"),
+			MessageGroupId: aws.String("group"),
+		})
+		require.NoError(t, err)
+
+		second, err := queues.SendMessage(&awssqs.SendMessageInput{
+			QueueUrl:       aws.String(fifoQueueURL),
+			MessageBody:    aws.String("second"),
+			MessageGroupId: aws.String("group"),
+		})
+		require.NoError(t, err)
+		require.True(t, aws.StringValue(first.SequenceNumber) < aws.StringValue(second.SequenceNumber))
+
+		messages, err := queues.Messages(fifoQueueURL)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		require.Equal(t, "group", messages[1].MessageGroupID)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		queues := sqs.NewQueues()
+
+		_, err := queues.SendMessage(&awssqs.SendMessageInput{
+			QueueUrl:    aws.String(queueURL),
+			MessageBody: aws.String("hello"),
+		})
+		requireAWSError(t, awssqs.ErrCodeQueueDoesNotExist, err)
+
+		_, err = queues.Messages(queueURL)
+		requireAWSError(t, awssqs.ErrCodeQueueDoesNotExist, err)
+
+		_, err = queues.SendMessage(&awssqs.SendMessageInput{QueueUrl: aws.String(queueURL)})
+		require.Error(t, err)
+	})
+}
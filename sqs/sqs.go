@@ -0,0 +1,12 @@
+//go:generate mockgen -package sqs -source=sqs.go -destination sqs_mock.go
+
+package sqs
+
+import (
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Client defines the sqs client interface of the service integration
+type Client interface {
+	SendMessage(*sqs.SendMessageInput) (*sqs.SendMessageOutput, error)
+}
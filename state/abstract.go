@@ -1,5 +1,7 @@
 package state
 
+import "github.com/eggsbenjamin/stepFnLocal/jsonpath"
+
 // Typer defines the typer interface which all state definitions must implement
 type Typer interface {
 	Type() string
@@ -21,10 +23,12 @@ type Transitioner interface {
 
 type InputPather interface {
 	InputPath() JSONPathExp
+	InputJSONPath() JSONPath
 }
 
 type OutputPather interface {
 	OutputPath() JSONPathExp
+	OutputJSONPath() JSONPath
 }
 
 type IOPather interface {
@@ -34,6 +38,31 @@ type IOPather interface {
 
 type ResultPather interface {
 	ResultPath() JSONPathExp
+	ResultJSONPath() JSONPath
+}
+
+type Parameterizer interface {
+	Parameters() PayloadTemplate
+}
+
+type ResultSelectorer interface {
+	ResultSelector() PayloadTemplate
+}
+
+type QueryLanguager interface {
+	QueryLanguage() string
+}
+
+type Argumenter interface {
+	Arguments() JSONataTemplate
+}
+
+type Outputter interface {
+	Output() JSONataTemplate
+}
+
+type Assigner interface {
+	Assign() AssignTemplate
 }
 
 // Definition defines the definition interface which all state definitions must implement
@@ -44,8 +73,9 @@ type Definition interface {
 
 // BaseDefinition represents an AWS states language state. It contains fields that can appear in all state types.
 type BaseDefinition struct {
-	StateType    string `json:"Type"`
-	StateComment string `json:"Comment"`
+	StateType          string `json:"Type"`
+	StateComment       string `json:"Comment"`
+	StateQueryLanguage string `json:"QueryLanguage"`
 }
 
 func (s BaseDefinition) Validate() error {
@@ -59,12 +89,23 @@ func (s BaseDefinition) Validate() error {
 		}
 	}
 
+	if s.StateQueryLanguage != "" {
+		if _, ok := validQueryLanguages[s.StateQueryLanguage]; !ok {
+			validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "QueryLanguage", s.StateQueryLanguage))
+		}
+	}
+
 	if len(validationErrs) > 0 {
 		return validationErrs
 	}
 	return nil
 }
 
+// QueryLanguage returns the query language set by the state, if any
+func (s BaseDefinition) QueryLanguage() string {
+	return s.StateQueryLanguage
+}
+
 type TransitionDefinition struct {
 	NextState string `json:"Next"`
 	EndState  bool   `json:"End"`
@@ -97,25 +138,33 @@ func (t TransitionDefinition) End() bool {
 type IOPathDefinition struct {
 	InputPathExp  JSONPathExp `json:"InputPath"`
 	OutputPathExp JSONPathExp `json:"OutputPath"`
+	inputPath     JSONPath
+	outputPath    JSONPath
+}
+
+// compile compiles the paths of a definition loaded from the fields
+func (i *IOPathDefinition) compile(fields definitionFields) {
+	i.inputPath = newDefinitionJSONPath(i.InputPathExp, fields, "InputPath")
+	i.outputPath = newDefinitionJSONPath(i.OutputPathExp, fields, "OutputPath")
 }
 
 func (i IOPathDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
-	if i.InputPathExp != "" {
-		if err := i.InputPathExp.Validate(); err != nil {
+	if inputPath := i.InputJSONPath(); !inputPath.IsEmpty() && !inputPath.IsNull() {
+		if err := inputPath.Validate(); err != nil {
 			validationErrs = append(validationErrs, NewValidationError(
 				InvalidJSONPathErrType,
-				"InputPath", string(i.InputPathExp),
+				"InputPath", i.InputPathExp.String(),
 			))
 		}
 	}
 
-	if i.OutputPathExp != "" {
-		if err := i.OutputPathExp.Validate(); err != nil {
+	if outputPath := i.OutputJSONPath(); !outputPath.IsEmpty() && !outputPath.IsNull() {
+		if err := outputPath.Validate(); err != nil {
 			validationErrs = append(validationErrs, NewValidationError(
 				InvalidJSONPathErrType,
-				"OutputPath", string(i.OutputPathExp),
+				"OutputPath", i.OutputPathExp.String(),
 			))
 		}
 	}
@@ -134,18 +183,35 @@ func (i IOPathDefinition) OutputPath() JSONPathExp {
 	return i.OutputPathExp
 }
 
+// InputJSONPath returns the compiled InputPath, which is null if it's explicitly null in the definition
+func (i IOPathDefinition) InputJSONPath() JSONPath {
+	return compiledJSONPath(i.inputPath, i.InputPathExp)
+}
+
+// OutputJSONPath returns the compiled OutputPath, which is null if it's explicitly null in the definition
+func (i IOPathDefinition) OutputJSONPath() JSONPath {
+	return compiledJSONPath(i.outputPath, i.OutputPathExp)
+}
+
 type ResultPathDefinition struct {
 	ResultPathExp JSONPathExp `json:"ResultPath"`
+	resultPath    JSONPath
+}
+
+// compile compiles the path of a definition loaded from the fields
+func (r *ResultPathDefinition) compile(fields definitionFields) {
+	r.resultPath = newDefinitionJSONPath(r.ResultPathExp, fields, "ResultPath")
 }
 
 func (r ResultPathDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
-	if r.ResultPathExp != "" {
-		if err := r.ResultPathExp.Validate(); err != nil {
+	if resultPath := r.ResultJSONPath(); !resultPath.IsEmpty() && !resultPath.IsNull() {
+		// variables are read only so the result can't be placed in one
+		if err := resultPath.ValidateReference(); err != nil || jsonpath.IsVariable(resultPath.String()) {
 			validationErrs = append(validationErrs, NewValidationError(
 				InvalidJSONPathErrType,
-				"ResultPath", string(r.ResultPathExp),
+				"ResultPath", r.ResultPathExp.String(),
 			))
 		}
 	}
@@ -159,3 +225,54 @@ func (r ResultPathDefinition) Validate() error {
 func (r ResultPathDefinition) ResultPath() JSONPathExp {
 	return r.ResultPathExp
 }
+
+// ResultJSONPath returns the compiled ResultPath, which is null if it's explicitly null in the definition
+func (r ResultPathDefinition) ResultJSONPath() JSONPath {
+	return compiledJSONPath(r.resultPath, r.ResultPathExp)
+}
+
+type ParametersDefinition struct {
+	ParametersTemplate PayloadTemplate `json:"Parameters"`
+}
+
+func (p ParametersDefinition) Validate() error {
+	validationErrs := ValidationErrors{}
+
+	if !p.ParametersTemplate.IsEmpty() {
+		if err := p.ParametersTemplate.Validate("Parameters"); err != nil {
+			validationErrs = append(validationErrs, err.(ValidationErrors)...)
+		}
+	}
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+func (p ParametersDefinition) Parameters() PayloadTemplate {
+	return p.ParametersTemplate
+}
+
+type ResultSelectorDefinition struct {
+	ResultSelectorTemplate PayloadTemplate `json:"ResultSelector"`
+}
+
+func (r ResultSelectorDefinition) Validate() error {
+	validationErrs := ValidationErrors{}
+
+	if !r.ResultSelectorTemplate.IsEmpty() {
+		if err := r.ResultSelectorTemplate.Validate("ResultSelector"); err != nil {
+			validationErrs = append(validationErrs, err.(ValidationErrors)...)
+		}
+	}
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+func (r ResultSelectorDefinition) ResultSelector() PayloadTemplate {
+	return r.ResultSelectorTemplate
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 )
@@ -33,6 +34,7 @@ const (
 	And                        = "And"
 	Or                         = "Or"
 	Not                        = "Not"
+	Condition                  = "Condition"
 )
 
 var (
@@ -94,6 +96,8 @@ type RuleDefinition interface {
 }
 
 type ChoiceRuleDefinition struct {
+	AssignDefinition
+	Condition                  JSONataExp             `json:"Condition"`
 	VariableExp                JSONPathExp            `json:"Variable"`
 	NextState                  string                 `json:"Next"`
 	StringEquals               *string                `json:"StringEquals"`
@@ -115,9 +119,30 @@ type ChoiceRuleDefinition struct {
 	And                        []ChoiceRuleDefinition `json:"And"`
 	Or                         []ChoiceRuleDefinition `json:"Or"`
 	Not                        *ChoiceRuleDefinition  `json:"Not"`
+	variable                   JSONPath
+}
+
+// UnmarshalJSON unmarshals the rule and compiles its Variable
+func (b *ChoiceRuleDefinition) UnmarshalJSON(data []byte) error {
+	type choiceRuleDefinition ChoiceRuleDefinition
+	if err := json.Unmarshal(data, (*choiceRuleDefinition)(b)); err != nil {
+		return err
+	}
+
+	b.variable = NewJSONPath(b.VariableExp)
+	return nil
+}
+
+// VariableJSONPath returns the compiled Variable of the rule
+func (b ChoiceRuleDefinition) VariableJSONPath() JSONPath {
+	return compiledJSONPath(b.variable, b.VariableExp)
 }
 
 func (b ChoiceRuleDefinition) Validate(depth int) error {
+	if !b.Condition.IsEmpty() {
+		return b.validateCondition(depth)
+	}
+
 	validationErrs := ValidationErrors{}
 
 	if err := b.validateLogicalOperatorCombinations(); err != nil {
@@ -126,11 +151,11 @@ func (b ChoiceRuleDefinition) Validate(depth int) error {
 
 	variableOperatorCount := b.countVariableOperators()
 
-	if b.VariableExp != "" {
-		if err := b.VariableExp.Validate(); err != nil {
+	if !b.VariableExp.IsEmpty() {
+		if err := b.VariableJSONPath().ValidateReference(); err != nil {
 			validationErrs = append(validationErrs, NewValidationError(
 				InvalidJSONPathErrType,
-				"Variable", string(b.VariableExp),
+				"Variable", b.VariableExp.String(),
 			))
 		}
 
@@ -212,6 +237,57 @@ func (b ChoiceRuleDefinition) Validate(depth int) error {
 				"Next", "",
 			))
 		}
+
+		if !b.AssignTemplate.IsEmpty() {
+			validationErrs = append(validationErrs, NewValidationError(
+				InvalidKeyErrType,
+				"Assign", "",
+			))
+		}
+	}
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+// validateCondition validates a JSONata choice rule, which is a Condition and a Next state only
+func (b ChoiceRuleDefinition) validateCondition(depth int) error {
+	validationErrs := ValidationErrors{}
+
+	if !b.VariableExp.IsEmpty() || b.And != nil || b.Or != nil || b.Not != nil || b.countVariableOperators() > 0 {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidCombinationErrType,
+			OnlyOneMustExistErrMsg,
+			"Condition/Variable/And/Or/Not",
+		))
+	}
+
+	if !b.Condition.IsExpression() {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidJSONataErrType,
+			"Condition", b.Condition.String(),
+		))
+	} else if err := b.Condition.Validate(); err != nil {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidJSONataErrType,
+			"Condition", b.Condition.String(),
+		))
+	}
+
+	if depth > 0 {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidKeyErrType,
+			"Condition", "",
+		))
+	}
+
+	if depth == 0 && b.NextState == "" {
+		validationErrs = append(validationErrs, NewValidationError(
+			MissingRequiredFieldErrType,
+			"Next", "",
+		))
 	}
 
 	if len(validationErrs) > 0 {
@@ -221,6 +297,9 @@ func (b ChoiceRuleDefinition) Validate(depth int) error {
 }
 
 func (b ChoiceRuleDefinition) Type() string {
+	if !b.Condition.IsEmpty() {
+		return Condition
+	}
 	if b.StringEquals != nil {
 		return StringEquals
 	}
@@ -283,7 +362,7 @@ func (b ChoiceRuleDefinition) validateLogicalOperatorCombinations() error {
 	validationErrs := ValidationErrors{}
 
 	var count int
-	if b.VariableExp != "" {
+	if !b.VariableExp.IsEmpty() {
 		count++
 	}
 	if b.And != nil {
@@ -373,6 +452,11 @@ func (b ChoiceRuleDefinition) countVariableOperators() int {
 }
 
 type ChoiceDefinition struct {
+	BaseDefinition
+	IOPathDefinition
+	OutputDefinition
+	// assigned when no choice rule matches and the Default state is transitioned to
+	AssignDefinition
 	Choices      []ChoiceRuleDefinition `json:"Choices"`
 	DefaultState string                 `json:"Default"`
 	NextState    string                 `json:"-"`
@@ -382,6 +466,22 @@ func (c ChoiceDefinition) Type() string {
 	return ChoiceStateType
 }
 
+// UnmarshalJSON unmarshals the definition and compiles its paths
+func (c *ChoiceDefinition) UnmarshalJSON(data []byte) error {
+	type choiceDefinition ChoiceDefinition
+	if err := json.Unmarshal(data, (*choiceDefinition)(c)); err != nil {
+		return err
+	}
+
+	fields, err := newDefinitionFields(data)
+	if err != nil {
+		return err
+	}
+	c.IOPathDefinition.compile(fields)
+
+	return nil
+}
+
 func (c ChoiceDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -392,10 +492,30 @@ func (c ChoiceDefinition) Validate() error {
 		))
 	}
 
+	if err := c.BaseDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := c.IOPathDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := c.OutputDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := c.AssignDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
 	for _, choiceRule := range c.Choices {
 		if err := choiceRule.Validate(0); err != nil {
 			validationErrs = append(validationErrs, err.(ValidationErrors)...)
 		}
+
+		if err := choiceRule.AssignDefinition.Validate(); err != nil {
+			validationErrs = append(validationErrs, err.(ValidationErrors)...)
+		}
 	}
 
 	if len(validationErrs) > 0 {
@@ -225,6 +225,38 @@ func TestChoiceRuleDefinitions(t *testing.T) {
 					},
 					nil,
 				},
+				{
+					"Condition with Variable",
+					state.ChoiceRuleDefinition{
+						Condition:   state.NewJSONataExp("{% true %}"),
+						VariableExp: "$",
+						NextState:   "test",
+					},
+					state.NewValidationError(
+						state.InvalidCombinationErrType,
+						state.OnlyOneMustExistErrMsg,
+						"Condition/Variable/And/Or/Not",
+					),
+				},
+				{
+					"invalid Condition",
+					state.ChoiceRuleDefinition{
+						Condition: state.NewJSONataExp("{% 1 + %}"),
+						NextState: "test",
+					},
+					state.NewValidationError(
+						state.InvalidJSONataErrType,
+						"Condition", "{% 1 + %}",
+					),
+				},
+				{
+					"valid Condition choice rule",
+					state.ChoiceRuleDefinition{
+						Condition: state.NewJSONataExp("{% $states.input.value > 1 %}"),
+						NextState: "test",
+					},
+					nil,
+				},
 			}
 
 			for _, tt := range tests {
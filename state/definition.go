@@ -1,6 +1,11 @@
 package state
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/eggsbenjamin/stepFnLocal/intrinsic"
 	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
 )
 
@@ -14,6 +19,13 @@ const (
 	ParallelStateType = "Parallel"
 )
 
+// the service integration patterns of task resources
+const (
+	RequestResponseIntegrationPattern  = ""
+	SyncIntegrationPattern             = ".sync"
+	WaitForTaskTokenIntegrationPattern = ".waitForTaskToken"
+)
+
 var validTypes = map[string]struct{}{
 	PassStateType:     {},
 	TaskStateType:     {},
@@ -24,19 +36,148 @@ var validTypes = map[string]struct{}{
 	ParallelStateType: {},
 }
 
+// JSONPathExp is a JSON path of a definition e.g. "$.name"
 type JSONPathExp string
 
+// String returns the path
+func (j JSONPathExp) String() string {
+	return string(j)
+}
+
+// IsEmpty reports whether the path isn't set
+func (j JSONPathExp) IsEmpty() bool {
+	return j == ""
+}
+
 func (j JSONPathExp) Validate() error {
 	_, err := jsonpath.NewExpression(string(j))
 	return err
 }
 
+// ValidateReference validates the expression as a reference path, which may only identify a single node
+func (j JSONPathExp) ValidateReference() error {
+	_, err := jsonpath.NewReferencePath(string(j))
+	return err
+}
+
 func (j JSONPathExp) Search(input []byte) ([]byte, error) {
-	if string(j) == "" {
+	return NewJSONPath(j).Search(input)
+}
+
+// Set returns a copy of input with value placed at the location the reference path refers to, creating
+// any missing intermediate objects. An empty path or "$" replaces the input entirely.
+func (j JSONPathExp) Set(input, value []byte) ([]byte, error) {
+	return NewJSONPath(j).Set(input, value)
+}
+
+// JSONPath is a JSONPathExp compiled once, when the definition it belongs to is loaded. A path explicitly
+// set to null in a definition is distinguished from an empty one, which isn't set at all.
+type JSONPath struct {
+	exp      JSONPathExp
+	null     bool
+	compiled *compiledPath
+}
+
+// compiledPath is a path compiled as an expression, and as a reference path for paths that identify a
+// single node e.g. a ResultPath. Errors compiling the path are reported by validation.
+type compiledPath struct {
+	expression    jsonpath.Expression
+	expressionErr error
+	reference     jsonpath.Expression
+	referenceErr  error
+}
+
+func NewJSONPath(exp JSONPathExp) JSONPath {
+	compiled := &compiledPath{}
+	compiled.expression, compiled.expressionErr = jsonpath.NewExpression(string(exp))
+	compiled.reference, compiled.referenceErr = jsonpath.NewReferencePath(string(exp))
+
+	return JSONPath{
+		exp:      exp,
+		compiled: compiled,
+	}
+}
+
+// NullJSONPath represents a path explicitly set to null in a definition. As an InputPath or OutputPath it
+// yields an empty object and as a ResultPath it discards the state's result.
+var NullJSONPath = JSONPath{null: true}
+
+// rootJSONPath is the path of a whole document, which an empty path searches
+var rootJSONPath = NewJSONPath("$")
+
+// newDefinitionJSONPath returns the compiled path of a definition's field, which is null if the field is
+// explicitly null in the definition's JSON
+func newDefinitionJSONPath(exp JSONPathExp, fields definitionFields, name string) JSONPath {
+	if fields.isNull(name) {
+		return NullJSONPath
+	}
+
+	return NewJSONPath(exp)
+}
+
+// compiledJSONPath returns the compiled path of a definition, which is compiled now if the definition
+// wasn't loaded from JSON e.g. it was declared in code
+func compiledJSONPath(path JSONPath, exp JSONPathExp) JSONPath {
+	if path.compiled == nil && !path.null {
+		return NewJSONPath(exp)
+	}
+
+	return path
+}
+
+// String returns the path, which is empty if the path is null
+func (j JSONPath) String() string {
+	return string(j.exp)
+}
+
+// IsEmpty reports whether the path isn't set
+func (j JSONPath) IsEmpty() bool {
+	return j.exp == "" && !j.null
+}
+
+func (j JSONPath) IsNull() bool {
+	return j.null
+}
+
+func (j JSONPath) Validate() error {
+	_, err := j.Expression()
+	return err
+}
+
+// ValidateReference validates the expression as a reference path, which may only identify a single node
+func (j JSONPath) ValidateReference() error {
+	_, err := j.ReferencePath()
+	return err
+}
+
+// Expression returns the compiled path
+func (j JSONPath) Expression() (jsonpath.Expression, error) {
+	if j.compiled == nil {
+		return jsonpath.NewExpression(string(j.exp))
+	}
+
+	return j.compiled.expression, j.compiled.expressionErr
+}
+
+// ReferencePath returns the compiled reference path
+func (j JSONPath) ReferencePath() (jsonpath.Expression, error) {
+	if j.compiled == nil {
+		return jsonpath.NewReferencePath(string(j.exp))
+	}
+
+	return j.compiled.reference, j.compiled.referenceErr
+}
+
+func (j JSONPath) Search(input []byte) ([]byte, error) {
+	if j.IsEmpty() {
 		return input, nil
 	}
 
-	exp, err := jsonpath.NewExpression(string(j))
+	if j.IsNull() {
+		return []byte(`{}`), nil
+	}
+
+	exp, err := j.Expression()
 	if err != nil {
 		return []byte{}, err
 	}
@@ -44,23 +185,181 @@ func (j JSONPathExp) Search(input []byte) ([]byte, error) {
 	return exp.Search(input)
 }
 
+// SearchDocument is equivalent to Search for an input that has already been parsed
+func (j JSONPath) SearchDocument(doc *jsonpath.Document) ([]byte, error) {
+	if j.IsEmpty() {
+		j = rootJSONPath
+	}
+
+	if j.IsNull() {
+		return []byte(`{}`), nil
+	}
+
+	exp, err := j.Expression()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return exp.SearchDocument(doc)
+}
+
+// Set returns a copy of input with value placed at the location the reference path refers to, creating
+// any missing intermediate objects. An empty path or "$" replaces the input entirely.
+func (j JSONPath) Set(input, value []byte) ([]byte, error) {
+	if j.IsEmpty() {
+		return value, nil
+	}
+
+	exp, err := j.ReferencePath()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return exp.Set(input, value)
+}
+
+// definitionFields are the raw fields of a definition, which distinguish the fields explicitly set to null
+// from those that aren't set at all
+type definitionFields map[string]json.RawMessage
+
+func newDefinitionFields(data []byte) (definitionFields, error) {
+	fields := definitionFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func (f definitionFields) isNull(name string) bool {
+	value, ok := f[name]
+	return ok && string(value) == "null"
+}
+
+// ValueExp is either a JSON path or an intrinsic function which resolves a value from a state's input
+// e.g. "$.name" or "States.Format('Hello {}', $.name)"
+type ValueExp string
+
+func (v ValueExp) Validate() error {
+	return v.Compile().err
+}
+
+// validationErrType returns the type of the validation error of the expression if it's invalid
+func (v ValueExp) validationErrType() string {
+	if intrinsic.IsFunction(string(v)) {
+		return InvalidIntrinsicErrType
+	}
+
+	return InvalidJSONPathErrType
+}
+
+// Evaluate resolves the value from the given input. Intrinsic function failures are returned as
+// States.IntrinsicFailure errors.
+func (v ValueExp) Evaluate(input []byte) ([]byte, error) {
+	compiled := v.Compile()
+	if compiled.isFunction {
+		if compiled.err != nil {
+			return []byte{}, compiled.err
+		}
+
+		result, err := compiled.function.Evaluate(input)
+		if err != nil {
+			return []byte{}, NewError(ErrIntrinsicFailureCode, err.Error())
+		}
+		return result, nil
+	}
+
+	return compiled.path.Search(input)
+}
+
+// EvaluateDocument is equivalent to Evaluate for an input that has already been parsed
+func (v ValueExp) EvaluateDocument(doc *jsonpath.Document) ([]byte, error) {
+	return v.Compile().EvaluateDocument(doc)
+}
+
+// Compile compiles the expression, whose errors are returned when it's evaluated
+func (v ValueExp) Compile() *CompiledValueExp {
+	compiled := &CompiledValueExp{exp: v, isFunction: intrinsic.IsFunction(string(v))}
+	if compiled.isFunction {
+		compiled.function, compiled.err = intrinsic.NewFunction(string(v))
+	} else {
+		compiled.path = NewJSONPath(JSONPathExp(v))
+		compiled.err = compiled.path.Validate()
+	}
+
+	return compiled
+}
+
+// CompiledValueExp is a compiled path or intrinsic function e.g. of a payload template or a Fail state,
+// which compile their expressions when they're loaded
+type CompiledValueExp struct {
+	exp        ValueExp
+	isFunction bool
+	function   intrinsic.Function
+	path       JSONPath
+	err        error
+}
+
+// IsEmpty reports whether the expression isn't set
+func (c *CompiledValueExp) IsEmpty() bool {
+	return c.exp == ""
+}
+
+// EvaluateDocument resolves the value from an input that has already been parsed. Intrinsic function
+// failures are returned as States.IntrinsicFailure errors.
+func (c *CompiledValueExp) EvaluateDocument(doc *jsonpath.Document) ([]byte, error) {
+	if c.isFunction {
+		if c.err != nil {
+			return []byte{}, c.err
+		}
+
+		result, err := c.function.EvaluateDocument(doc)
+		if err != nil {
+			return []byte{}, NewError(ErrIntrinsicFailureCode, err.Error())
+		}
+		return result, nil
+	}
+
+	return c.path.SearchDocument(doc)
+}
+
 // TaskDefinition represents an AWS states language task state.
 type TaskDefinition struct {
 	BaseDefinition
 	TransitionDefinition
 	IOPathDefinition
+	ParametersDefinition
+	ResultSelectorDefinition
 	ResultPathDefinition
-	Resource string `json:"Resource"`
-	/*
-		TimeoutSeconds   int    `json:"TimeoutSeconds"`
-		HeartbeatSeconds int    `json:"HeartbeatSeconds"`
-	*/
+	ArgumentsDefinition
+	OutputDefinition
+	AssignDefinition
+	Resource         string `json:"Resource"`
+	TimeoutSeconds   int    `json:"TimeoutSeconds"`
+	HeartbeatSeconds int    `json:"HeartbeatSeconds"`
 }
 
 func (t TaskDefinition) Type() string {
 	return TaskStateType
 }
 
+// UnmarshalJSON unmarshals the definition and compiles its paths
+func (t *TaskDefinition) UnmarshalJSON(data []byte) error {
+	type taskDefinition TaskDefinition
+	if err := json.Unmarshal(data, (*taskDefinition)(t)); err != nil {
+		return err
+	}
+
+	fields, err := newDefinitionFields(data)
+	if err != nil {
+		return err
+	}
+	t.IOPathDefinition.compile(fields)
+	t.ResultPathDefinition.compile(fields)
+
+	return nil
+}
+
 func (t TaskDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -76,10 +375,30 @@ func (t TaskDefinition) Validate() error {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
+	if err := t.ParametersDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := t.ResultSelectorDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
 	if err := t.ResultPathDefinition.Validate(); err != nil {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
+	if err := t.ArgumentsDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := t.OutputDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := t.AssignDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
 	if t.Resource == "" {
 		validationErrs = append(validationErrs, NewValidationError(
 			MissingRequiredFieldErrType,
@@ -87,31 +406,18 @@ func (t TaskDefinition) Validate() error {
 		))
 	}
 
-	if t.InputPathExp != "" {
-		if err := t.InputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"InputPath", string(t.InputPathExp),
-			))
-		}
-	}
-
-	if t.OutputPathExp != "" {
-		if err := t.OutputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"OutputPath", string(t.OutputPathExp),
-			))
-		}
+	if t.TimeoutSeconds < 0 {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidValueErrType,
+			"TimeoutSeconds", strconv.Itoa(t.TimeoutSeconds),
+		))
 	}
 
-	if t.ResultPathExp != "" {
-		if err := t.ResultPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"ResultPath", string(t.ResultPathExp),
-			))
-		}
+	if t.HeartbeatSeconds < 0 || (t.HeartbeatSeconds > 0 && t.TimeoutSeconds > 0 && t.HeartbeatSeconds >= t.TimeoutSeconds) {
+		validationErrs = append(validationErrs, NewValidationError(
+			InvalidValueErrType,
+			"HeartbeatSeconds", strconv.Itoa(t.HeartbeatSeconds),
+		))
 	}
 
 	if len(validationErrs) > 0 {
@@ -120,6 +426,19 @@ func (t TaskDefinition) Validate() error {
 	return nil
 }
 
+// IntegrationPattern returns the service integration pattern of the task's resource, which is suffixed
+// with the pattern unless it's request response e.g. arn:aws:states:::ecs:runTask.sync
+func (t TaskDefinition) IntegrationPattern() string {
+	switch {
+	case strings.HasSuffix(t.Resource, SyncIntegrationPattern), strings.HasSuffix(t.Resource, SyncIntegrationPattern+":2"):
+		return SyncIntegrationPattern
+	case strings.HasSuffix(t.Resource, WaitForTaskTokenIntegrationPattern):
+		return WaitForTaskTokenIntegrationPattern
+	}
+
+	return RequestResponseIntegrationPattern
+}
+
 // RetryDefinition represents an AWS states language retry block
 type RetryDefinition struct {
 	ErrorEquals     []string `json:"ErrorEquals"`
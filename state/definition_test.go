@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
 	"github.com/eggsbenjamin/stepFnLocal/state"
 	"github.com/stretchr/testify/require"
 )
@@ -111,6 +112,184 @@ func TestDefinitions(t *testing.T) {
 					},
 					nil,
 				},
+				{
+					"invalid QueryLanguage",
+					state.MachineDefinition{
+						StartAt:       "test",
+						QueryLanguage: "XPath",
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Succeed"}`),
+						},
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"QueryLanguage", "XPath",
+					),
+				},
+				{
+					"JSONPath state in JSONata machine",
+					state.MachineDefinition{
+						StartAt:       "test",
+						QueryLanguage: state.JSONataQueryLanguage,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Succeed","QueryLanguage":"JSONPath"}`),
+						},
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"QueryLanguage", state.JSONPathQueryLanguage,
+					),
+				},
+				{
+					"JSONPath field in JSONata state",
+					state.MachineDefinition{
+						StartAt:       "test",
+						QueryLanguage: state.JSONataQueryLanguage,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Task","Resource":"Test","End":true,"InputPath":"$.input"}`),
+						},
+					},
+					state.NewValidationError(
+						state.UnsupportedFieldErrType,
+						"InputPath", state.JSONataQueryLanguage,
+					),
+				},
+				{
+					"JSONata field in JSONPath state",
+					state.MachineDefinition{
+						StartAt: "test",
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Task","Resource":"Test","End":true,"Arguments":{"a":1}}`),
+						},
+					},
+					state.NewValidationError(
+						state.UnsupportedFieldErrType,
+						"Arguments", state.JSONPathQueryLanguage,
+					),
+				},
+				{
+					"JSONPath rule in JSONata branch",
+					state.MachineDefinition{
+						StartAt:       "test",
+						QueryLanguage: state.JSONataQueryLanguage,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Parallel","End":true,"Branches":[{"StartAt":"choice","States":{
+								"choice":{"Type":"Choice","Choices":[{"Variable":"$.a","BooleanEquals":true,"Next":"end"}]},
+								"end":{"Type":"Succeed"}
+							}}]}`),
+						},
+					},
+					state.NewValidationError(
+						state.UnsupportedFieldErrType,
+						"Choices.Variable/And/Or/Not", state.JSONataQueryLanguage,
+					),
+				},
+				{
+					"valid JSONata",
+					state.MachineDefinition{
+						StartAt:       "test1",
+						QueryLanguage: state.JSONataQueryLanguage,
+						States: map[string]json.RawMessage{
+							"test1": []byte(`{"Type":"Task","Resource":"Test","Next":"test2","Arguments":{"id":"{% $states.input.id %}"},"Output":"{% $states.result %}"}`),
+							"test2": []byte(`{"Type":"Choice","Choices":[{"Condition":"{% $states.input.ok %}","Next":"test3"}],"Default":"test4"}`),
+							"test3": []byte(`{"Type":"Succeed"}`),
+							"test4": []byte(`{"Type":"Fail","Error":"{% 'Custom.' & $states.input.code %}"}`),
+						},
+					},
+					nil,
+				},
+				{
+					"invalid Type",
+					state.MachineDefinition{
+						StartAt: "test",
+						Type:    "BATCH",
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Succeed"}`),
+						},
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"Type", "BATCH",
+					),
+				},
+				{
+					"express TimeoutSeconds",
+					state.MachineDefinition{
+						StartAt:        "test",
+						Type:           state.ExpressMachineType,
+						TimeoutSeconds: 600,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Succeed"}`),
+						},
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"TimeoutSeconds", "600",
+					),
+				},
+				{
+					"express sync task",
+					state.MachineDefinition{
+						StartAt: "test",
+						Type:    state.ExpressMachineType,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Task","Resource":"arn:aws:states:::states:startExecution.sync:2","End":true}`),
+						},
+					},
+					state.NewValidationError(
+						state.UnsupportedPatternErrType,
+						"Resource", "arn:aws:states:::states:startExecution.sync:2",
+					),
+				},
+				{
+					"express branch waitForTaskToken task",
+					state.MachineDefinition{
+						StartAt: "test",
+						Type:    state.ExpressMachineType,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Parallel","End":true,"Branches":[{"StartAt":"task","States":{
+								"task":{"Type":"Task","Resource":"arn:aws:states:::sqs:sendMessage.waitForTaskToken","End":true}
+							}}]}`),
+						},
+					},
+					state.NewValidationError(
+						state.UnsupportedPatternErrType,
+						"Resource", "arn:aws:states:::sqs:sendMessage.waitForTaskToken",
+					),
+				},
+				{
+					"standard sync task",
+					state.MachineDefinition{
+						StartAt: "test",
+						Type:    state.StandardMachineType,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Task","Resource":"arn:aws:states:::states:startExecution.sync","End":true}`),
+						},
+					},
+					nil,
+				},
+				{
+					"valid express",
+					state.MachineDefinition{
+						StartAt:        "test",
+						Type:           state.ExpressMachineType,
+						TimeoutSeconds: 300,
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Task","Resource":"arn:aws:lambda:us-east-1:123456789012:function:test","End":true}`),
+						},
+					},
+					nil,
+				},
+				{
+					"valid JSONata state in JSONPath machine",
+					state.MachineDefinition{
+						StartAt: "test",
+						States: map[string]json.RawMessage{
+							"test": []byte(`{"Type":"Pass","QueryLanguage":"JSONata","End":true,"Output":{"a":"{% 1 + 1 %}"}}`),
+						},
+					},
+					nil,
+				},
 			}
 
 			for _, tt := range tests {
@@ -323,6 +502,39 @@ func TestDefinitions(t *testing.T) {
 						"Resource", "",
 					),
 				},
+				{
+					"invalid ResultSelector",
+					state.TaskDefinition{
+						ResultSelectorDefinition: state.ResultSelectorDefinition{
+							ResultSelectorTemplate: state.NewPayloadTemplate([]byte(`{"id.$":"invalid json path"}`)),
+						},
+					},
+					state.NewValidationError(
+						state.InvalidJSONPathErrType,
+						"ResultSelector.id.$", "invalid json path",
+					),
+				},
+				{
+					"negative TimeoutSeconds",
+					state.TaskDefinition{
+						TimeoutSeconds: -1,
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"TimeoutSeconds", "-1",
+					),
+				},
+				{
+					"HeartbeatSeconds not less than TimeoutSeconds",
+					state.TaskDefinition{
+						TimeoutSeconds:   10,
+						HeartbeatSeconds: 10,
+					},
+					state.NewValidationError(
+						state.InvalidValueErrType,
+						"HeartbeatSeconds", "10",
+					),
+				},
 				{
 					"valid",
 					state.TaskDefinition{
@@ -336,6 +548,21 @@ func TestDefinitions(t *testing.T) {
 					},
 					nil,
 				},
+				{
+					"valid with timeouts",
+					state.TaskDefinition{
+						BaseDefinition: state.BaseDefinition{
+							StateType: state.TaskStateType,
+						},
+						TransitionDefinition: state.TransitionDefinition{
+							EndState: true,
+						},
+						Resource:         "test",
+						TimeoutSeconds:   10,
+						HeartbeatSeconds: 5,
+					},
+					nil,
+				},
 			}
 
 			for _, tt := range tests {
@@ -355,6 +582,27 @@ func TestDefinitions(t *testing.T) {
 		})
 	})
 
+	t.Run("TaskDefinition", func(t *testing.T) {
+		t.Run("Validate reports invalid paths once", func(t *testing.T) {
+			def := state.TaskDefinition{
+				BaseDefinition: state.BaseDefinition{
+					StateType: state.TaskStateType,
+				},
+				TransitionDefinition: state.TransitionDefinition{
+					EndState: true,
+				},
+				IOPathDefinition: state.IOPathDefinition{
+					InputPathExp: "invalid json path",
+				},
+				Resource: "test",
+			}
+
+			err := def.Validate()
+			require.Error(t, err)
+			require.Len(t, err.(state.ValidationErrors), 1)
+		})
+	})
+
 	t.Run("PassDefinition", func(t *testing.T) {
 		t.Run("Validate", func(t *testing.T) {
 			tests := []struct {
@@ -401,31 +649,81 @@ func TestDefinitions(t *testing.T) {
 				expectedError *state.ValidationError
 			}{
 				{
-					"missing error field",
+					"Error with ErrorPath",
 					state.FailDefinition{
 						BaseDefinition: state.BaseDefinition{
 							StateType: state.FailStateType,
 						},
-						Cause: "test",
+						Error:     "test",
+						ErrorPath: "$.error",
 					},
 					state.NewValidationError(
-						state.MissingRequiredFieldErrType,
-						"Error", "",
+						state.InvalidCombinationErrType,
+						state.OnlyOneMustExistErrMsg,
+						"Error/ErrorPath",
 					),
 				},
 				{
-					"missing cause field",
+					"Cause with CausePath",
 					state.FailDefinition{
 						BaseDefinition: state.BaseDefinition{
 							StateType: state.FailStateType,
 						},
-						Error: "test",
+						Cause:     "test",
+						CausePath: "$.cause",
 					},
 					state.NewValidationError(
-						state.MissingRequiredFieldErrType,
-						"Cause", "",
+						state.InvalidCombinationErrType,
+						state.OnlyOneMustExistErrMsg,
+						"Cause/CausePath",
 					),
 				},
+				{
+					"invalid ErrorPath",
+					state.FailDefinition{
+						BaseDefinition: state.BaseDefinition{
+							StateType: state.FailStateType,
+						},
+						ErrorPath: "invalid json path",
+					},
+					state.NewValidationError(
+						state.InvalidJSONPathErrType,
+						"ErrorPath", "invalid json path",
+					),
+				},
+				{
+					"invalid CausePath intrinsic function",
+					state.FailDefinition{
+						BaseDefinition: state.BaseDefinition{
+							StateType: state.FailStateType,
+						},
+						CausePath: "States.Unknown($.cause)",
+					},
+					state.NewValidationError(
+						state.InvalidIntrinsicErrType,
+						"CausePath", "States.Unknown($.cause)",
+					),
+				},
+				{
+					"valid without error or cause",
+					state.FailDefinition{
+						BaseDefinition: state.BaseDefinition{
+							StateType: state.FailStateType,
+						},
+					},
+					nil,
+				},
+				{
+					"valid with paths",
+					state.FailDefinition{
+						BaseDefinition: state.BaseDefinition{
+							StateType: state.FailStateType,
+						},
+						ErrorPath: "$.error",
+						CausePath: "States.Format('failed: {}', $.cause)",
+					},
+					nil,
+				},
 				{
 					"valid",
 					state.FailDefinition{
@@ -454,6 +752,26 @@ func TestDefinitions(t *testing.T) {
 				})
 			}
 		})
+
+		t.Run("UnmarshalJSON", func(t *testing.T) {
+			var def state.FailDefinition
+			require.NoError(t, json.Unmarshal([]byte(`{"Type":"Fail","ErrorPath":"$.error","CausePath":"States.Format('{}', $.cause)"}`), &def))
+
+			compiled := def.CompiledErrorPath()
+			require.True(t, compiled == def.CompiledErrorPath())
+			require.True(t, def.CompiledCausePath() == def.CompiledCausePath())
+
+			doc, err := jsonpath.NewDocument([]byte(`{"error":"MyError","cause":"my cause"}`))
+			require.NoError(t, err)
+
+			result, err := compiled.EvaluateDocument(doc)
+			require.NoError(t, err)
+			require.Equal(t, `"MyError"`, string(result))
+
+			result, err = def.CompiledCausePath().EvaluateDocument(doc)
+			require.NoError(t, err)
+			require.Equal(t, `"my cause"`, string(result))
+		})
 	})
 
 	t.Run("ParallelDefinition", func(t *testing.T) {
@@ -2,7 +2,8 @@ package state
 
 import (
 	"encoding/json"
-	"errors"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -11,8 +12,12 @@ const (
 	InvalidKeyErrType           = "Invalid Key"
 	InvalidValueErrType         = "Invalid Value"
 	InvalidJSONPathErrType      = "Invalid JSON path expression"
+	InvalidIntrinsicErrType     = "Invalid intrinsic function"
 	InvalidCombinationErrType   = "Invalid Combination"
 	NonRFC3339TimeStampErrType  = "Non RFC3339 timestamp"
+	InvalidJSONataErrType       = "Invalid JSONata expression"
+	UnsupportedFieldErrType     = "Field not supported by query language"
+	UnsupportedPatternErrType   = "Integration pattern not supported by workflow type"
 
 	OnlyOneMustExistErrMsg = "Only one must exist"
 )
@@ -21,11 +26,18 @@ var (
 	// states language error codes
 	ErrAllCode                    = "States.ALL"
 	ErrTimeoutCode                = "States.Timeout"
+	ErrHeartbeatTimeoutCode       = "States.HeartbeatTimeout"
 	ErrTaskFailedCode             = "States.TaskFailed"
 	ErrTaskPermissionsCode        = "States.TaskPermissions"
+	ErrPermissionsCode            = "States.Permissions"
 	ErrResultPathMatchFailureCode = "States.ResultPathMatchFailure"
+	ErrParameterPathFailureCode   = "States.ParameterPathFailure"
+	ErrQueryEvaluationErrorCode   = "States.QueryEvaluationError"
 	ErrBranchFailedCode           = "States.BranchFailed"
 	ErrNoChoiceMatchedCode        = "States.NoChoiceMatched"
+	ErrIntrinsicFailureCode       = "States.IntrinsicFailure"
+	ErrDataLimitExceededCode      = "States.DataLimitExceeded"
+	ErrRuntimeCode                = "States.Runtime"
 
 	// internal errors
 	ErrStateNotFound = errors.New("state not found")
@@ -48,6 +60,16 @@ func NewError(name string, cause string) Error {
 	}
 }
 
+// AsError returns err as an Error. If the cause of err is already an Error it is returned unchanged,
+// otherwise a new Error is created with the given name and the error message as its cause.
+func AsError(err error, name string) Error {
+	if stateErr, ok := errors.Cause(err).(Error); ok {
+		return stateErr
+	}
+
+	return NewError(name, err.Error())
+}
+
 // ValidationError represents a single AWS states language validation error.
 type ValidationError struct {
 	Type  string
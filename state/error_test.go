@@ -0,0 +1,29 @@
+// +build unit
+
+package state_test
+
+import (
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	t.Run("AsError", func(t *testing.T) {
+		t.Run("states language error", func(t *testing.T) {
+			stateErr := state.NewError(state.ErrTimeoutCode, "timed out")
+
+			err := state.AsError(errors.Wrap(stateErr, "wrapped"), state.ErrRuntimeCode)
+			require.Equal(t, stateErr, err)
+		})
+
+		t.Run("other error", func(t *testing.T) {
+			expectedErr := state.NewError(state.ErrRuntimeCode, "error")
+
+			err := state.AsError(errors.New("error"), state.ErrRuntimeCode)
+			require.Equal(t, expectedErr, err)
+		})
+	})
+}
@@ -0,0 +1,8 @@
+// +build unit
+
+package state
+
+// CompiledPath exposes the compiled form of a path, which is shared by every copy of the path
+func CompiledPath(j JSONPath) *compiledPath {
+	return j.compiled
+}
@@ -1,15 +1,51 @@
 package state
 
+import "encoding/json"
+
 type FailDefinition struct {
 	BaseDefinition
-	Error string `json:"Error"`
-	Cause string `json:"Cause"`
+	Error     string   `json:"Error"`
+	ErrorPath ValueExp `json:"ErrorPath"`
+	Cause     string   `json:"Cause"`
+	CausePath ValueExp `json:"CausePath"`
+	errorPath *CompiledValueExp
+	causePath *CompiledValueExp
 }
 
 func (FailDefinition) Type() string {
 	return FailStateType
 }
 
+// UnmarshalJSON unmarshals the definition and compiles its paths
+func (s *FailDefinition) UnmarshalJSON(data []byte) error {
+	type failDefinition FailDefinition
+	if err := json.Unmarshal(data, (*failDefinition)(s)); err != nil {
+		return err
+	}
+
+	s.errorPath = s.ErrorPath.Compile()
+	s.causePath = s.CausePath.Compile()
+	return nil
+}
+
+// CompiledErrorPath returns the compiled ErrorPath
+func (s FailDefinition) CompiledErrorPath() *CompiledValueExp {
+	if s.errorPath == nil {
+		return s.ErrorPath.Compile()
+	}
+
+	return s.errorPath
+}
+
+// CompiledCausePath returns the compiled CausePath
+func (s FailDefinition) CompiledCausePath() *CompiledValueExp {
+	if s.causePath == nil {
+		return s.CausePath.Compile()
+	}
+
+	return s.causePath
+}
+
 func (s FailDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -17,20 +53,40 @@ func (s FailDefinition) Validate() error {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if s.Error == "" {
+	if s.Error != "" && s.ErrorPath != "" {
 		validationErrs = append(validationErrs, NewValidationError(
-			MissingRequiredFieldErrType,
-			"Error", "",
+			InvalidCombinationErrType,
+			OnlyOneMustExistErrMsg,
+			"Error/ErrorPath",
 		))
 	}
 
-	if s.Cause == "" {
+	if s.Cause != "" && s.CausePath != "" {
 		validationErrs = append(validationErrs, NewValidationError(
-			MissingRequiredFieldErrType,
-			"Cause", "",
+			InvalidCombinationErrType,
+			OnlyOneMustExistErrMsg,
+			"Cause/CausePath",
 		))
 	}
 
+	if s.ErrorPath != "" {
+		if err := s.ErrorPath.Validate(); err != nil {
+			validationErrs = append(validationErrs, NewValidationError(
+				s.ErrorPath.validationErrType(),
+				"ErrorPath", string(s.ErrorPath),
+			))
+		}
+	}
+
+	if s.CausePath != "" {
+		if err := s.CausePath.Validate(); err != nil {
+			validationErrs = append(validationErrs, NewValidationError(
+				s.CausePath.validationErrType(),
+				"CausePath", string(s.CausePath),
+			))
+		}
+	}
+
 	if len(validationErrs) > 0 {
 		return validationErrs
 	}
@@ -0,0 +1,190 @@
+package state
+
+import (
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
+	"github.com/pkg/errors"
+)
+
+// IOProcessor applies the AWS states language input and output processing pipeline to a state:
+//
+//	InputPath -> Parameters -> (state) -> ResultSelector -> ResultPath -> OutputPath
+//
+// or, for JSONata states:
+//
+//	Arguments -> (state) -> Output
+//
+// Each step is only applied when the state's definition implements the corresponding interface. Paths and
+// expressions can reference the variables of the state's scope. All errors returned are states language
+// errors.
+type IOProcessor struct {
+	def           Definition
+	queryLanguage string
+	variables     Variables
+}
+
+func NewIOProcessor(def Definition, queryLanguage string, variables Variables) IOProcessor {
+	return IOProcessor{
+		def:           def,
+		queryLanguage: queryLanguage,
+		variables:     variables,
+	}
+}
+
+// ProcessInput returns the effective input of a state from its raw input by applying InputPath and Parameters
+func (p IOProcessor) ProcessInput(rawInput []byte) ([]byte, error) {
+	if p.queryLanguage == JSONataQueryLanguage {
+		return p.processJSONataInput(rawInput)
+	}
+
+	input := rawInput
+
+	if v, ok := p.def.(InputPather); ok {
+		var err error
+		input, err = p.search(v.InputJSONPath(), input)
+		if err != nil {
+			return []byte{}, AsError(errors.Wrap(err, "error applying InputPath"), ErrRuntimeCode)
+		}
+	}
+
+	if v, ok := p.def.(Parameterizer); ok && !v.Parameters().IsEmpty() {
+		var err error
+		input, err = p.resolve(v.Parameters(), input)
+		if err != nil {
+			return []byte{}, AsError(errors.Wrap(err, "error applying Parameters"), ErrParameterPathFailureCode)
+		}
+	}
+
+	return input, nil
+}
+
+// ProcessOutput returns the output of a state from its raw input and result by applying ResultSelector,
+// ResultPath and OutputPath. The result after ResultSelector has been applied is also returned, for the
+// state's Assign to be evaluated against, as the selector is only evaluated once.
+func (p IOProcessor) ProcessOutput(rawInput, result []byte) ([]byte, []byte, error) {
+	if p.queryLanguage == JSONataQueryLanguage {
+		output, err := p.processJSONataOutput(rawInput, result)
+		if err != nil {
+			return []byte{}, []byte{}, err
+		}
+		return output, result, nil
+	}
+
+	selected, err := p.selectResult(result)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	output := selected
+
+	if v, ok := p.def.(ResultPather); ok {
+		if resultPath := v.ResultJSONPath(); resultPath.IsNull() {
+			output = rawInput
+		} else {
+			output, err = resultPath.Set(rawInput, output)
+			if err != nil {
+				return []byte{}, []byte{}, AsError(errors.Wrap(err, "error applying ResultPath"), ErrResultPathMatchFailureCode)
+			}
+		}
+	}
+
+	if v, ok := p.def.(OutputPather); ok {
+		output, err = p.search(v.OutputJSONPath(), output)
+		if err != nil {
+			return []byte{}, []byte{}, AsError(errors.Wrap(err, "error applying OutputPath"), ErrRuntimeCode)
+		}
+	}
+
+	return output, selected, nil
+}
+
+// processJSONataInput returns the effective input of a JSONata state by evaluating Arguments against
+// $states.input
+func (p IOProcessor) processJSONataInput(rawInput []byte) ([]byte, error) {
+	v, ok := p.def.(Argumenter)
+	if !ok || v.Arguments().IsEmpty() {
+		return rawInput, nil
+	}
+
+	bindings, err := NewJSONataBindings(rawInput, nil, p.variables)
+	if err != nil {
+		return []byte{}, AsError(errors.Wrap(err, "error applying Arguments"), ErrQueryEvaluationErrorCode)
+	}
+
+	input, err := v.Arguments().Evaluate(bindings)
+	if err != nil {
+		return []byte{}, AsError(errors.Wrap(err, "error applying Arguments"), ErrQueryEvaluationErrorCode)
+	}
+
+	return input, nil
+}
+
+// processJSONataOutput returns the output of a JSONata state by evaluating Output against $states.input
+// and $states.result
+func (p IOProcessor) processJSONataOutput(rawInput, result []byte) ([]byte, error) {
+	v, ok := p.def.(Outputter)
+	if !ok || v.Output().IsEmpty() {
+		return result, nil
+	}
+
+	bindings, err := NewJSONataBindings(rawInput, result, p.variables)
+	if err != nil {
+		return []byte{}, AsError(errors.Wrap(err, "error applying Output"), ErrQueryEvaluationErrorCode)
+	}
+
+	output, err := v.Output().Evaluate(bindings)
+	if err != nil {
+		return []byte{}, AsError(errors.Wrap(err, "error applying Output"), ErrQueryEvaluationErrorCode)
+	}
+
+	return output, nil
+}
+
+// ProcessAssign returns the variables assigned by a state from its raw input and the result selected by
+// ProcessOutput i.e. after ResultSelector has been applied.
+func (p IOProcessor) ProcessAssign(assign AssignTemplate, rawInput, selected []byte) (Variables, error) {
+	variables, err := assign.Evaluate(p.queryLanguage, rawInput, selected, p.variables)
+	if err != nil {
+		return nil, AsError(err, ErrRuntimeCode)
+	}
+
+	return variables, nil
+}
+
+// selectResult applies ResultSelector to the result of a state
+func (p IOProcessor) selectResult(result []byte) ([]byte, error) {
+	v, ok := p.def.(ResultSelectorer)
+	if !ok || v.ResultSelector().IsEmpty() {
+		return result, nil
+	}
+
+	selected, err := p.resolve(v.ResultSelector(), result)
+	if err != nil {
+		return []byte{}, AsError(errors.Wrap(err, "error applying ResultSelector"), ErrRuntimeCode)
+	}
+
+	return selected, nil
+}
+
+// search evaluates a path against the input. Only paths rooted at a variable need the variables.
+func (p IOProcessor) search(path JSONPath, input []byte) ([]byte, error) {
+	if !jsonpath.IsVariable(path.String()) {
+		return path.Search(input)
+	}
+
+	doc, err := p.variables.Document(input)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return path.SearchDocument(doc)
+}
+
+// resolve resolves a payload template against the input
+func (p IOProcessor) resolve(template PayloadTemplate, input []byte) ([]byte, error) {
+	doc, err := p.variables.Document(input)
+	if err != nil {
+		return []byte{}, errors.Wrap(err, "error unmarshaling input")
+	}
+
+	return template.ResolveDocument(doc)
+}
@@ -0,0 +1,225 @@
+// +build unit
+
+package state_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIOProcessor(t *testing.T) {
+	getDefinition := func(t *testing.T, rawDef string) state.Definition {
+		def, err := state.MachineStates{"test": json.RawMessage(rawDef)}.GetDefinition("test")
+		require.NoError(t, err)
+		return def
+	}
+
+	t.Run("pipeline", func(t *testing.T) {
+		tests := []struct {
+			title                  string
+			def                    string
+			rawInput               string
+			result                 string
+			expectedEffectiveInput string
+			expectedOutput         string
+		}{
+			{
+				"task defaults",
+				`{"Type":"Task","Resource":"test","End":true}`,
+				`{"input":1}`,
+				`{"result":1}`,
+				`{"input":1}`,
+				`{"result":1}`,
+			},
+			{
+				"task all fields",
+				`{
+					"Type": "Task",
+					"Resource": "test",
+					"End": true,
+					"InputPath": "$.request",
+					"Parameters": {"id.$": "$.id", "static": true},
+					"ResultSelector": {"status.$": "$.Payload.status"},
+					"ResultPath": "$.response",
+					"OutputPath": "$.response"
+				}`,
+				`{"request":{"id":1},"other":"value"}`,
+				`{"Payload":{"status":"done","ignored":1}}`,
+				`{"id":1,"static":true}`,
+				`{"status":"done"}`,
+			},
+			{
+				"parallel ResultPath keeps raw input",
+				`{
+					"Type": "Parallel",
+					"End": true,
+					"InputPath": "$.request",
+					"ResultPath": "$.results",
+					"Branches": [{"StartAt": "s", "States": {"s": {"Type": "Succeed"}}}]
+				}`,
+				`{"request":1}`,
+				`[1]`,
+				`1`,
+				`{"request":1,"results":[1]}`,
+			},
+			{
+				"null ResultPath",
+				`{"Type":"Task","Resource":"test","End":true,"ResultPath":null}`,
+				`{"input":1}`,
+				`{"result":1}`,
+				`{"input":1}`,
+				`{"input":1}`,
+			},
+			{
+				"null OutputPath",
+				`{"Type":"Task","Resource":"test","End":true,"OutputPath":null}`,
+				`{"input":1}`,
+				`{"result":1}`,
+				`{"input":1}`,
+				`{}`,
+			},
+			{
+				"choice has no ResultPath",
+				`{"Type":"Choice","InputPath":"$.a","OutputPath":"$.b","Choices":[{"Variable":"$.b","BooleanEquals":true,"Next":"x"}]}`,
+				`{"a":{"b":true}}`,
+				`{"b":true}`,
+				`{"b":true}`,
+				`true`,
+			},
+			{
+				"succeed OutputPath",
+				`{"Type":"Succeed","OutputPath":"$.a"}`,
+				`{"a":1}`,
+				`{"a":1}`,
+				`{"a":1}`,
+				`1`,
+			},
+			{
+				"fail has no processing",
+				`{"Type":"Fail","Error":"test"}`,
+				`{"a":1}`,
+				`{"a":1}`,
+				`{"a":1}`,
+				`{"a":1}`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				processor := state.NewIOProcessor(getDefinition(t, tt.def), state.JSONPathQueryLanguage, nil)
+
+				effectiveInput, err := processor.ProcessInput([]byte(tt.rawInput))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedEffectiveInput, string(effectiveInput))
+
+				output, _, err := processor.ProcessOutput([]byte(tt.rawInput), []byte(tt.result))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(output))
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			title        string
+			def          string
+			expectedName string
+		}{
+			{
+				"Parameters path failure",
+				`{"Type":"Task","Resource":"test","End":true,"Parameters":{"id.$":"$.missing"}}`,
+				state.ErrParameterPathFailureCode,
+			},
+			{
+				"ResultSelector path failure",
+				`{"Type":"Task","Resource":"test","End":true,"ResultSelector":{"id.$":"$.missing"}}`,
+				state.ErrRuntimeCode,
+			},
+			{
+				"ResultPath match failure",
+				`{"Type":"Task","Resource":"test","End":true,"ResultPath":"$.input.result"}`,
+				state.ErrResultPathMatchFailureCode,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				processor := state.NewIOProcessor(getDefinition(t, tt.def), state.JSONPathQueryLanguage, nil)
+				rawInput := []byte(`{"input":1}`)
+
+				effectiveInput, err := processor.ProcessInput(rawInput)
+				if err == nil {
+					_, _, err = processor.ProcessOutput(rawInput, effectiveInput)
+				}
+
+				require.Error(t, err)
+				stateErr, ok := err.(state.Error)
+				require.True(t, ok)
+				require.Equal(t, tt.expectedName, stateErr.Name)
+			})
+		}
+	})
+
+	t.Run("JSONata", func(t *testing.T) {
+		tests := []struct {
+			title                  string
+			def                    string
+			expectedEffectiveInput string
+			expectedOutput         string
+		}{
+			{
+				"defaults",
+				`{"Type":"Task","Resource":"test","End":true}`,
+				`{"request":{"id":1}}`,
+				`{"status":"done"}`,
+			},
+			{
+				"Arguments and Output",
+				`{
+					"Type": "Task",
+					"Resource": "test",
+					"End": true,
+					"Arguments": {"id": "{% $states.input.request.id %}", "static": true},
+					"Output": {"id": "{% $states.input.request.id %}", "status": "{% $uppercase($states.result.status) %}"}
+				}`,
+				`{"id":1,"static":true}`,
+				`{"id":1,"status":"DONE"}`,
+			},
+			{
+				"undefined fields omitted",
+				`{"Type":"Pass","End":true,"Output":{"missing":"{% $states.input.missing %}","kept":1}}`,
+				`{"request":{"id":1}}`,
+				`{"kept":1}`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				processor := state.NewIOProcessor(getDefinition(t, tt.def), state.JSONataQueryLanguage, nil)
+				rawInput := []byte(`{"request":{"id":1}}`)
+
+				effectiveInput, err := processor.ProcessInput(rawInput)
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedEffectiveInput, string(effectiveInput))
+
+				output, _, err := processor.ProcessOutput(rawInput, []byte(`{"status":"done"}`))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(output))
+			})
+		}
+
+		t.Run("evaluation error", func(t *testing.T) {
+			def := `{"Type":"Task","Resource":"test","End":true,"Arguments":"{% $states.input.missing %}"}`
+			processor := state.NewIOProcessor(getDefinition(t, def), state.JSONataQueryLanguage, nil)
+
+			_, err := processor.ProcessInput([]byte(`{}`))
+			require.Error(t, err)
+			stateErr, ok := err.(state.Error)
+			require.True(t, ok)
+			require.Equal(t, state.ErrQueryEvaluationErrorCode, stateErr.Name)
+		})
+	})
+}
@@ -0,0 +1,364 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/eggsbenjamin/stepFnLocal/jsonata"
+	"github.com/pkg/errors"
+)
+
+const (
+	JSONPathQueryLanguage = "JSONPath"
+	JSONataQueryLanguage  = "JSONata"
+)
+
+var validQueryLanguages = map[string]struct{}{
+	JSONPathQueryLanguage: {},
+	JSONataQueryLanguage:  {},
+}
+
+// IsJSONataExpression reports whether a string is a JSONata expression i.e. is enclosed in {% %}
+func IsJSONataExpression(str string) bool {
+	return strings.HasPrefix(str, "{%") && strings.HasSuffix(str, "%}") && len(str) >= 4
+}
+
+// JSONataExp is a string which may be a JSONata expression e.g. the Condition of a choice rule. The
+// expression is compiled when the string is created or unmarshaled and errors compiling it are reported
+// by validation.
+type JSONataExp struct {
+	str string
+	exp jsonata.Expression
+	err error
+}
+
+func NewJSONataExp(str string) JSONataExp {
+	j := JSONataExp{str: str}
+	if IsJSONataExpression(str) {
+		j.exp, j.err = jsonata.Compile(strings.TrimSpace(str[2 : len(str)-2]))
+	}
+
+	return j
+}
+
+func (j JSONataExp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.str)
+}
+
+func (j *JSONataExp) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	*j = NewJSONataExp(str)
+	return nil
+}
+
+func (j JSONataExp) String() string {
+	return j.str
+}
+
+// IsEmpty reports whether the string isn't set
+func (j JSONataExp) IsEmpty() bool {
+	return j.str == ""
+}
+
+// IsExpression reports whether the string is a JSONata expression i.e. is enclosed in {% %}
+func (j JSONataExp) IsExpression() bool {
+	return IsJSONataExpression(j.str)
+}
+
+func (j JSONataExp) Validate() error {
+	return j.err
+}
+
+// Evaluate returns the result of the expression, or the string itself if it isn't an expression.
+// Failures are returned as States.QueryEvaluationError errors.
+func (j JSONataExp) Evaluate(bindings map[string]interface{}) (interface{}, error) {
+	if !j.IsExpression() {
+		return j.str, nil
+	}
+
+	if j.err != nil {
+		return nil, NewError(ErrQueryEvaluationErrorCode, j.err.Error())
+	}
+
+	result, err := j.exp.Evaluate(nil, bindings)
+	if err != nil {
+		return nil, NewError(ErrQueryEvaluationErrorCode, errors.Wrapf(err, "error evaluating '%s'", j.str).Error())
+	}
+	return result, nil
+}
+
+// JSONataTemplate is a JSON value in which any string enclosed in {% %} is a JSONata expression e.g.
+// {"total": "{% $sum($states.input.items.price) %}"}. It's used for the Arguments and Output fields of
+// JSONata states. The expressions are compiled when the template is created or unmarshaled.
+type JSONataTemplate struct {
+	raw      json.RawMessage
+	template interface{}
+	err      error
+}
+
+func NewJSONataTemplate(data []byte) JSONataTemplate {
+	template, err := jsonata.Decode(data)
+	if err != nil {
+		return JSONataTemplate{raw: data, err: err}
+	}
+
+	return JSONataTemplate{
+		raw:      data,
+		template: compileJSONataTemplate(template),
+	}
+}
+
+func (j JSONataTemplate) MarshalJSON() ([]byte, error) {
+	return j.raw.MarshalJSON()
+}
+
+func (j *JSONataTemplate) UnmarshalJSON(data []byte) error {
+	*j = NewJSONataTemplate(append(json.RawMessage{}, data...))
+	return nil
+}
+
+// IsEmpty reports whether the template isn't set
+func (j JSONataTemplate) IsEmpty() bool {
+	return j.raw == nil
+}
+
+// Validate validates every expression in the template. Errors identify the expression by the field name
+// of the template and the keys leading to the expression e.g. "Arguments.total".
+func (j JSONataTemplate) Validate(field string) error {
+	if j.err != nil {
+		return ValidationErrors{NewValidationError(InvalidValueErrType, field, string(j.raw))}
+	}
+
+	validationErrs := ValidationErrors{}
+	walkJSONataTemplate(j.template, field, func(field string, exp JSONataExp) {
+		if err := exp.Validate(); err != nil {
+			validationErrs = append(validationErrs, NewValidationError(InvalidJSONataErrType, field, exp.String()))
+		}
+	})
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+// compileJSONataTemplate returns a copy of the template in which the expressions are compiled
+func compileJSONataTemplate(template interface{}) interface{} {
+	switch v := template.(type) {
+	case string:
+		if IsJSONataExpression(v) {
+			return NewJSONataExp(v)
+		}
+	case map[string]interface{}:
+		compiled := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			compiled[key] = compileJSONataTemplate(value)
+		}
+		return compiled
+	case []interface{}:
+		compiled := make([]interface{}, len(v))
+		for i, value := range v {
+			compiled[i] = compileJSONataTemplate(value)
+		}
+		return compiled
+	}
+
+	return template
+}
+
+func walkJSONataTemplate(template interface{}, field string, fn func(field string, exp JSONataExp)) {
+	switch v := template.(type) {
+	case JSONataExp:
+		fn(field, v)
+	case map[string]interface{}:
+		for key, value := range v {
+			walkJSONataTemplate(value, field+"."+key, fn)
+		}
+	case []interface{}:
+		for _, value := range v {
+			walkJSONataTemplate(value, field, fn)
+		}
+	}
+}
+
+// Evaluate replaces every expression in the template with its result. The bindings are available to
+// expressions as variables e.g. $states. Object fields and array items that evaluate to undefined are
+// omitted. Failures are returned as States.QueryEvaluationError errors.
+func (j JSONataTemplate) Evaluate(bindings map[string]interface{}) ([]byte, error) {
+	if j.err != nil {
+		return []byte{}, errors.Wrap(j.err, "error unmarshaling template")
+	}
+
+	result, err := evaluateJSONataTemplate(j.template, bindings)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return encodeJSONataResult(result)
+}
+
+// encodeJSONataResult returns the JSON of the result of a template or expression
+func encodeJSONataResult(result interface{}) ([]byte, error) {
+	if result == jsonata.Undefined {
+		return []byte{}, NewError(ErrQueryEvaluationErrorCode, "the JSONata expression returned undefined")
+	}
+
+	output, err := jsonata.Encode(result)
+	if err != nil {
+		return []byte{}, NewError(ErrQueryEvaluationErrorCode, err.Error())
+	}
+	return output, nil
+}
+
+func evaluateJSONataTemplate(template interface{}, bindings map[string]interface{}) (interface{}, error) {
+	switch v := template.(type) {
+	case JSONataExp:
+		return v.Evaluate(bindings)
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, value := range v {
+			resolved, err := evaluateJSONataTemplate(value, bindings)
+			if err != nil {
+				return nil, err
+			}
+			if resolved != jsonata.Undefined {
+				result[key] = resolved
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := []interface{}{}
+		for _, value := range v {
+			resolved, err := evaluateJSONataTemplate(value, bindings)
+			if err != nil {
+				return nil, err
+			}
+			if resolved != jsonata.Undefined {
+				result = append(result, resolved)
+			}
+		}
+		return result, nil
+	}
+
+	return template, nil
+}
+
+// NewJSONataBindings returns the variables available to the expressions of a state, the workflow
+// variables and $states. A nil result is omitted i.e. before the state has run.
+func NewJSONataBindings(input, result []byte, variables Variables) (map[string]interface{}, error) {
+	bindings := map[string]interface{}{}
+	states := map[string]interface{}{}
+
+	for name, rawValue := range variables {
+		if name == contextVariable {
+			context, err := jsonata.Decode(rawValue)
+			if err != nil {
+				return nil, errors.Wrap(err, "error unmarshaling context object")
+			}
+			states["context"] = context
+			continue
+		}
+
+		value, err := jsonata.Decode(rawValue)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error unmarshaling variable '%s'", name)
+		}
+		bindings[name] = value
+	}
+
+	value, err := jsonata.Decode(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling input")
+	}
+	states["input"] = value
+
+	if result != nil {
+		value, err := jsonata.Decode(result)
+		if err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling result")
+		}
+		states["result"] = value
+	}
+
+	bindings[reservedVariable] = states
+	return bindings, nil
+}
+
+// EvaluateJSONataString evaluates a string field which may be a JSONata expression e.g. the Error of
+// a Fail state. A string that isn't an expression is returned unchanged.
+func EvaluateJSONataString(str string, bindings map[string]interface{}) (string, error) {
+	if !IsJSONataExpression(str) {
+		return str, nil
+	}
+
+	result, err := evaluateJSONataExp(NewJSONataExp(str), bindings)
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	if err := json.Unmarshal(result, &value); err != nil {
+		return "", NewError(ErrQueryEvaluationErrorCode, "the JSONata expression '"+str+"' must return a string")
+	}
+	return value, nil
+}
+
+// EvaluateJSONataCondition evaluates the Condition of a JSONata choice rule, which must return a boolean
+func EvaluateJSONataCondition(condition JSONataExp, bindings map[string]interface{}) (bool, error) {
+	result, err := evaluateJSONataExp(condition, bindings)
+	if err != nil {
+		return false, err
+	}
+
+	var value bool
+	if err := json.Unmarshal(result, &value); err != nil {
+		return false, NewError(ErrQueryEvaluationErrorCode, "the JSONata expression '"+condition.String()+"' must return a boolean")
+	}
+	return value, nil
+}
+
+func evaluateJSONataExp(exp JSONataExp, bindings map[string]interface{}) ([]byte, error) {
+	result, err := exp.Evaluate(bindings)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return encodeJSONataResult(result)
+}
+
+// ArgumentsDefinition represents the Arguments of a JSONata state, the JSONata equivalent of Parameters
+type ArgumentsDefinition struct {
+	ArgumentsTemplate JSONataTemplate `json:"Arguments"`
+}
+
+func (a ArgumentsDefinition) Validate() error {
+	if a.ArgumentsTemplate.IsEmpty() {
+		return nil
+	}
+	return a.ArgumentsTemplate.Validate("Arguments")
+}
+
+func (a ArgumentsDefinition) Arguments() JSONataTemplate {
+	return a.ArgumentsTemplate
+}
+
+// OutputDefinition represents the Output of a JSONata state, which replaces ResultSelector, ResultPath
+// and OutputPath
+type OutputDefinition struct {
+	OutputTemplate JSONataTemplate `json:"Output"`
+}
+
+func (o OutputDefinition) Validate() error {
+	if o.OutputTemplate.IsEmpty() {
+		return nil
+	}
+	return o.OutputTemplate.Validate("Output")
+}
+
+func (o OutputDefinition) Output() JSONataTemplate {
+	return o.OutputTemplate
+}
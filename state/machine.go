@@ -2,10 +2,24 @@ package state
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	StandardMachineType = "STANDARD"
+	ExpressMachineType  = "EXPRESS"
+
+	// MaxExpressTimeoutSeconds is the maximum duration of an express workflow execution
+	MaxExpressTimeoutSeconds = 300
+)
+
+var validMachineTypes = map[string]struct{}{
+	StandardMachineType: {},
+	ExpressMachineType:  {},
+}
+
 // MachineStates represents an AWS states language 'States' object.
 type MachineStates map[string]json.RawMessage
 
@@ -68,7 +82,73 @@ type MachineDefinition struct {
 	StartAt        string        `json:"StartAt"`
 	Version        string        `json:"Version"`
 	TimeoutSeconds int           `json:"TimeoutSeconds"`
+	QueryLanguage  string        `json:"QueryLanguage"`
 	States         MachineStates `json:"States"`
+	// Type is the workflow type of the machine, which is set when the machine is created rather than in
+	// its definition. Machines are standard unless set to be express.
+	Type string `json:"-"`
+	// definitions are the states parsed ahead of time e.g. those of a Parallel state's branch
+	definitions map[string]Definition
+}
+
+// parseStates parses the states of the machine once, rather than each time a machine is built from it. A
+// state that can't be parsed isn't kept, so getting its definition returns the error.
+func (m *MachineDefinition) parseStates() {
+	m.definitions = map[string]Definition{}
+	for name := range m.States {
+		if def, err := m.States.GetDefinition(name); err == nil {
+			m.definitions[name] = def
+		}
+	}
+}
+
+// IsExpress reports whether the machine is an express workflow
+func (m MachineDefinition) IsExpress() bool {
+	return m.Type == ExpressMachineType
+}
+
+// GetDefinition returns the definition of a state of the machine. The branches of a Parallel state
+// inherit its query language unless they set their own, and the workflow type of the machine.
+func (m MachineDefinition) GetDefinition(name string) (Definition, error) {
+	def, ok := m.definitions[name]
+	if !ok {
+		var err error
+		if def, err = m.States.GetDefinition(name); err != nil {
+			return nil, err
+		}
+	}
+
+	parallelDef, ok := def.(ParallelDefinition)
+	if !ok {
+		return def, nil
+	}
+
+	queryLanguage := m.StateQueryLanguage(def)
+	branches := make([]MachineDefinition, 0, len(parallelDef.Branches))
+	for _, branch := range parallelDef.Branches {
+		if branch.QueryLanguage == "" {
+			branch.QueryLanguage = queryLanguage
+		}
+		branch.Type = m.Type
+		branches = append(branches, branch)
+	}
+	parallelDef.Branches = branches
+
+	return parallelDef, nil
+}
+
+// StateQueryLanguage returns the query language of a state, which is that of the machine unless the
+// state sets its own
+func (m MachineDefinition) StateQueryLanguage(def Definition) string {
+	if v, ok := def.(QueryLanguager); ok && v.QueryLanguage() != "" {
+		return v.QueryLanguage()
+	}
+
+	if m.QueryLanguage != "" {
+		return m.QueryLanguage
+	}
+
+	return JSONPathQueryLanguage
 }
 
 func (m MachineDefinition) Validate() error {
@@ -86,8 +166,24 @@ func (m MachineDefinition) Validate() error {
 		validationErrs = append(validationErrs, NewValidationError(MissingRequiredFieldErrType, "States", ""))
 	}
 
+	if m.QueryLanguage != "" {
+		if _, ok := validQueryLanguages[m.QueryLanguage]; !ok {
+			validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "QueryLanguage", m.QueryLanguage))
+		}
+	}
+
+	if m.Type != "" {
+		if _, ok := validMachineTypes[m.Type]; !ok {
+			validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "Type", m.Type))
+		}
+	}
+
+	if m.IsExpress() && m.TimeoutSeconds > MaxExpressTimeoutSeconds {
+		validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "TimeoutSeconds", strconv.Itoa(m.TimeoutSeconds)))
+	}
+
 	for title := range m.States {
-		def, err := m.States.GetDefinition(title)
+		def, err := m.GetDefinition(title)
 		if err != nil {
 			return errors.Wrapf(err, "error getting state definiton for %s", title)
 		}
@@ -96,6 +192,14 @@ func (m MachineDefinition) Validate() error {
 			validationErrs = append(validationErrs, err.(ValidationErrors)...)
 		}
 
+		if err := m.validateQueryLanguage(def); err != nil {
+			validationErrs = append(validationErrs, err.(ValidationErrors)...)
+		}
+
+		if err := m.validateIntegrationPattern(def); err != nil {
+			validationErrs = append(validationErrs, err.(ValidationErrors)...)
+		}
+
 		transitioner, ok := def.(Transitioner)
 		if !ok {
 			continue
@@ -115,3 +219,132 @@ func (m MachineDefinition) Validate() error {
 	}
 	return nil
 }
+
+// validateQueryLanguage validates that a state only uses the fields of its query language. A state of
+// a JSONata machine can't use JSONPath.
+func (m MachineDefinition) validateQueryLanguage(def Definition) error {
+	validationErrs := ValidationErrors{}
+	queryLanguage := m.StateQueryLanguage(def)
+
+	if m.QueryLanguage == JSONataQueryLanguage && queryLanguage == JSONPathQueryLanguage {
+		validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "QueryLanguage", queryLanguage))
+	}
+
+	unsupported := jsonPathFields(def)
+	if queryLanguage == JSONPathQueryLanguage {
+		unsupported = jsonataFields(def)
+	}
+
+	for _, field := range unsupported {
+		validationErrs = append(validationErrs, NewValidationError(UnsupportedFieldErrType, field, queryLanguage))
+	}
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+// validateIntegrationPattern validates that the tasks of an express machine only use the request
+// response integration pattern. Express workflows can't run a job or wait for a task token.
+func (m MachineDefinition) validateIntegrationPattern(def Definition) error {
+	taskDef, ok := def.(TaskDefinition)
+	if !ok || !m.IsExpress() || taskDef.IntegrationPattern() == RequestResponseIntegrationPattern {
+		return nil
+	}
+
+	return ValidationErrors{NewValidationError(UnsupportedPatternErrType, "Resource", taskDef.Resource)}
+}
+
+// jsonPathFields returns the JSONPath only fields a state sets
+func jsonPathFields(def Definition) []string {
+	fields := []string{}
+
+	if v, ok := def.(InputPather); ok && !v.InputPath().IsEmpty() {
+		fields = append(fields, "InputPath")
+	}
+	if v, ok := def.(OutputPather); ok && !v.OutputPath().IsEmpty() {
+		fields = append(fields, "OutputPath")
+	}
+	if v, ok := def.(Parameterizer); ok && !v.Parameters().IsEmpty() {
+		fields = append(fields, "Parameters")
+	}
+	if v, ok := def.(ResultSelectorer); ok && !v.ResultSelector().IsEmpty() {
+		fields = append(fields, "ResultSelector")
+	}
+	if v, ok := def.(ResultPather); ok && !v.ResultPath().IsEmpty() {
+		fields = append(fields, "ResultPath")
+	}
+	if v, ok := def.(Assigner); ok && v.Assign().usesJSONPath() {
+		fields = append(fields, "Assign")
+	}
+
+	switch v := def.(type) {
+	case PassDefinition:
+		if v.Result != nil {
+			fields = append(fields, "Result")
+		}
+	case FailDefinition:
+		if v.ErrorPath != "" {
+			fields = append(fields, "ErrorPath")
+		}
+		if v.CausePath != "" {
+			fields = append(fields, "CausePath")
+		}
+	case ChoiceDefinition:
+		for _, choice := range v.Choices {
+			if choice.Condition.IsEmpty() {
+				fields = append(fields, "Choices.Variable/And/Or/Not")
+				break
+			}
+		}
+		for _, choice := range v.Choices {
+			if choice.Assign().usesJSONPath() {
+				fields = append(fields, "Choices.Assign")
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// jsonataFields returns the JSONata only fields a state sets
+func jsonataFields(def Definition) []string {
+	fields := []string{}
+
+	if v, ok := def.(Argumenter); ok && !v.Arguments().IsEmpty() {
+		fields = append(fields, "Arguments")
+	}
+	if v, ok := def.(Outputter); ok && !v.Output().IsEmpty() {
+		fields = append(fields, "Output")
+	}
+	if v, ok := def.(Assigner); ok && v.Assign().usesJSONata() {
+		fields = append(fields, "Assign")
+	}
+
+	switch v := def.(type) {
+	case FailDefinition:
+		if IsJSONataExpression(v.Error) {
+			fields = append(fields, "Error")
+		}
+		if IsJSONataExpression(v.Cause) {
+			fields = append(fields, "Cause")
+		}
+	case ChoiceDefinition:
+		for _, choice := range v.Choices {
+			if !choice.Condition.IsEmpty() {
+				fields = append(fields, "Choices.Condition")
+				break
+			}
+		}
+		for _, choice := range v.Choices {
+			if choice.Assign().usesJSONata() {
+				fields = append(fields, "Choices.Assign")
+				break
+			}
+		}
+	}
+
+	return fields
+}
@@ -1,10 +1,17 @@
 package state
 
+import "encoding/json"
+
 type ParallelDefinition struct {
 	BaseDefinition
 	TransitionDefinition
 	IOPathDefinition
+	ParametersDefinition
+	ResultSelectorDefinition
 	ResultPathDefinition
+	ArgumentsDefinition
+	OutputDefinition
+	AssignDefinition
 	Branches []MachineDefinition `json:"Branches"`
 }
 
@@ -12,6 +19,28 @@ func (p ParallelDefinition) Type() string {
 	return ParallelStateType
 }
 
+// UnmarshalJSON unmarshals the definition, compiles its paths and parses the states of its branches
+func (p *ParallelDefinition) UnmarshalJSON(data []byte) error {
+	type parallelDefinition ParallelDefinition
+	if err := json.Unmarshal(data, (*parallelDefinition)(p)); err != nil {
+		return err
+	}
+
+	fields, err := newDefinitionFields(data)
+	if err != nil {
+		return err
+	}
+	p.IOPathDefinition.compile(fields)
+	p.ResultPathDefinition.compile(fields)
+
+	// the states of the branches are parsed with the state rather than each time it runs
+	for i := range p.Branches {
+		p.Branches[i].parseStates()
+	}
+
+	return nil
+}
+
 func (p ParallelDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -27,42 +56,35 @@ func (p ParallelDefinition) Validate() error {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
+	if err := p.ParametersDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := p.ResultSelectorDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
 	if err := p.ResultPathDefinition.Validate(); err != nil {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if len(p.Branches) == 0 {
-		validationErrs = append(validationErrs, NewValidationError(
-			MissingRequiredFieldErrType,
-			"Branches", "Is empty",
-		))
+	if err := p.ArgumentsDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.InputPathExp != "" {
-		if err := p.InputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"InputPath", string(p.InputPathExp),
-			))
-		}
+	if err := p.OutputDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.OutputPathExp != "" {
-		if err := p.OutputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"OutputPath", string(p.OutputPathExp),
-			))
-		}
+	if err := p.AssignDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.ResultPathExp != "" {
-		if err := p.ResultPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"ResultPath", string(p.ResultPathExp),
-			))
-		}
+	if len(p.Branches) == 0 {
+		validationErrs = append(validationErrs, NewValidationError(
+			MissingRequiredFieldErrType,
+			"Branches", "Is empty",
+		))
 	}
 
 	for _, branch := range p.Branches {
@@ -6,7 +6,10 @@ type PassDefinition struct {
 	BaseDefinition
 	TransitionDefinition
 	IOPathDefinition
+	ParametersDefinition
 	ResultPathDefinition
+	OutputDefinition
+	AssignDefinition
 	Result json.RawMessage `json:"Result"`
 }
 
@@ -14,6 +17,23 @@ func (PassDefinition) Type() string {
 	return PassStateType
 }
 
+// UnmarshalJSON unmarshals the definition and compiles its paths
+func (p *PassDefinition) UnmarshalJSON(data []byte) error {
+	type passDefinition PassDefinition
+	if err := json.Unmarshal(data, (*passDefinition)(p)); err != nil {
+		return err
+	}
+
+	fields, err := newDefinitionFields(data)
+	if err != nil {
+		return err
+	}
+	p.IOPathDefinition.compile(fields)
+	p.ResultPathDefinition.compile(fields)
+
+	return nil
+}
+
 func (p PassDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -29,35 +49,20 @@ func (p PassDefinition) Validate() error {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if err := p.ResultPathDefinition.Validate(); err != nil {
+	if err := p.ParametersDefinition.Validate(); err != nil {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.InputPathExp != "" {
-		if err := p.InputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"InputPath", string(p.InputPathExp),
-			))
-		}
+	if err := p.ResultPathDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.OutputPathExp != "" {
-		if err := p.OutputPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"OutputPath", string(p.OutputPathExp),
-			))
-		}
+	if err := p.OutputDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
-	if p.ResultPathExp != "" {
-		if err := p.ResultPathExp.Validate(); err != nil {
-			validationErrs = append(validationErrs, NewValidationError(
-				InvalidJSONPathErrType,
-				"ResultPath", string(p.ResultPathExp),
-			))
-		}
+	if err := p.AssignDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
 	if len(validationErrs) > 0 {
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
+	"github.com/pkg/errors"
+)
+
+const dynamicKeySuffix = ".$"
+
+// PayloadTemplate represents an AWS states language payload template as used by Parameters. Fields with
+// keys ending in ".$" have their values, a path or intrinsic function, resolved from the state's input.
+// The paths and intrinsic functions are compiled when the template is created or unmarshaled.
+type PayloadTemplate struct {
+	raw      json.RawMessage
+	template interface{}
+	err      error
+}
+
+func NewPayloadTemplate(data []byte) PayloadTemplate {
+	template, err := decodeJSON(data)
+	if err != nil {
+		return PayloadTemplate{raw: data, err: err}
+	}
+
+	return PayloadTemplate{
+		raw:      data,
+		template: compileTemplate(template),
+	}
+}
+
+func (p PayloadTemplate) MarshalJSON() ([]byte, error) {
+	return p.raw.MarshalJSON()
+}
+
+func (p *PayloadTemplate) UnmarshalJSON(data []byte) error {
+	*p = NewPayloadTemplate(append(json.RawMessage{}, data...))
+	return nil
+}
+
+// IsEmpty reports whether the template isn't set
+func (p PayloadTemplate) IsEmpty() bool {
+	return p.raw == nil
+}
+
+// Validate validates the template as the value of the given field e.g. "Parameters"
+func (p PayloadTemplate) Validate(field string) error {
+	validationErrs := ValidationErrors{}
+
+	if p.err != nil {
+		return append(validationErrs, NewValidationError(InvalidValueErrType, field, string(p.raw)))
+	}
+
+	walkTemplate(p.template, field, func(field string, exp interface{}) {
+		compiled, ok := exp.(*CompiledValueExp)
+		if !ok {
+			validationErrs = append(validationErrs, NewValidationError(InvalidJSONPathErrType, field, ""))
+			return
+		}
+
+		if compiled.err != nil {
+			validationErrs = append(validationErrs, NewValidationError(compiled.exp.validationErrType(), field, string(compiled.exp)))
+		}
+	})
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+// Resolve returns the payload built from the template and the given input. Intrinsic function failures
+// are returned as States.IntrinsicFailure errors.
+func (p PayloadTemplate) Resolve(input []byte) ([]byte, error) {
+	doc, err := jsonpath.NewDocument(input)
+	if err != nil {
+		return []byte{}, errors.Wrap(err, "error unmarshaling input")
+	}
+
+	return p.ResolveDocument(doc)
+}
+
+// ResolveDocument is equivalent to Resolve for an input that has already been parsed. Paths may
+// reference the document's variables.
+func (p PayloadTemplate) ResolveDocument(doc *jsonpath.Document) ([]byte, error) {
+	if p.err != nil {
+		return []byte{}, errors.Wrap(p.err, "error unmarshaling payload template")
+	}
+
+	payload, err := resolveTemplate(p.template, doc)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return encodeJSON(payload)
+}
+
+// compileTemplate returns a copy of the template in which the string values of dynamic fields are
+// replaced by their compiled expressions
+func compileTemplate(template interface{}) interface{} {
+	switch v := template.(type) {
+	case map[string]interface{}:
+		compiled := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if !strings.HasSuffix(key, dynamicKeySuffix) {
+				compiled[key] = compileTemplate(value)
+				continue
+			}
+
+			if exp, ok := value.(string); ok {
+				compiled[key] = ValueExp(exp).Compile()
+				continue
+			}
+			compiled[key] = value
+		}
+		return compiled
+	case []interface{}:
+		compiled := make([]interface{}, len(v))
+		for i, value := range v {
+			compiled[i] = compileTemplate(value)
+		}
+		return compiled
+	}
+
+	return template
+}
+
+func resolveTemplate(template interface{}, doc *jsonpath.Document) (interface{}, error) {
+	switch v := template.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if !strings.HasSuffix(key, dynamicKeySuffix) {
+				child, err := resolveTemplate(value, doc)
+				if err != nil {
+					return nil, err
+				}
+				resolved[key] = child
+				continue
+			}
+
+			exp, ok := value.(*CompiledValueExp)
+			if !ok {
+				return nil, errors.Errorf("value of '%s' must be a path", key)
+			}
+
+			result, err := exp.EvaluateDocument(doc)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error resolving '%s'", key)
+			}
+			resolved[strings.TrimSuffix(key, dynamicKeySuffix)] = json.RawMessage(result)
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, value := range v {
+			child, err := resolveTemplate(value, doc)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = child
+		}
+		return resolved, nil
+	}
+
+	return template, nil
+}
+
+// walkTemplate calls fn for each dynamic field in the template with the field's location and expression
+func walkTemplate(template interface{}, location string, fn func(field string, exp interface{})) {
+	switch v := template.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if strings.HasSuffix(key, dynamicKeySuffix) {
+				fn(location+"."+key, value)
+				continue
+			}
+			walkTemplate(value, location+"."+key, fn)
+		}
+	case []interface{}:
+		for _, value := range v {
+			walkTemplate(value, location, fn)
+		}
+	}
+}
+
+func decodeJSON(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func encodeJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return []byte{}, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,172 @@
+// +build unit
+
+package state_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadTemplate(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		tests := []struct {
+			title         string
+			template      state.PayloadTemplate
+			expectedError *state.ValidationError
+		}{
+			{
+				"invalid path",
+				state.NewPayloadTemplate([]byte(`{"nested":{"name.$":"invalid json path"}}`)),
+				state.NewValidationError(
+					state.InvalidJSONPathErrType,
+					"Parameters.nested.name.$", "invalid json path",
+				),
+			},
+			{
+				"non string path",
+				state.NewPayloadTemplate([]byte(`{"name.$":1}`)),
+				state.NewValidationError(
+					state.InvalidJSONPathErrType,
+					"Parameters.name.$", "",
+				),
+			},
+			{
+				"invalid intrinsic function",
+				state.NewPayloadTemplate([]byte(`{"name.$":"States.Format("}`)),
+				state.NewValidationError(
+					state.InvalidIntrinsicErrType,
+					"Parameters.name.$", "States.Format(",
+				),
+			},
+			{
+				"valid",
+				state.NewPayloadTemplate([]byte(`{"static":1,"name.$":"$.name","list":[{"id.$":"States.UUID()"}]}`)),
+				nil,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				err := tt.template.Validate("Parameters")
+				if tt.expectedError == nil {
+					require.NoError(t, err)
+					return
+				}
+
+				require.Error(t, err)
+				vErr, ok := err.(state.ValidationErrors)
+				require.True(t, ok)
+				require.Contains(t, vErr, tt.expectedError)
+			})
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		template := state.NewPayloadTemplate([]byte(`{
+			"static": {"big": 12345678901234567890},
+			"name.$": "$.name",
+			"list": [{"first.$": "$.items[0]"}],
+			"count.$": "States.ArrayLength($.items)"
+		}`))
+
+		result, err := template.Resolve([]byte(`{"name":"test","items":["a","b"]}`))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"static":{"big":12345678901234567890},"name":"test","list":[{"first":"a"}],"count":2}`, string(result))
+	})
+}
+
+func TestJSONPathExp(t *testing.T) {
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		var def state.PassDefinition
+		require.NoError(t, json.Unmarshal([]byte(`{"ResultPath":null}`), &def))
+		require.True(t, def.ResultJSONPath().IsNull())
+
+		def = state.PassDefinition{}
+		require.NoError(t, json.Unmarshal([]byte(`{"ResultPath":"$.result"}`), &def))
+		require.Equal(t, state.JSONPathExp("$.result"), def.ResultPath())
+		require.Equal(t, state.NewJSONPath("$.result"), def.ResultJSONPath())
+
+		def = state.PassDefinition{}
+		require.NoError(t, json.Unmarshal([]byte(`{"ResultPath":"null"}`), &def))
+		require.False(t, def.ResultJSONPath().IsNull())
+		require.Error(t, def.ResultPathDefinition.Validate())
+
+		def = state.PassDefinition{}
+		require.NoError(t, json.Unmarshal([]byte(`{"InputPath":null,"OutputPath":"null"}`), &def))
+		require.True(t, def.InputJSONPath().IsNull())
+		require.Error(t, def.IOPathDefinition.Validate())
+	})
+
+	t.Run("declared in code", func(t *testing.T) {
+		def := state.IOPathDefinition{InputPathExp: "$.a"}
+
+		result, err := def.InputJSONPath().Search([]byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.Equal(t, "1", string(result))
+	})
+
+	t.Run("compiled once", func(t *testing.T) {
+		var def state.PassDefinition
+		require.NoError(t, json.Unmarshal([]byte(`{"InputPath":"$.a","OutputPath":"invalid json path"}`), &def))
+
+		compiled := state.CompiledPath(def.InputJSONPath())
+		require.NotNil(t, compiled)
+		require.True(t, compiled == state.CompiledPath(def.InputJSONPath()))
+
+		copied := def
+		require.True(t, compiled == state.CompiledPath(copied.InputJSONPath()))
+
+		result, err := copied.InputJSONPath().Search([]byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.Equal(t, "1", string(result))
+
+		require.Error(t, def.OutputJSONPath().Validate())
+		require.Error(t, def.IOPathDefinition.Validate())
+	})
+
+	t.Run("compiled once in branches", func(t *testing.T) {
+		var def state.ParallelDefinition
+		require.NoError(t, json.Unmarshal([]byte(`{"Type":"Parallel","End":true,"Branches":[
+			{"StartAt":"pass","States":{"pass":{"Type":"Pass","InputPath":"$.a","End":true}}}
+		]}`), &def))
+
+		first, err := def.Branches[0].GetDefinition("pass")
+		require.NoError(t, err)
+		second, err := def.Branches[0].GetDefinition("pass")
+		require.NoError(t, err)
+
+		compiled := state.CompiledPath(first.(state.PassDefinition).InputJSONPath())
+		require.NotNil(t, compiled)
+		require.True(t, compiled == state.CompiledPath(second.(state.PassDefinition).InputJSONPath()))
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		tests := []struct {
+			title          string
+			exp            state.JSONPathExp
+			input          string
+			expectedOutput string
+		}{
+			{"empty path", state.JSONPathExp(""), `{"a":1}`, `"value"`},
+			{"root", state.JSONPathExp("$"), `{"a":1}`, `"value"`},
+			{"existing field", state.JSONPathExp("$.a"), `{"a":1}`, `{"a":"value"}`},
+			{"intermediate objects", state.JSONPathExp("$.b.c"), `{"a":1}`, `{"a":1,"b":{"c":"value"}}`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				result, err := tt.exp.Set([]byte(tt.input), []byte(`"value"`))
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedOutput, string(result))
+			})
+		}
+
+		t.Run("non object in path", func(t *testing.T) {
+			_, err := state.JSONPathExp("$.a.b").Set([]byte(`{"a":1}`), []byte(`"value"`))
+			require.Error(t, err)
+		})
+	})
+}
@@ -1,13 +1,33 @@
 package state
 
+import "encoding/json"
+
 type SucceedDefinition struct {
 	BaseDefinition
+	IOPathDefinition
+	OutputDefinition
 }
 
 func (SucceedDefinition) Type() string {
 	return SucceedStateType
 }
 
+// UnmarshalJSON unmarshals the definition and compiles its paths
+func (s *SucceedDefinition) UnmarshalJSON(data []byte) error {
+	type succeedDefinition SucceedDefinition
+	if err := json.Unmarshal(data, (*succeedDefinition)(s)); err != nil {
+		return err
+	}
+
+	fields, err := newDefinitionFields(data)
+	if err != nil {
+		return err
+	}
+	s.IOPathDefinition.compile(fields)
+
+	return nil
+}
+
 func (s SucceedDefinition) Validate() error {
 	validationErrs := ValidationErrors{}
 
@@ -15,6 +35,14 @@ func (s SucceedDefinition) Validate() error {
 		validationErrs = append(validationErrs, err.(ValidationErrors)...)
 	}
 
+	if err := s.IOPathDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := s.OutputDefinition.Validate(); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
 	if len(validationErrs) > 0 {
 		return validationErrs
 	}
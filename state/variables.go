@@ -0,0 +1,240 @@
+package state
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/eggsbenjamin/stepFnLocal/jsonpath"
+	"github.com/pkg/errors"
+)
+
+// reservedVariable is the name of the variable holding a state's input and result, e.g. $states.input,
+// which can't be assigned
+const reservedVariable = "states"
+
+// contextVariable is the name of the variable holding the context object of the running state, which
+// JSONPath states reference as $$ and JSONata states as $states.context
+const contextVariable = jsonpath.ContextVariable
+
+var variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,79}$`)
+
+// Variables are the workflow variables of an execution scope. States assign variables with their Assign
+// field once they complete and reference them as $name in paths and JSONata expressions.
+type Variables map[string]json.RawMessage
+
+// Copy returns a copy of the variables e.g. for the scope of a Parallel branch, which can read the
+// variables of the outer scope but not change them
+func (v Variables) Copy() Variables {
+	variables := make(Variables, len(v))
+	for name, value := range v {
+		variables[name] = value
+	}
+
+	return variables
+}
+
+// SetContext sets the context object of the running state
+func (v Variables) SetContext(context json.RawMessage) {
+	v[contextVariable] = context
+}
+
+// Context returns the context object of the running state, if any
+func (v Variables) Context() json.RawMessage {
+	return v[contextVariable]
+}
+
+// Set assigns each of the given variables
+func (v Variables) Set(variables Variables) {
+	for name, value := range variables {
+		v[name] = value
+	}
+}
+
+// Document parses JSON as a document in which the variables can be referenced by paths e.g. $name.field
+// Only the variables that paths reference are decoded, so the context object, which embeds the
+// execution input, costs nothing unless it is used.
+func (v Variables) Document(input []byte) (*jsonpath.Document, error) {
+	doc, err := jsonpath.NewDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range v {
+		if err := doc.SetVariable(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// AssignTemplate represents the Assign field of a state, an object of variable names and their values.
+// As with Parameters, the values of JSONPath states are resolved from fields with keys ending in ".$",
+// whereas JSONata states use {% %} expressions. The values are compiled as both when the template is
+// created or unmarshaled.
+type AssignTemplate struct {
+	raw     json.RawMessage
+	fields  map[string]json.RawMessage
+	err     error
+	payload PayloadTemplate
+	jsonata JSONataTemplate
+}
+
+func NewAssignTemplate(data []byte) AssignTemplate {
+	a := AssignTemplate{
+		raw:     data,
+		payload: NewPayloadTemplate(data),
+		jsonata: NewJSONataTemplate(data),
+	}
+
+	// the shape of the template is checked once, an invalid template being reported by validation
+	if err := json.Unmarshal(data, &a.fields); err != nil {
+		a.fields, a.err = nil, err
+	} else if a.fields == nil {
+		a.err = errors.New("assign must be an object")
+	}
+
+	return a
+}
+
+func (a AssignTemplate) MarshalJSON() ([]byte, error) {
+	return a.raw.MarshalJSON()
+}
+
+func (a *AssignTemplate) UnmarshalJSON(data []byte) error {
+	*a = NewAssignTemplate(append(json.RawMessage{}, data...))
+	return nil
+}
+
+// IsEmpty reports whether the template isn't set
+func (a AssignTemplate) IsEmpty() bool {
+	return a.raw == nil
+}
+
+func (a AssignTemplate) Validate() error {
+	if a.err != nil {
+		return ValidationErrors{NewValidationError(InvalidValueErrType, "Assign", string(a.raw))}
+	}
+
+	validationErrs := ValidationErrors{}
+
+	for key := range a.fields {
+		name := strings.TrimSuffix(key, dynamicKeySuffix)
+		if !variableNameRegexp.MatchString(name) || name == reservedVariable {
+			validationErrs = append(validationErrs, NewValidationError(InvalidValueErrType, "Assign", key))
+			continue
+		}
+
+		if _, ok := a.fields[name+dynamicKeySuffix]; ok && key == name {
+			validationErrs = append(validationErrs, NewValidationError(InvalidCombinationErrType, "Assign."+name, OnlyOneMustExistErrMsg))
+		}
+	}
+
+	if err := a.payload.Validate("Assign"); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if err := a.jsonata.Validate("Assign"); err != nil {
+		validationErrs = append(validationErrs, err.(ValidationErrors)...)
+	}
+
+	if len(validationErrs) > 0 {
+		return validationErrs
+	}
+	return nil
+}
+
+// Evaluate returns the variables assigned by the template. Every value is evaluated against the variables
+// as they were before the state ran. JSONPath states resolve paths against the state's result, whereas
+// JSONata states reference it as $states.result. Both can reference the state's raw input as
+// $states.input.
+func (a AssignTemplate) Evaluate(queryLanguage string, input, result []byte, variables Variables) (Variables, error) {
+	var (
+		assigned []byte
+		err      error
+	)
+
+	if queryLanguage == JSONataQueryLanguage {
+		bindings, err := NewJSONataBindings(input, result, variables)
+		if err != nil {
+			return nil, errors.Wrap(err, "error applying Assign")
+		}
+
+		assigned, err = a.jsonata.Evaluate(bindings)
+		if err != nil {
+			return nil, AsError(errors.Wrap(err, "error applying Assign"), ErrQueryEvaluationErrorCode)
+		}
+	} else {
+		assigned, err = a.resolve(input, result, variables)
+		if err != nil {
+			return nil, AsError(errors.Wrap(err, "error applying Assign"), ErrRuntimeCode)
+		}
+	}
+
+	values := Variables{}
+	if err := json.Unmarshal(assigned, &values); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling assigned variables")
+	}
+
+	return values, nil
+}
+
+func (a AssignTemplate) resolve(input, result []byte, variables Variables) ([]byte, error) {
+	doc, err := variables.Document(result)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	states, err := json.Marshal(map[string]json.RawMessage{
+		"input":  input,
+		"result": result,
+	})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if err := doc.SetVariable(reservedVariable, states); err != nil {
+		return []byte{}, err
+	}
+
+	return a.payload.ResolveDocument(doc)
+}
+
+// usesJSONPath reports whether any of the values are resolved from paths. A template that isn't an
+// object has no values and is reported by validation.
+func (a AssignTemplate) usesJSONPath() bool {
+	for key := range a.fields {
+		if strings.HasSuffix(key, dynamicKeySuffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesJSONata reports whether any of the values are JSONata expressions
+func (a AssignTemplate) usesJSONata() bool {
+	found := false
+	walkJSONataTemplate(a.jsonata.template, "Assign", func(string, JSONataExp) {
+		found = true
+	})
+
+	return found
+}
+
+// AssignDefinition represents the Assign field of a state
+type AssignDefinition struct {
+	AssignTemplate AssignTemplate `json:"Assign"`
+}
+
+func (a AssignDefinition) Validate() error {
+	if a.AssignTemplate.IsEmpty() {
+		return nil
+	}
+	return a.AssignTemplate.Validate()
+}
+
+func (a AssignDefinition) Assign() AssignTemplate {
+	return a.AssignTemplate
+}
@@ -0,0 +1,152 @@
+// +build unit
+
+package state_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignTemplate(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		tests := []struct {
+			title         string
+			assign        string
+			expectedError *state.ValidationError
+		}{
+			{
+				"not an object",
+				`[1]`,
+				state.NewValidationError(state.InvalidValueErrType, "Assign", `[1]`),
+			},
+			{
+				"null",
+				`null`,
+				state.NewValidationError(state.InvalidValueErrType, "Assign", `null`),
+			},
+			{
+				"invalid name",
+				`{"1st": 1}`,
+				state.NewValidationError(state.InvalidValueErrType, "Assign", "1st"),
+			},
+			{
+				"reserved name",
+				`{"states.$": "$.a"}`,
+				state.NewValidationError(state.InvalidValueErrType, "Assign", "states.$"),
+			},
+			{
+				"static and dynamic value",
+				`{"a": 1, "a.$": "$.a"}`,
+				state.NewValidationError(state.InvalidCombinationErrType, "Assign.a", state.OnlyOneMustExistErrMsg),
+			},
+			{
+				"invalid path",
+				`{"a.$": "$.["}`,
+				state.NewValidationError(state.InvalidJSONPathErrType, "Assign.a.$", "$.["),
+			},
+			{
+				"invalid JSONata",
+				`{"a": "{% 1 + %}"}`,
+				state.NewValidationError(state.InvalidJSONataErrType, "Assign.a", "{% 1 + %}"),
+			},
+			{
+				"valid",
+				`{"a": 1, "b.$": "$.b", "c.$": "$c.field", "d": "{% $states.input %}"}`,
+				nil,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				err := state.NewAssignTemplate([]byte(tt.assign)).Validate()
+				if tt.expectedError == nil {
+					require.NoError(t, err)
+					return
+				}
+
+				require.Error(t, err)
+				vErr, ok := err.(state.ValidationErrors)
+				require.True(t, ok)
+				require.Contains(t, vErr, tt.expectedError)
+			})
+		}
+	})
+
+	t.Run("Evaluate", func(t *testing.T) {
+		variables := state.Variables{
+			"count": json.RawMessage(`1`),
+			"order": json.RawMessage(`{"id":"o-1"}`),
+		}
+		input := []byte(`{"input":true}`)
+		result := []byte(`{"total":10}`)
+
+		tests := []struct {
+			title            string
+			queryLanguage    string
+			assign           string
+			expectedAssigned string
+		}{
+			{
+				"JSONPath",
+				state.JSONPathQueryLanguage,
+				`{
+					"static": "value",
+					"total.$": "$.total",
+					"input.$": "$states.input.input",
+					"id.$": "$order.id",
+					"count.$": "States.MathAdd($count, 1)"
+				}`,
+				`{"static":"value","total":10,"input":true,"id":"o-1","count":2}`,
+			},
+			{
+				"JSONata",
+				state.JSONataQueryLanguage,
+				`{
+					"static": "value",
+					"total": "{% $states.result.total %}",
+					"input": "{% $states.input.input %}",
+					"id": "{% $order.id %}",
+					"count": "{% $count + 1 %}"
+				}`,
+				`{"static":"value","total":10,"input":true,"id":"o-1","count":2}`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				assigned, err := state.NewAssignTemplate([]byte(tt.assign)).Evaluate(tt.queryLanguage, input, result, variables)
+				require.NoError(t, err)
+
+				actual, err := json.Marshal(assigned)
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expectedAssigned, string(actual))
+			})
+		}
+
+		t.Run("undefined variable", func(t *testing.T) {
+			_, err := state.NewAssignTemplate([]byte(`{"a.$": "$missing"}`)).Evaluate(state.JSONPathQueryLanguage, input, result, variables)
+			require.Error(t, err)
+			stateErr, ok := err.(state.Error)
+			require.True(t, ok)
+			require.Equal(t, state.ErrRuntimeCode, stateErr.Name)
+		})
+	})
+
+	t.Run("Variables", func(t *testing.T) {
+		variables := state.Variables{"a": json.RawMessage(`1`)}
+
+		copied := variables.Copy()
+		copied.Set(state.Variables{"a": json.RawMessage(`2`), "b": json.RawMessage(`3`)})
+		require.Equal(t, state.Variables{"a": json.RawMessage(`1`)}, variables)
+		require.Len(t, copied, 2)
+
+		doc, err := variables.Document([]byte(`{}`))
+		require.NoError(t, err)
+		result, err := state.ValueExp("$a").EvaluateDocument(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `1`, string(result))
+	})
+}
@@ -0,0 +1,33 @@
+// Package uuid generates the random IDs of executions, tasks and the local services e.g. request and
+// message IDs
+package uuid
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// NewRandom returns a random version 4 UUID e.g. 2a7b7c12-5f1e-4d3a-9b6c-0e8f1d2c3b4a
+func NewRandom() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "error generating uuid")
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// New is equivalent to NewRandom for IDs that can't fail to be generated. It panics if the system's
+// random number generator fails, as nothing can be identified without it.
+func New() string {
+	id, err := NewRandom()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
@@ -0,0 +1,23 @@
+// +build unit
+
+package uuid_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/eggsbenjamin/stepFnLocal/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	id := uuid.New()
+	require.Regexp(t, pattern, id)
+	require.NotEqual(t, id, uuid.New())
+
+	id, err := uuid.NewRandom()
+	require.NoError(t, err)
+	require.Regexp(t, pattern, id)
+}